@@ -0,0 +1,182 @@
+// Command scoreboard writes a challenge's scoreboard.json and SCOREBOARD.md from test results.
+// It is run by the scoreboard workflows after testing every submission of a challenge.
+//
+// Usage:
+//
+//	scoreboard -dir challenge-1 -title "Scoreboard for challenge-1" < results
+//
+// Each line of results is "username passed total executionMs submittedAt", with submittedAt
+// in RFC 3339. The scoreboard is replaced by the users listed.
+//
+// With -migrate, it instead writes a scoreboard.json for every challenge under a repository
+// checkout that only has a SCOREBOARD.md, dating each entry by the commit time of the last commit
+// to the user's submission directory, as the scoreboard workflows do. Entries last changed by a
+// root commit, such as an import of the repository, stay undated. The checkout must have its full
+// history; the Migrate Scoreboards workflow runs it on the repository:
+//
+//	scoreboard -migrate ..
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-ui/internal/scoreboard"
+)
+
+func main() {
+	dir := flag.String("dir", "", "challenge directory to write the scoreboard to")
+	title := flag.String("title", "", "scoreboard title")
+	migrate := flag.String("migrate", "", "repository checkout whose markdown-only scoreboards to migrate")
+	flag.Parse()
+
+	if *migrate != "" {
+		if err := migrateAll(*migrate); err != nil {
+			log.Fatalf("Failed to migrate scoreboards: %v", err)
+		}
+		return
+	}
+	if *dir == "" || *title == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	board := &scoreboard.Board{Title: *title, Entries: []scoreboard.Entry{}}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		entry, err := parseResult(scanner.Text())
+		if err != nil {
+			log.Fatalf("line %d: %v", line, err)
+		}
+		board.Record(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("Failed to read results: %v", err)
+	}
+
+	if err := scoreboard.Save(*dir, board); err != nil {
+		log.Fatalf("Failed to save scoreboard: %v", err)
+	}
+	log.Printf("Wrote %d entries to %s", len(board.Entries), *dir)
+}
+
+// parseResult reads an entry from a "username passed total executionMs submittedAt" line
+func parseResult(line string) (scoreboard.Entry, error) {
+	var entry scoreboard.Entry
+
+	fields := strings.Fields(line)
+	if len(fields) != 5 {
+		return entry, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	entry.Username = fields[0]
+
+	var err error
+	if entry.TestsPassed, err = strconv.Atoi(fields[1]); err != nil {
+		return entry, fmt.Errorf("invalid passed count %q", fields[1])
+	}
+	if entry.TestsTotal, err = strconv.Atoi(fields[2]); err != nil {
+		return entry, fmt.Errorf("invalid total count %q", fields[2])
+	}
+	if entry.ExecutionMs, err = strconv.ParseInt(fields[3], 10, 64); err != nil {
+		return entry, fmt.Errorf("invalid execution time %q", fields[3])
+	}
+	if entry.SubmittedAt, err = time.Parse(time.RFC3339, fields[4]); err != nil {
+		return entry, fmt.Errorf("invalid submission time %q", fields[4])
+	}
+	entry.SubmittedAt = entry.SubmittedAt.UTC()
+
+	return entry, nil
+}
+
+// migrateAll migrates the scoreboard of every core and package challenge under root. A shallow
+// clone would date every entry by its oldest fetched commit, so it is refused.
+func migrateAll(root string) error {
+	output, err := exec.Command("git", "-C", root, "rev-parse", "--is-shallow-repository").Output()
+	if err != nil {
+		return fmt.Errorf("%s is not a git checkout: %v", root, err)
+	}
+	if strings.TrimSpace(string(output)) == "true" {
+		return fmt.Errorf("%s is a shallow clone; fetch the full history first, e.g. git fetch --unshallow", root)
+	}
+
+	var dirs []string
+	for _, pattern := range []string{"challenge-*", filepath.Join("packages", "*", "challenge-*")} {
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, matches...)
+	}
+
+	for _, dir := range dirs {
+		migrated, err := migrate(dir)
+		if err != nil {
+			return fmt.Errorf("%s: %v", dir, err)
+		}
+		if migrated {
+			log.Printf("Migrated %s", dir)
+		}
+	}
+	return nil
+}
+
+// migrate writes scoreboard.json for a challenge that only has a SCOREBOARD.md, which it leaves
+// as it is. Entries without a submission commit stay undated.
+func migrate(dir string) (bool, error) {
+	if _, err := os.Stat(filepath.Join(dir, scoreboard.JSONFileName)); err == nil {
+		return false, nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, scoreboard.MarkdownFileName)); err != nil {
+		return false, nil
+	}
+
+	board, err := scoreboard.Load(dir)
+	if err != nil {
+		return false, err
+	}
+	for i := range board.Entries {
+		entry := &board.Entries[i]
+		if !entry.SubmittedAt.IsZero() {
+			continue
+		}
+		if entry.SubmittedAt, err = lastCommitTime(dir, filepath.Join("submissions", entry.Username)); err != nil {
+			return false, err
+		}
+		if entry.SubmittedAt.IsZero() {
+			log.Printf("Warning: %s has no submission commit for %s, so the entry stays undated", dir, entry.Username)
+		}
+	}
+	return true, scoreboard.SaveJSON(dir, board)
+}
+
+// lastCommitTime returns the commit time of the last commit to path in dir, or zero if there is
+// none or it is a root commit, whose time is when the history was imported rather than submitted
+func lastCommitTime(dir, path string) (time.Time, error) {
+	output, err := exec.Command("git", "-C", dir, "log", "-1", "--format=%cI %P", "--", path).Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("git log %s: %v", path, err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) < 2 {
+		return time.Time{}, nil
+	}
+	at, err := time.Parse(time.RFC3339, fields[0])
+	if err != nil {
+		return time.Time{}, err
+	}
+	return at.UTC(), nil
+}
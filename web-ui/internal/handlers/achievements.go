@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"web-ui/internal/models"
+	"web-ui/internal/scoreboard"
+	"web-ui/internal/services"
+)
+
+// evaluateAchievements awards the badges an event earns. A package-challenge-passed event names
+// the package challenge that passed, since package submissions reach its scoreboard only later.
+func (h *APIHandler) evaluateAchievements(event services.AchievementEvent, packageName, packageChallengeID string) []models.EarnedBadge {
+	if event.Username == "" {
+		return nil
+	}
+
+	base, progress := h.achievementProgress(event.Username)
+	facts := achievementFacts(base, progress[event.Username])
+	if packageName != "" && packageChallengeID != "" {
+		addCompletion(&facts, achievementCompletion{PackageName: packageName, PackageChallengeID: packageChallengeID, At: event.At})
+	}
+
+	awarded, err := h.achievements.Evaluate(event, facts)
+	if err != nil {
+		log.Printf("Error evaluating %s achievements of %s: %v", event.Type, event.Username, err)
+	}
+	for _, badge := range awarded {
+		log.Printf("Awarded %s the %s badge", event.Username, badge.ID)
+	}
+	return awarded
+}
+
+// syncAchievements awards the badges users' progress earned that they do not have yet, such as after
+// their scoreboards changed elsewhere. Their completions are replayed oldest first, so each badge is
+// dated when the completion that earned it was made. A nil set syncs every user with progress.
+func (h *APIHandler) syncAchievements(usernames map[string]bool) {
+	base, progress := h.achievementProgress("")
+	for username, completions := range progress {
+		if usernames != nil && !usernames[username] {
+			continue
+		}
+
+		// Undated completions, from markdown scoreboards, are taken to be as old as the oldest dated one
+		sort.SliceStable(completions, func(i, j int) bool {
+			return completions[i].At.Before(completions[j].At)
+		})
+		undatedAt := time.Now()
+		for _, completion := range completions {
+			if !completion.At.IsZero() {
+				undatedAt = completion.At
+				break
+			}
+		}
+
+		facts := achievementFacts(base, nil)
+		for _, completion := range completions {
+			addCompletion(&facts, completion)
+			event := services.AchievementEvent{Type: services.EventSync, Username: username, At: completion.At}
+			if event.At.IsZero() {
+				event.At = undatedAt
+			}
+
+			awarded, err := h.achievements.Evaluate(event, facts)
+			if err != nil {
+				log.Printf("Error syncing achievements of %s: %v", username, err)
+				break
+			}
+			for _, badge := range awarded {
+				log.Printf("Awarded %s the %s badge, dated %s", username, badge.ID, event.At.UTC().Format(time.RFC3339))
+			}
+		}
+	}
+}
+
+// BackfillAchievements awards every user the badges their progress earned before badges were stored,
+// dated when they earned them. It only runs while no badge has been awarded yet; after that, badges
+// are awarded as submissions pass and scoreboards change.
+func (h *APIHandler) BackfillAchievements() {
+	empty, err := h.achievements.Empty()
+	if err != nil {
+		log.Printf("Error checking the achievement store: %v", err)
+		return
+	}
+	if !empty {
+		return
+	}
+
+	log.Println("Backfilling achievements...")
+	h.syncAchievements(nil)
+}
+
+// achievementCompletion is a challenge a user completed: a core challenge, or a package's challenge
+type achievementCompletion struct {
+	ChallengeID        int
+	PackageName        string
+	PackageChallengeID string
+	At                 time.Time // When they passed it, zero if unknown
+}
+
+// achievementFacts returns facts that share the challenges and learning paths of the base
+// and hold the completions
+func achievementFacts(base services.AchievementFacts, completions []achievementCompletion) services.AchievementFacts {
+	facts := services.AchievementFacts{
+		CoreDifficulties: base.CoreDifficulties,
+		CoreCompleted:    make(map[int]bool),
+		PackagePaths:     base.PackagePaths,
+		PackageCompleted: make(map[string]map[string]bool),
+	}
+	for _, completion := range completions {
+		addCompletion(&facts, completion)
+	}
+	return facts
+}
+
+// addCompletion records a completion in the facts
+func addCompletion(facts *services.AchievementFacts, completion achievementCompletion) {
+	if completion.PackageName != "" {
+		if facts.PackageCompleted[completion.PackageName] == nil {
+			facts.PackageCompleted[completion.PackageName] = make(map[string]bool)
+		}
+		facts.PackageCompleted[completion.PackageName][completion.PackageChallengeID] = true
+	} else {
+		facts.CoreCompleted[completion.ChallengeID] = true
+	}
+	facts.PassDates = append(facts.PassDates, completion.At)
+}
+
+// achievementProgress gathers the completions of one user, or of everyone for an empty username,
+// from the scoreboards and the stored submissions. A passing stored submit counts as a completion
+// even before the scoreboard has it. The base facts hold the challenges and learning paths.
+func (h *APIHandler) achievementProgress(username string) (services.AchievementFacts, map[string][]achievementCompletion) {
+	base := services.AchievementFacts{
+		CoreDifficulties: make(map[int]string),
+		PackagePaths:     make(map[string][]string),
+	}
+	progress := make(map[string][]achievementCompletion)
+	record := func(entry scoreboard.Entry, completion achievementCompletion) {
+		if (username == "" || entry.Username == username) && entry.Completed() {
+			completion.At = entry.SubmittedAt
+			progress[entry.Username] = append(progress[entry.Username], completion)
+		}
+	}
+
+	for id, challenge := range h.challengeService.GetChallenges() {
+		base.CoreDifficulties[id] = challenge.Difficulty
+	}
+
+	for id, board := range h.challengeScoreboards() {
+		for _, entry := range board.Entries {
+			record(entry, achievementCompletion{ChallengeID: id})
+		}
+	}
+
+	submissions, err := h.submissionStore.Query(services.SubmissionQuery{Username: username, Action: models.ActionSubmit})
+	if err != nil {
+		log.Printf("Error querying submissions for achievements: %v", err)
+	}
+	for _, submission := range submissions {
+		if submission.Passed {
+			progress[submission.Username] = append(progress[submission.Username],
+				achievementCompletion{ChallengeID: submission.ChallengeID, At: submission.SubmittedAt})
+		}
+	}
+
+	for packageName := range h.packageService.GetPackages() {
+		_, challenges, err := h.packageLearningPath(packageName)
+		if err != nil {
+			continue
+		}
+
+		for _, challenge := range challenges {
+			base.PackagePaths[packageName] = append(base.PackagePaths[packageName], challenge.ID)
+
+			board, err := scoreboard.Load(challenge.Dir)
+			if err != nil {
+				if !errors.Is(err, fs.ErrNotExist) {
+					log.Printf("Warning: Could not load scoreboard for %s %s: %v", packageName, challenge.ID, err)
+				}
+				continue
+			}
+			for _, entry := range board.Entries {
+				record(entry, achievementCompletion{PackageName: packageName, PackageChallengeID: challenge.ID})
+			}
+		}
+	}
+
+	return base, progress
+}
+
+// earnedBadges returns the badges a user has earned, oldest first
+func (h *APIHandler) earnedBadges(username string) []models.EarnedBadge {
+	badges, err := h.achievements.EarnedBadges(username)
+	if err != nil {
+		log.Printf("Error listing badges of %s: %v", username, err)
+		return []models.EarnedBadge{}
+	}
+	return badges
+}
+
+// GetBadgeSVG serves a user's badges as SVG images: /badges/{username}.svg shows their level and
+// badge count, and /badges/{username}/{badgeId}.svg one badge they earned
+func (h *APIHandler) GetBadgeSVG(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/badges/")
+	if !strings.HasSuffix(path, ".svg") {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.Split(strings.TrimSuffix(path, ".svg"), "/")
+	if len(parts) > 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	username := parts[0]
+	if !services.ValidGitHubUsername(username) {
+		http.NotFound(w, r)
+		return
+	}
+	badges := h.earnedBadges(username)
+
+	var label, message, color string
+	if len(parts) == 1 {
+		if len(badges) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		label = username
+		message = fmt.Sprintf("%s · %d badges", services.LevelTitle(badges), len(badges))
+		color = "00add8"
+	} else {
+		found := false
+		for _, badge := range badges {
+			if badge.ID == parts[1] {
+				label = badge.Icon + " " + badge.Name
+				message = badge.AwardedAt.UTC().Format("2006-01-02")
+				color = badge.Color
+				found = true
+				break
+			}
+		}
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "max-age=300")
+	w.Write(renderBadgeSVG(label, message, color))
+}
+
+// renderBadgeSVG draws a flat two-part badge in the style of shields.io
+func renderBadgeSVG(label, message, color string) []byte {
+	// Text widths are estimated, as the SVG has no font metrics to measure with
+	textWidth := func(text string) int {
+		return utf8.RuneCountInString(text)*7 + 12
+	}
+	labelWidth := textWidth(label)
+	messageWidth := textWidth(message)
+	width := labelWidth + messageWidth
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+  <title>%[4]s: %[5]s</title>
+  <linearGradient id="s" x2="0" y2="100%%">
+    <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+    <stop offset="1" stop-opacity=".1"/>
+  </linearGradient>
+  <clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+  <g clip-path="url(#r)">
+    <rect width="%[2]d" height="20" fill="#555"/>
+    <rect x="%[2]d" width="%[3]d" height="20" fill="#%[6]s"/>
+    <rect width="%[1]d" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="%[7]d" y="14">%[4]s</text>
+    <text x="%[8]d" y="14">%[5]s</text>
+  </g>
+</svg>
+`, width, labelWidth, messageWidth, html.EscapeString(label), html.EscapeString(message), html.EscapeString(color),
+		labelWidth/2, labelWidth+messageWidth/2))
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"web-ui/internal/services"
+)
+
+// adminAttempt summarizes any user's attempt for the admin attempt list
+type adminAttempt struct {
+	ID                 int64     `json:"id"`
+	Username           string    `json:"username"`
+	ChallengeID        int       `json:"challengeId"`
+	PackageName        string    `json:"packageName,omitempty"`
+	PackageChallengeID string    `json:"packageChallengeId,omitempty"`
+	Action             string    `json:"action"`
+	SubmittedAt        time.Time `json:"submittedAt"`
+	Passed             bool      `json:"passed"`
+	TestsPassed        int       `json:"testsPassed"`
+	TestsTotal         int       `json:"testsTotal"`
+	ExecutionMs        int64     `json:"executionMs"`
+}
+
+// ReloadChallenges reads the challenges and their scoreboards from the workspace again, so edits
+// and merged submissions show up without a restart. Route: POST /api/admin/reload
+func (h *APIHandler) ReloadChallenges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.challengeService.LoadChallenges(); err != nil {
+		log.Printf("Error reloading challenges: %v", err)
+		http.Error(w, "Failed to reload challenges", http.StatusInternalServerError)
+		return
+	}
+	challenges := h.challengeService.GetChallenges()
+	if err := h.scoreboardService.LoadScoreboards(challenges); err != nil {
+		log.Printf("Error reloading scoreboards: %v", err)
+		http.Error(w, "Failed to reload scoreboards", http.StatusInternalServerError)
+		return
+	}
+
+	// Attempt status is derived from the files just reloaded
+	h.userService.ClearCache()
+
+	username, _ := h.auth.Role(r)
+	log.Printf("Challenges reloaded by %s", username)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"challenges":  len(challenges),
+		"scoreboards": len(h.scoreboardService.GetAllScoreboards()),
+	})
+}
+
+// ClearCaches drops the cached run results and users' cached progress. Route: POST /api/admin/clear-caches
+func (h *APIHandler) ClearCaches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.executionService.ClearCaches()
+	users := h.userService.ClearCache()
+
+	username, _ := h.auth.Role(r)
+	log.Printf("Caches cleared by %s", username)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results": "cleared",
+		"users":   users,
+	})
+}
+
+// GetQueue returns the execution queue's workers and the jobs queued or running. Route: GET /api/admin/queue
+func (h *APIHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.executionService.GetQueueStats())
+}
+
+// ListAllAttempts returns the attempts of any user across challenges, newest first, without their code.
+// Route: GET /api/admin/attempts. Query parameters: username, challengeId, packageName, packageChallengeId
+// and limit, all optional. Without packageName, only core challenges are listed.
+func (h *APIHandler) ListAllAttempts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	query := services.SubmissionQuery{
+		Username:           params.Get("username"),
+		PackageName:        params.Get("packageName"),
+		PackageChallengeID: params.Get("packageChallengeId"),
+		Limit:              defaultSubmissionsLimit,
+	}
+	if query.PackageChallengeID != "" && query.PackageName == "" {
+		http.Error(w, "packageChallengeId requires packageName", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if value := params.Get("challengeId"); value != "" {
+		if query.ChallengeID, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "Invalid challengeId", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := params.Get("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit < 1 || query.Limit > maxSubmissionsLimit {
+			http.Error(w, fmt.Sprintf("Invalid limit, expected 1 to %d", maxSubmissionsLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	attempts, err := h.submissionStore.Query(query)
+	if err != nil {
+		log.Printf("Error querying attempts: %v", err)
+		http.Error(w, "Failed to load attempts", http.StatusInternalServerError)
+		return
+	}
+
+	summaries := make([]adminAttempt, 0, len(attempts))
+	for _, attempt := range attempts {
+		summaries = append(summaries, adminAttempt{
+			ID:                 attempt.ID,
+			Username:           attempt.Username,
+			ChallengeID:        attempt.ChallengeID,
+			PackageName:        attempt.PackageName,
+			PackageChallengeID: attempt.PackageChallengeID,
+			Action:             attempt.Action,
+			SubmittedAt:        attempt.SubmittedAt,
+			Passed:             attempt.Passed,
+			TestsPassed:        attempt.TestsPassed,
+			TestsTotal:         attempt.TestsTotal,
+			ExecutionMs:        attempt.ExecutionMs,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -15,6 +16,7 @@ import (
 	"time"
 
 	"web-ui/internal/models"
+	"web-ui/internal/scoreboard"
 	"web-ui/internal/services"
 	"web-ui/internal/utils"
 )
@@ -152,7 +154,14 @@ type APIHandler struct {
 	executionService  *services.ExecutionService
 	packageService    *services.PackageService
 	aiService         *services.AIService
-	submissions       []models.Submission
+	submissionStore   services.SubmissionStore
+	cohortStore       services.CohortStore
+	auth              *services.AuthService
+	scoring           services.ScoringModel
+	git               *services.GitService
+	webhooks          *services.WebhookService
+	achievements      *services.AchievementEngine
+	events            *services.EventHub
 }
 
 // NewAPIHandler creates a new API handler
@@ -163,6 +172,11 @@ func NewAPIHandler(
 	executionService *services.ExecutionService,
 	packageService *services.PackageService,
 	aiService *services.AIService,
+	submissionStore services.SubmissionStore,
+	achievementStore services.AchievementStore,
+	cohortStore services.CohortStore,
+	webhookDeliveries services.WebhookDeliveryStore,
+	auth *services.AuthService,
 ) *APIHandler {
 	return &APIHandler{
 		challengeService:  challengeService,
@@ -171,7 +185,14 @@ func NewAPIHandler(
 		executionService:  executionService,
 		packageService:    packageService,
 		aiService:         aiService,
-		submissions:       make([]models.Submission, 0),
+		submissionStore:   submissionStore,
+		cohortStore:       cohortStore,
+		auth:              auth,
+		scoring:           services.NewScoringModelFromEnv(),
+		git:               services.NewGitServiceFromEnv(),
+		webhooks:          services.NewWebhookServiceFromEnv(webhookDeliveries),
+		achievements:      services.NewAchievementEngine(achievementStore, packageService.GetPackages()),
+		events:            services.NewEventHub(),
 	}
 }
 
@@ -240,6 +261,13 @@ func (h *APIHandler) createSubmission(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Submissions are made as the signed-in user
+	username, ok := h.requireUser(w, r, submission.Username)
+	if !ok {
+		return
+	}
+	submission.Username = username
+
 	// Set submission timestamp
 	submission.SubmittedAt = time.Now()
 
@@ -250,28 +278,132 @@ func (h *APIHandler) createSubmission(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Run the code
-	result := h.executionService.RunCode(submission.Code, challenge)
-	submission.Passed = result.Passed
-	submission.TestOutput = result.Output
-	submission.ExecutionMs = result.ExecutionMs
+	// Run the code with coverage for review, benchmarking it if the challenge is scored by speedup
+	options := services.RunOptions{Coverage: true}
+	if challenge.BenchmarkCount > 0 {
+		options.Benchmark = &services.BenchmarkOptions{Count: challenge.BenchmarkCount}
+	}
+	result := h.executionService.RunCode(r.Context(), submission.Code, challenge, options)
 
 	// Store submission
-	h.submissions = append(h.submissions, submission)
+	submission, err = h.submissionStore.Save(newAttempt(models.ActionSubmit, submission.Username, coreTarget(challenge.ID), submission.Code, submission.SubmittedAt, result))
+	if err != nil {
+		log.Printf("Error storing submission: %v", err)
+		http.Error(w, "Failed to store submission", http.StatusInternalServerError)
+		return
+	}
 
 	// Add to scoreboard if passed
 	if submission.Passed {
+		previousChallengeRank := h.challengeBoardRank(challenge, submission.Username)
+		previousMainRank := h.calculateMainScoreboardRank(submission.Username)
+
 		h.scoreboardService.AddSubmission(submission)
+
+		// Tell live scoreboards about the submission and any rank it moved
+		event := services.HubEvent{
+			Username:    submission.Username,
+			ChallengeID: challenge.ID,
+			TestsPassed: submission.TestsPassed,
+			TestsTotal:  submission.TestsTotal,
+			At:          submission.SubmittedAt,
+		}
+		passedEvent := event
+		passedEvent.Type = services.HubEventSubmissionPassed
+		h.events.Publish(passedEvent)
+
+		challengeEvent := event
+		challengeEvent.Leaderboard = services.LeaderboardChallenge
+		h.publishRankChange(challengeEvent, previousChallengeRank, h.challengeBoardRank(challenge, submission.Username))
+
+		mainEvent := event
+		mainEvent.Leaderboard = services.LeaderboardMain
+		h.publishRankChange(mainEvent, previousMainRank, h.calculateMainScoreboardRank(submission.Username))
+
+		h.evaluateAchievements(services.AchievementEvent{
+			Type:     services.EventSubmissionPassed,
+			Username: submission.Username,
+			At:       submission.SubmittedAt,
+		}, "", "")
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(submission)
 }
 
-// getSubmissions returns all submissions
+// defaultSubmissionsLimit and maxSubmissionsLimit bound how many submissions one request returns
+const (
+	defaultSubmissionsLimit = 50
+	maxSubmissionsLimit     = 500
+)
+
+// getSubmissions returns the submissions of a user or a challenge, newest first.
+// Query parameters: username, challengeId, packageName and packageChallengeId, since and until (RFC 3339), limit.
+// Without packageName, only core challenges are returned.
 func (h *APIHandler) getSubmissions(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := services.SubmissionQuery{
+		Username:           params.Get("username"),
+		PackageName:        params.Get("packageName"),
+		PackageChallengeID: params.Get("packageChallengeId"),
+		Action:             models.ActionSubmit,
+		Limit:              defaultSubmissionsLimit,
+	}
+
+	var err error
+	if value := params.Get("challengeId"); value != "" {
+		if query.ChallengeID, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "Invalid challengeId", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := params.Get("since"); value != "" {
+		if query.Since, err = time.Parse(time.RFC3339, value); err != nil {
+			http.Error(w, "Invalid since, expected an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := params.Get("until"); value != "" {
+		if query.Until, err = time.Parse(time.RFC3339, value); err != nil {
+			http.Error(w, "Invalid until, expected an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := params.Get("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit < 1 || query.Limit > maxSubmissionsLimit {
+			http.Error(w, fmt.Sprintf("Invalid limit, expected 1 to %d", maxSubmissionsLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Listing every submission of every user is not allowed
+	if query.PackageChallengeID != "" && query.PackageName == "" {
+		http.Error(w, "packageChallengeId requires packageName", http.StatusBadRequest)
+		return
+	}
+	if query.Username == "" && query.ChallengeID == 0 && query.PackageChallengeID == "" {
+		http.Error(w, "Filter by username, challengeId or packageChallengeId", http.StatusBadRequest)
+		return
+	}
+
+	// Submissions include their code, so users see only their own unless they coach
+	if query.Username != "" {
+		if !h.canViewAttempts(w, r, query.Username) {
+			return
+		}
+	} else if _, ok := authorizeRole(h.auth, w, r, services.RoleCoach); !ok {
+		return
+	}
+
+	submissions, err := h.submissionStore.Query(query)
+	if err != nil {
+		log.Printf("Error querying submissions: %v", err)
+		http.Error(w, "Failed to load submissions", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(h.submissions)
+	json.NewEncoder(w).Encode(submissions)
 }
 
 // GetScoreboard returns the scoreboard for a challenge
@@ -294,6 +426,18 @@ func (h *APIHandler) GetScoreboard(w http.ResponseWriter, r *http.Request) {
 		scoreboard = []models.ScoreboardEntry{}
 	}
 
+	// ?sort=speedup ranks each user's best benchmark result
+	if r.URL.Query().Get("sort") == "speedup" {
+		scoreboard = h.scoreboardService.GetSpeedupRanking(id)
+	}
+
+	// ?cohort= keeps only the cohort's members
+	cohort, ok := requestCohort(h.cohortStore, w, r)
+	if !ok {
+		return
+	}
+	scoreboard = cohortScoreboard(scoreboard, cohort)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(scoreboard)
 }
@@ -308,6 +452,7 @@ func (h *APIHandler) RunCode(w http.ResponseWriter, r *http.Request) {
 	var request struct {
 		ChallengeID int    `json:"challengeId"`
 		Code        string `json:"code"`
+		services.RunOptions
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&request)
@@ -316,56 +461,257 @@ func (h *APIHandler) RunCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := request.RunOptions.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	challenge, exists := h.challengeService.GetChallenge(request.ChallengeID)
 	if !exists {
 		http.Error(w, "Challenge not found", http.StatusNotFound)
 		return
 	}
 
-	result := h.executionService.RunCode(request.Code, challenge)
+	// The request context cancels the run if the client disconnects
+	result := h.executionService.RunCode(r.Context(), request.Code, challenge, request.RunOptions)
+	// Runs of signed-in users are recorded as attempts
+	h.recordRun(h.auth.Username(r), coreTarget(challenge.ID), request.Code, result)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
-// SaveSubmissionToFilesystem saves a submission to the filesystem
-func (h *APIHandler) SaveSubmissionToFilesystem(w http.ResponseWriter, r *http.Request) {
+// HandleJobs queues a code run and returns its job ID without waiting for the result
+func (h *APIHandler) HandleJobs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var request services.SaveSubmissionRequest
+	var request struct {
+		ChallengeID        int    `json:"challengeId"`
+		PackageName        string `json:"packageName"`
+		PackageChallengeID string `json:"packageChallengeId"`
+		Code               string `json:"code"`
+		services.RunOptions
+	}
+
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
 		http.Error(w, "Invalid request data", http.StatusBadRequest)
 		return
 	}
 
-	if request.Username == "" {
-		http.Error(w, "Username is required", http.StatusBadRequest)
+	if err := request.RunOptions.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Set username cookie
-	h.setUsernameCookie(w, request.Username)
+	// Jobs can target either a core challenge or a package challenge
+	var challenge *models.Challenge
+	var target attemptTarget
+	if request.PackageName != "" {
+		packageChallenge, err := h.packageService.GetPackageChallenge(request.PackageName, request.PackageChallengeID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Challenge not found: %v", err), http.StatusNotFound)
+			return
+		}
+		challenge = services.ExecutionChallenge(packageChallenge)
+		target = packageTarget(request.PackageName, packageChallenge.ID)
+	} else {
+		var exists bool
+		challenge, exists = h.challengeService.GetChallenge(request.ChallengeID)
+		if !exists {
+			http.Error(w, "Challenge not found", http.StatusNotFound)
+			return
+		}
+		target = coreTarget(challenge.ID)
+	}
 
-	// Validate challenge exists
-	_, exists := h.challengeService.GetChallenge(request.ChallengeID)
+	username := h.auth.Username(r)
+	job, err := h.executionService.SubmitJob(username, request.Code, challenge, request.RunOptions)
+	if err == services.ErrQueueFull {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to queue run: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Record a signed-in user's run once a worker has finished it
+	if username != "" {
+		go h.recordJob(job.ID, username, target, request.Code)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// HandleJob returns the status, queue position and result of a job, or cancels it
+func (h *APIHandler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, exists := h.executionService.GetJob(id)
 	if !exists {
-		http.Error(w, "Challenge not found", http.StatusNotFound)
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if !h.canAccessJob(w, r, job) {
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+	case "DELETE":
+		if !h.executionService.CancelJob(id) {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+
+		job, _ = h.executionService.GetJob(id)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// canAccessJob reports whether the signed-in user may read or cancel a job: their own, or anyone's for
+// admins. A job queued without signing in has no owner, so its random ID is all that protects it.
+// Otherwise it writes 401 or 403.
+func (h *APIHandler) canAccessJob(w http.ResponseWriter, r *http.Request, job services.JobStatus) bool {
+	if job.Username == "" {
+		return true
+	}
+	viewer, role := h.auth.Role(r)
+	if viewer == "" {
+		http.Error(w, "Sign in to continue", http.StatusUnauthorized)
+		return false
+	}
+	if !strings.EqualFold(viewer, job.Username) && !role.Allows(services.RoleAdmin) {
+		http.Error(w, "Only admins can access other users' jobs", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// SaveSubmissionToFilesystem saves the signed-in user's solution to a core or package challenge
+// into the repository, ready to commit
+func (h *APIHandler) SaveSubmissionToFilesystem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	response := h.executionService.SaveSubmissionToFilesystem(request)
+	target, username, code, ok := h.solutionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Package leaderboards are read from the saved solutions, so saving one can move the user
+	previousPackageRank := 0
+	if target.IsPackage() {
+		previousPackageRank = h.packageBoardRank(target.PackageName, username)
+	}
+
+	response, err := services.SaveSubmission(target, username, code)
+	if err != nil {
+		log.Printf("Error saving submission for %s: %v", username, err)
+		http.Error(w, "Failed to save solution", http.StatusInternalServerError)
+		return
+	}
 
 	// Clear user attempts cache
-	h.userService.RefreshUserAttempts(request.Username, h.challengeService.GetChallenges())
+	if !target.IsPackage() {
+		h.userService.RefreshUserAttempts(username, h.challengeService.GetChallenges())
+	} else {
+		event := services.HubEvent{
+			Username:           username,
+			PackageName:        target.PackageName,
+			PackageChallengeID: target.ChallengeID,
+			Leaderboard:        services.LeaderboardPackage,
+			At:                 time.Now(),
+		}
+		h.publishRankChange(event, previousPackageRank, h.packageBoardRank(target.PackageName, username))
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// solutionRequest decodes a signed-in user's solution to an existing challenge. A request with a
+// packageName targets that package's challenge; otherwise challengeId is a core challenge's number.
+// It writes an error response and returns false if the request is not valid.
+func (h *APIHandler) solutionRequest(w http.ResponseWriter, r *http.Request) (services.SubmissionTarget, string, string, bool) {
+	var request struct {
+		Username    string          `json:"username"`
+		PackageName string          `json:"packageName"`
+		ChallengeID json.RawMessage `json:"challengeId"` // A number or a string
+		Code        string          `json:"code"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return services.SubmissionTarget{}, "", "", false
+	}
+
+	// Solutions are saved under the signed-in user's name
+	username, ok := h.requireUser(w, r, request.Username)
+	if !ok {
+		return services.SubmissionTarget{}, "", "", false
+	}
+
+	target := services.SubmissionTarget{
+		PackageName: request.PackageName,
+		ChallengeID: challengeIDString(request.ChallengeID),
+	}
+	if !h.requireSubmissionTarget(w, target, username) {
+		return services.SubmissionTarget{}, "", "", false
+	}
+	return target, username, request.Code, true
+}
+
+// requireSubmissionTarget writes an error response and returns false unless target names an
+// existing challenge that username can save a solution to
+func (h *APIHandler) requireSubmissionTarget(w http.ResponseWriter, target services.SubmissionTarget, username string) bool {
+	if _, err := services.SubmissionPath(target, username); err != nil {
+		http.Error(w, "Invalid challenge", http.StatusBadRequest)
+		return false
+	}
+
+	// Validate challenge exists
+	if target.IsPackage() {
+		if _, err := h.packageService.GetPackageChallenge(target.PackageName, target.ChallengeID); err != nil {
+			http.Error(w, "Challenge not found", http.StatusNotFound)
+			return false
+		}
+	} else {
+		challengeID, _ := strconv.Atoi(target.ChallengeID)
+		if _, exists := h.challengeService.GetChallenge(challengeID); !exists {
+			http.Error(w, "Challenge not found", http.StatusNotFound)
+			return false
+		}
+	}
+	return true
+}
+
+// challengeIDString returns a challenge ID sent as a JSON string, or as a number for core challenges
+func challengeIDString(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	return string(raw)
+}
+
 // RefreshUserAttempts refreshes user's attempt cache
 func (h *APIHandler) RefreshUserAttempts(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -406,7 +752,8 @@ func (h *APIHandler) RefreshUserAttempts(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetGitUsername returns the username extracted from git configuration
+// GetGitUsername returns the username extracted from git configuration. It only suggests a name
+// for the sign-in form; who a request acts as always comes from its session.
 func (h *APIHandler) GetGitUsername(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -431,20 +778,6 @@ func (h *APIHandler) GetGitUsername(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// setUsernameCookie sets the username cookie
-func (h *APIHandler) setUsernameCookie(w http.ResponseWriter, username string) {
-	// Cookie expires in 30 days
-	expiration := time.Now().Add(30 * 24 * time.Hour)
-	cookie := http.Cookie{
-		Name:     "username",
-		Value:    username,
-		Expires:  expiration,
-		Path:     "/",
-		HttpOnly: false, // Allow JavaScript to access it
-	}
-	http.SetCookie(w, &cookie)
-}
-
 // GetMainScoreboardRank returns the user's rank in the main scoreboard
 func (h *APIHandler) GetMainScoreboardRank(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
@@ -477,59 +810,11 @@ func (h *APIHandler) GetMainScoreboardRank(w http.ResponseWriter, r *http.Reques
 
 // calculateMainScoreboardRank calculates the user's rank based on completed challenges
 func (h *APIHandler) calculateMainScoreboardRank(username string) int {
-	// Get all users and their completion counts (only count if ALL tests passed)
-	challenges := h.challengeService.GetChallenges()
-	userCompletions := make(map[string]int)
-
-	// Process all challenge scoreboards to count actual completions
-	for challengeID := range challenges {
-		// Read scoreboard file directly to check test results
-		scoreboardPath := fmt.Sprintf("../challenge-%d/SCOREBOARD.md", challengeID)
-		content, err := ioutil.ReadFile(scoreboardPath)
-		if err != nil {
-			// Try alternative path
-			scoreboardPath = fmt.Sprintf("challenge-%d/SCOREBOARD.md", challengeID)
-			content, err = ioutil.ReadFile(scoreboardPath)
-			if err != nil {
-				continue
-			}
-		}
-
-		// Parse scoreboard to find users who passed ALL tests
-		lines := strings.Split(string(content), "\n")
-		for _, line := range lines {
-			// Skip header and separator lines
-			if !strings.Contains(line, "|") || strings.Contains(line, "Username") || strings.Contains(line, "---") {
-				continue
-			}
-
-			parts := strings.Split(line, "|")
-			if len(parts) < 4 {
-				continue
-			}
-
-			username := strings.TrimSpace(parts[1])
-			passedTestsStr := strings.TrimSpace(parts[2])
-			totalTestsStr := strings.TrimSpace(parts[3])
-
-			// Skip empty usernames or placeholders
-			if username == "" || username == "------" {
-				continue
-			}
-
-			// Parse test counts
-			passedTests, err1 := strconv.Atoi(passedTestsStr)
-			totalTests, err2 := strconv.Atoi(totalTestsStr)
-
-			// Only count as completed if ALL tests passed
-			if err1 == nil && err2 == nil && passedTests > 0 && passedTests == totalTests {
-				userCompletions[username]++
-			}
-		}
-	}
+	// Get all users and their completed challenges (only count if ALL tests passed)
+	userCompletions := challengeCompletions(h.challengeScoreboards())
 
 	// Get the target user's completion count
-	targetCompletions := userCompletions[username]
+	targetCompletions := len(userCompletions[username])
 	if targetCompletions == 0 {
 		return 0 // User is unranked
 	}
@@ -537,7 +822,7 @@ func (h *APIHandler) calculateMainScoreboardRank(username string) int {
 	// Count how many users have more completions (following Python script logic)
 	rank := 1
 	for user, completions := range userCompletions {
-		if user != username && completions > targetCompletions {
+		if user != username && len(completions) > targetCompletions {
 			rank++
 		}
 	}
@@ -545,27 +830,52 @@ func (h *APIHandler) calculateMainScoreboardRank(username string) int {
 	return rank
 }
 
-// GetMainLeaderboard returns the main leaderboard data
+// GetMainLeaderboard returns the main leaderboard data.
+// Query parameters: period (week or month), since and until (RFC 3339 or YYYY-MM-DD) to count only
+// completions in a window, ranking=points to rank users by points or ranking=improved to rank
+// them by how much they improved on the window before, and cohort to rank only a cohort's members
+// and include the cohort's stats.
 func (h *APIHandler) GetMainLeaderboard(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Calculate leaderboard data
-	leaderboard := h.calculateMainLeaderboard()
+	cohort, ok := requestCohort(h.cohortStore, w, r)
+	if !ok {
+		return
+	}
+	leaderboard, window, err := h.rankMainLeaderboard(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	leaderboard = cohortLeaderboard(leaderboard, cohort)
 
 	// Include total number of classic challenges for dynamic UI rendering
 	totalChallenges := len(h.challengeService.GetChallenges())
 
 	response := struct {
-		Leaderboard     []LeaderboardUser `json:"leaderboard"`
-		Success         bool              `json:"success"`
-		TotalChallenges int               `json:"totalChallenges"`
+		Leaderboard     []LeaderboardUser   `json:"leaderboard"`
+		Success         bool                `json:"success"`
+		TotalChallenges int                 `json:"totalChallenges"`
+		Since           string              `json:"since,omitempty"`
+		Until           string              `json:"until,omitempty"`
+		UntimedEntries  int                 `json:"untimedEntries,omitempty"` // Entries left out of the window for lack of a submission time
+		Cohort          *models.Cohort      `json:"cohort,omitempty"`
+		CohortStats     *models.CohortStats `json:"cohortStats,omitempty"`
 	}{
 		Leaderboard:     leaderboard,
 		Success:         true,
 		TotalChallenges: totalChallenges,
+		Since:           formatWindowBound(window.Since),
+		Until:           formatWindowBound(window.Until),
+		UntimedEntries:  h.untimedEntries(window, cohort),
+		Cohort:          cohort,
+	}
+	if cohort != nil {
+		stats := h.cohortStats(*cohort)
+		response.CohortStats = &stats
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -585,27 +895,20 @@ func (h *APIHandler) GetPackageLeaderboard(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Load package and its challenges
-	pkg, err := h.packageService.GetPackage(packageName)
+	// Load package and its challenges in learning path order
+	pkg, challenges, err := h.packageLearningPath(packageName)
 	if err != nil {
 		http.Error(w, "Package not found", http.StatusNotFound)
 		return
 	}
 
-	// Build package challenge list in learning path order
-	challengesMap, err := h.packageService.GetPackageChallenges(packageName)
-	if err != nil {
-		challengesMap = make(map[string]*models.PackageChallenge)
-	}
-	var challenges []*models.PackageChallenge
-	for _, id := range pkg.LearningPath {
-		if ch, ok := challengesMap[id]; ok {
-			challenges = append(challenges, ch)
-		}
+	cohort, ok := requestCohort(h.cohortStore, w, r)
+	if !ok {
+		return
 	}
 
 	// Reuse existing creator to gather leaderboard
-	leaderboard := h.createPackageLeaderboard(packageName, challenges)
+	leaderboard := cohortPackageLeaderboard(h.createPackageLeaderboard(packageName, challenges), cohort)
 
 	response := map[string]interface{}{
 		"success":         true,
@@ -614,11 +917,34 @@ func (h *APIHandler) GetPackageLeaderboard(w http.ResponseWriter, r *http.Reques
 		"package":         pkg.Name,
 		"displayName":     pkg.DisplayName,
 	}
+	if cohort != nil {
+		response["cohort"] = cohort
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// packageLearningPath returns a package and its challenges in learning path order
+func (h *APIHandler) packageLearningPath(packageName string) (*models.Package, []*models.PackageChallenge, error) {
+	pkg, err := h.packageService.GetPackage(packageName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	challengesMap, err := h.packageService.GetPackageChallenges(packageName)
+	if err != nil {
+		challengesMap = make(map[string]*models.PackageChallenge)
+	}
+	var challenges []*models.PackageChallenge
+	for _, id := range pkg.LearningPath {
+		if ch, ok := challengesMap[id]; ok {
+			challenges = append(challenges, ch)
+		}
+	}
+	return pkg, challenges, nil
+}
+
 // createPackageLeaderboard builds the package leaderboard based on filesystem submissions
 func (h *APIHandler) createPackageLeaderboard(packageName string, challenges []*models.PackageChallenge) []models.PackageScoreboardEntry {
 	var leaderboard []models.PackageScoreboardEntry
@@ -634,7 +960,7 @@ func (h *APIHandler) createPackageLeaderboard(packageName string, challenges []*
 	sponsors := h.LoadSponsors()
 
 	for _, challenge := range challenges {
-		submissionsDir := filepath.Join("..", "packages", packageName, challenge.ID, "submissions")
+		submissionsDir := services.WorkspacePath("packages", packageName, challenge.ID, "submissions")
 		if _, err := os.Stat(submissionsDir); os.IsNotExist(err) {
 			continue
 		}
@@ -701,6 +1027,26 @@ func (h *APIHandler) createPackageLeaderboard(packageName string, challenges []*
 	return leaderboard
 }
 
+// challengeCompletions returns when each user completed each classic challenge they passed every test of.
+// Completion times are zero for scoreboards that do not record them.
+func challengeCompletions(boards map[int]*scoreboard.Board) map[string]map[int]time.Time {
+	userCompletions := make(map[string]map[int]time.Time)
+
+	for challengeID, board := range boards {
+		for _, entry := range board.Entries {
+			if !entry.Completed() {
+				continue
+			}
+			if userCompletions[entry.Username] == nil {
+				userCompletions[entry.Username] = make(map[int]time.Time)
+			}
+			userCompletions[entry.Username][challengeID] = entry.SubmittedAt
+		}
+	}
+
+	return userCompletions
+}
+
 // LeaderboardUser represents a user in the leaderboard
 type LeaderboardUser struct {
 	Username            string       `json:"username"`
@@ -710,104 +1056,52 @@ type LeaderboardUser struct {
 	Achievement         string       `json:"achievement"`
 	Rank                int          `json:"rank"`
 	IsSponsor           bool         `json:"isSponsor"`
+	Points              float64      `json:"points"`                  // Weighted by difficulty, including partly passed and package challenges
+	PackageCount        int          `json:"packageCount"`            // Package challenges completed
+	PreviousCount       int          `json:"previousCount,omitempty"` // Completions in the window before, for the most improved ranking
+	Improvement         int          `json:"improvement,omitempty"`   // CompletedCount - PreviousCount
 }
 
-// calculateMainLeaderboard calculates the main leaderboard data
-func (h *APIHandler) calculateMainLeaderboard() []LeaderboardUser {
-	challenges := h.challengeService.GetChallenges()
-	totalChallenges := len(challenges)
-	userCompletions := make(map[string]map[int]bool)
+// calculateMainLeaderboard ranks users by the challenges they completed in a window, or by their points
+func (h *APIHandler) calculateMainLeaderboard(window leaderboardWindow, byPoints bool) []LeaderboardUser {
+	totalChallenges := len(h.challengeService.GetChallenges())
+	boards := h.challengeScoreboards()
+	userCompletions := challengeCompletions(boards)
+	points := h.userPoints(boards, window)
 
 	// Load sponsor information
 	sponsors := h.LoadSponsors()
 
-	// Process all challenge scoreboards to find completions
-	for challengeID := range challenges {
-		// Read scoreboard file directly to check test results
-		scoreboardPath := fmt.Sprintf("../challenge-%d/SCOREBOARD.md", challengeID)
-		content, err := ioutil.ReadFile(scoreboardPath)
-		if err != nil {
-			// Try alternative path
-			scoreboardPath = fmt.Sprintf("challenge-%d/SCOREBOARD.md", challengeID)
-			content, err = ioutil.ReadFile(scoreboardPath)
-			if err != nil {
-				continue
-			}
-		}
-
-		// Parse scoreboard to find users who passed ALL tests
-		lines := strings.Split(string(content), "\n")
-		for _, line := range lines {
-			// Skip header and separator lines
-			if !strings.Contains(line, "|") || strings.Contains(line, "Username") || strings.Contains(line, "---") {
-				continue
-			}
-
-			parts := strings.Split(line, "|")
-			if len(parts) < 4 {
-				continue
-			}
-
-			username := strings.TrimSpace(parts[1])
-			passedTestsStr := strings.TrimSpace(parts[2])
-			totalTestsStr := strings.TrimSpace(parts[3])
-
-			// Skip empty usernames or placeholders
-			if username == "" || username == "------" {
-				continue
-			}
-
-			// Parse test counts
-			passedTests, err1 := strconv.Atoi(passedTestsStr)
-			totalTests, err2 := strconv.Atoi(totalTestsStr)
-
-			// Only count as completed if ALL tests passed
-			if err1 == nil && err2 == nil && passedTests > 0 && passedTests == totalTests {
-				if userCompletions[username] == nil {
-					userCompletions[username] = make(map[int]bool)
-				}
-				userCompletions[username][challengeID] = true
-			}
-		}
-	}
-
 	// Convert to leaderboard format
-	var leaderboard []LeaderboardUser
-	for username, completions := range userCompletions {
-		completedCount := len(completions)
-		completionRate := float64(completedCount) / float64(totalChallenges) * 100
-
-		// Determine achievement
-		achievement := "🌱 Beginner"
-		if completedCount >= 20 {
-			achievement = "🔥 Master"
-		} else if completedCount >= 15 {
-			achievement = "⭐ Expert"
-		} else if completedCount >= 10 {
-			achievement = "💪 Advanced"
-		} else if completedCount >= 5 {
-			achievement = "🚀 Intermediate"
+	leaderboard := []LeaderboardUser{}
+	for username, userPoints := range points {
+		completed := window.filter(userCompletions[username])
+		if len(completed) == 0 && !(byPoints && userPoints.Points > 0) {
+			continue
 		}
 
 		leaderboard = append(leaderboard, LeaderboardUser{
 			Username:            username,
-			CompletedCount:      completedCount,
-			CompletionRate:      completionRate,
-			CompletedChallenges: completions,
-			Achievement:         achievement,
+			CompletedCount:      len(completed),
+			CompletionRate:      float64(len(completed)) / float64(totalChallenges) * 100,
+			CompletedChallenges: completed,
+			Achievement:         services.CompletionTitle(len(userCompletions[username])),
 			IsSponsor:           sponsors[username],
+			Points:              services.RoundPoints(userPoints.Points),
+			PackageCount:        userPoints.PackageCount,
 		})
 	}
 
-	// Sort by completion count (descending), then by username
-	for i := 0; i < len(leaderboard); i++ {
-		for j := i + 1; j < len(leaderboard); j++ {
-			if leaderboard[j].CompletedCount > leaderboard[i].CompletedCount ||
-				(leaderboard[j].CompletedCount == leaderboard[i].CompletedCount && leaderboard[j].Username < leaderboard[i].Username) {
-				leaderboard[i], leaderboard[j] = leaderboard[j], leaderboard[i]
-			}
+	// Sort by points or completion count (descending), then by username
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if byPoints && leaderboard[i].Points != leaderboard[j].Points {
+			return leaderboard[i].Points > leaderboard[j].Points
 		}
-	}
+		if leaderboard[i].CompletedCount != leaderboard[j].CompletedCount {
+			return leaderboard[i].CompletedCount > leaderboard[j].CompletedCount
+		}
+		return leaderboard[i].Username < leaderboard[j].Username
+	})
 
 	// Assign ranks
 	for i := range leaderboard {
@@ -847,6 +1141,7 @@ func (h *APIHandler) HandlePackageChallenge(w http.ResponseWriter, r *http.Reque
 	var request struct {
 		Code     string `json:"code"`
 		Username string `json:"username"`
+		services.RunOptions
 	}
 
 	body, err := ioutil.ReadAll(r.Body)
@@ -866,6 +1161,21 @@ func (h *APIHandler) HandlePackageChallenge(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if err := request.RunOptions.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Submissions are scored on the tests alone, with coverage kept for review, and made as the signed-in user
+	if action == "submit" {
+		request.RunOptions = services.RunOptions{Coverage: true}
+		username, ok := h.requireUser(w, r, request.Username)
+		if !ok {
+			return
+		}
+		request.Username = username
+	}
+
 	// Use the existing package service
 	packageService := h.packageService
 
@@ -876,176 +1186,65 @@ func (h *APIHandler) HandlePackageChallenge(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Convert PackageChallenge to Challenge format for ExecutionService
-	challengeForExecution := &models.Challenge{
-		ID:       0, // Package challenges don't use numeric IDs
-		Title:    challenge.Title,
-		TestFile: challenge.TestFile,
-	}
+	challengeForExecution := services.ExecutionChallenge(challenge)
 
-	// Run the actual tests using ExecutionService
-	result := h.executionService.RunCode(request.Code, challengeForExecution)
+	// Run the actual tests using ExecutionService; a client disconnect cancels the run
+	result := h.executionService.RunCode(r.Context(), request.Code, challengeForExecution, request.RunOptions)
 
 	// Format response
 	response := map[string]interface{}{
 		"success":      result.Passed,
+		"status":       result.Status,
+		"killed":       result.Killed,
 		"execution_ms": result.ExecutionMs,
 		"output":       result.Output,
 	}
 
-	// Count passed tests from output for display
-	testsPassed, testsTotal := h.parseTestResults(result.Output)
-	response["tests_passed"] = testsPassed
-	response["tests_total"] = testsTotal
-
-	if action == "submit" && result.Passed {
-		response["message"] = "Solution submitted successfully!"
-		response["show_pr_instructions"] = true
+	// Structured per-test results from go test -json
+	response["tests"] = result.Tests
+	response["tests_passed"] = result.Summary.Passed
+	response["tests_total"] = result.Summary.Total
+	response["diagnostics"] = result.Diagnostics
+	response["coverage"] = result.Coverage
 
-		// Set username cookie if provided
-		if request.Username != "" {
-			h.setUsernameCookie(w, request.Username)
-		}
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-// parseTestResults parses Go test output to count passed and total tests
-func (h *APIHandler) parseTestResults(output string) (passed int, total int) {
-	lines := strings.Split(output, "\n")
-
-	for _, line := range lines {
-		// Look for test result lines like "--- PASS: TestGetUsers" or "--- FAIL: TestCreateUser"
-		if strings.Contains(line, "--- PASS:") {
-			passed++
-			total++
-		} else if strings.Contains(line, "--- FAIL:") {
-			total++
+	// Runs of signed-in users are recorded as attempts, and submissions are stored like core ones
+	if action == "test" {
+		h.recordRun(h.auth.Username(r), packageTarget(packageName, challenge.ID), request.Code, result)
+	} else {
+		submission, err := h.submissionStore.Save(newAttempt(models.ActionSubmit, request.Username, packageTarget(packageName, challenge.ID), request.Code, time.Now(), result))
+		if err != nil {
+			log.Printf("Error storing submission: %v", err)
+			http.Error(w, "Failed to store submission", http.StatusInternalServerError)
+			return
 		}
-	}
+		response["id"] = submission.ID
+
+		if submission.Passed {
+			response["message"] = "Solution submitted successfully!"
+			response["show_pr_instructions"] = true
+
+			h.events.Publish(services.HubEvent{
+				Type:               services.HubEventSubmissionPassed,
+				Username:           submission.Username,
+				PackageName:        packageName,
+				PackageChallengeID: challenge.ID,
+				TestsPassed:        submission.TestsPassed,
+				TestsTotal:         submission.TestsTotal,
+				At:                 submission.SubmittedAt,
+			})
 
-	// If no individual test results found, check for overall result
-	if total == 0 {
-		if strings.Contains(output, "PASS") && !strings.Contains(output, "FAIL") {
-			// Assume basic success case
-			passed = 1
-			total = 1
-		} else if strings.Contains(output, "FAIL") {
-			// Assume basic failure case
-			passed = 0
-			total = 1
+			h.evaluateAchievements(services.AchievementEvent{
+				Type:     services.EventPackageChallengePassed,
+				Username: submission.Username,
+				At:       submission.SubmittedAt,
+			}, packageName, challenge.ID)
 		}
 	}
 
-	return passed, total
-}
-
-// SavePackageChallengeToFilesystem saves a package challenge submission to the filesystem
-func (h *APIHandler) SavePackageChallengeToFilesystem(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var request struct {
-		Username    string `json:"username"`
-		PackageName string `json:"packageName"`
-		ChallengeID string `json:"challengeId"`
-		Code        string `json:"code"`
-	}
-
-	err := json.NewDecoder(r.Body).Decode(&request)
-	if err != nil {
-		http.Error(w, "Invalid request data", http.StatusBadRequest)
-		return
-	}
-
-	if request.Username == "" {
-		http.Error(w, "Username is required", http.StatusBadRequest)
-		return
-	}
-
-	// Set username cookie
-	h.setUsernameCookie(w, request.Username)
-
-	// Validate challenge exists
-	_, err = h.packageService.GetPackageChallenge(request.PackageName, request.ChallengeID)
-	if err != nil {
-		http.Error(w, "Challenge not found", http.StatusNotFound)
-		return
-	}
-
-	// Save to filesystem
-	response := h.savePackageChallengeToFilesystem(request)
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// savePackageChallengeToFilesystem handles the actual file saving for package challenges
-func (h *APIHandler) savePackageChallengeToFilesystem(request struct {
-	Username    string `json:"username"`
-	PackageName string `json:"packageName"`
-	ChallengeID string `json:"challengeId"`
-	Code        string `json:"code"`
-}) services.SaveSubmissionResponse {
-	// Get working directory for correct relative paths
-	workDir, _ := os.Getwd()
-
-	// Try different path approaches to handle potential path issues
-	var submissionDir string
-	var fileSaved bool
-
-	// Try multiple path options for package challenges
-	pathOptions := []string{
-		// Option 1: From web-ui directory (standard case)
-		filepath.Join("..", "packages", request.PackageName, request.ChallengeID, "submissions", request.Username),
-		// Option 2: From root workspace
-		filepath.Join("packages", request.PackageName, request.ChallengeID, "submissions", request.Username),
-		// Option 3: Absolute path from detected workspace root
-		filepath.Join(workDir, "..", "packages", request.PackageName, request.ChallengeID, "submissions", request.Username),
-	}
-
-	for _, dirPath := range pathOptions {
-		err := os.MkdirAll(dirPath, 0755)
-		if err != nil {
-			continue
-		}
-
-		solutionFile := filepath.Join(dirPath, "solution.go")
-		err = ioutil.WriteFile(solutionFile, []byte(request.Code), 0644)
-		if err != nil {
-			continue
-		}
-
-		submissionDir = dirPath
-		fileSaved = true
-		break
-	}
-
-	if !fileSaved {
-		return services.SaveSubmissionResponse{
-			Success: false,
-			Message: "Failed to save solution to any available path",
-		}
-	}
-
-	// Return success response with git commands
-	relativePath := filepath.Join("packages", request.PackageName, request.ChallengeID, "submissions", request.Username, "solution.go")
-	return services.SaveSubmissionResponse{
-		Success:  true,
-		Message:  "Solution saved to filesystem",
-		FilePath: filepath.Join(submissionDir, "solution.go"),
-		GitCommands: []string{
-			"cd " + filepath.Join(workDir, ".."),
-			fmt.Sprintf("git add %s", relativePath),
-			fmt.Sprintf("git commit -m \"Add solution for %s %s by %s\"", request.PackageName, request.ChallengeID, request.Username),
-			"git push origin main",
-		},
-	}
-}
-
 // AICodeReview performs AI-powered code review
 func (h *APIHandler) AICodeReview(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -1222,43 +1421,6 @@ func (h *APIHandler) AIDebugResponse(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// GitHubWebhookHandler handles GitHub sponsor webhooks
-func (h *APIHandler) GitHubWebhookHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Error reading request body", http.StatusBadRequest)
-		return
-	}
-
-	// Parse the webhook payload
-	var payload map[string]interface{}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
-		return
-	}
-
-	// Check if this is a sponsorship event
-	eventType := r.Header.Get("X-GitHub-Event")
-	if eventType == "sponsorship" {
-		// Clear the sponsor cache to force a refresh on next request
-		sponsorCache.mutex.Lock()
-		sponsorCache.sponsors = make(map[string]bool)
-		sponsorCache.lastUpdated = time.Time{} // Reset to zero time to force refresh
-		sponsorCache.mutex.Unlock()
-
-		fmt.Printf("Sponsor cache cleared due to webhook event: %s\n", eventType)
-	}
-
-	// Respond with 200 OK to acknowledge receipt
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
-}
-
 // GetSponsorsDebug returns current sponsors for debugging
 func (h *APIHandler) GetSponsorsDebug(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
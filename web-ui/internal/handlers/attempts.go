@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+	"web-ui/internal/services"
+)
+
+// attemptTarget is the challenge an attempt is for: a core challenge by ID, or a package challenge
+type attemptTarget struct {
+	challengeID        int
+	packageName        string
+	packageChallengeID string
+}
+
+// coreTarget returns the target of a core challenge
+func coreTarget(challengeID int) attemptTarget {
+	return attemptTarget{challengeID: challengeID}
+}
+
+// packageTarget returns the target of a package challenge
+func packageTarget(packageName, challengeID string) attemptTarget {
+	return attemptTarget{packageName: packageName, packageChallengeID: challengeID}
+}
+
+// newAttempt builds the stored record of a run or submit from its result
+func newAttempt(action, username string, target attemptTarget, code string, submittedAt time.Time, result services.ExecutionResult) models.Submission {
+	attempt := models.Submission{
+		Action:             action,
+		Username:           username,
+		ChallengeID:        target.challengeID,
+		PackageName:        target.packageName,
+		PackageChallengeID: target.packageChallengeID,
+		Code:               code,
+		SubmittedAt:        submittedAt,
+		Passed:             result.Passed,
+		TestOutput:         result.Output,
+		ExecutionMs:        result.ExecutionMs,
+		TestsPassed:        result.Summary.Passed,
+		TestsTotal:         result.Summary.Total,
+		Tests:              result.Tests,
+		Coverage:           result.Coverage,
+		Benchmarks:         result.Benchmarks,
+	}
+	if result.Benchmarks != nil {
+		attempt.Speedup = result.Benchmarks.Speedup
+	}
+	return attempt
+}
+
+// recordRun stores a finished test run as an attempt. Runs without a known user or
+// that were cancelled before finishing are not recorded.
+func (h *APIHandler) recordRun(username string, target attemptTarget, code string, result services.ExecutionResult) {
+	if username == "" || result.Status == services.ExecutionStatusCanceled {
+		return
+	}
+
+	if _, err := h.submissionStore.Save(newAttempt(models.ActionRun, username, target, code, time.Now(), result)); err != nil {
+		log.Printf("Error storing attempt: %v", err)
+	}
+}
+
+// recordJob waits for a queued run and stores it as an attempt
+func (h *APIHandler) recordJob(jobID, username string, target attemptTarget, code string) {
+	job, exists := h.executionService.WaitJob(context.Background(), jobID)
+	if !exists || job.Result == nil {
+		return
+	}
+	h.recordRun(username, target, code, *job.Result)
+}
+
+// ListAttempts returns a user's runs and submits of a challenge, newest first, without their code.
+// Query parameters: username, and challengeId for a core challenge or packageName and
+// packageChallengeId for a package challenge.
+func (h *APIHandler) ListAttempts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	query := services.SubmissionQuery{
+		Username:           params.Get("username"),
+		PackageName:        params.Get("packageName"),
+		PackageChallengeID: params.Get("packageChallengeId"),
+	}
+	if query.PackageName != "" {
+		if query.Username == "" || query.PackageChallengeID == "" {
+			http.Error(w, "username and packageChallengeId are required", http.StatusBadRequest)
+			return
+		}
+	} else {
+		var err error
+		query.ChallengeID, err = strconv.Atoi(params.Get("challengeId"))
+		if query.Username == "" || err != nil {
+			http.Error(w, "username and challengeId are required", http.StatusBadRequest)
+			return
+		}
+	}
+	if !h.canViewAttempts(w, r, query.Username) {
+		return
+	}
+
+	attempts, err := h.submissionStore.Query(query)
+	if err != nil {
+		log.Printf("Error querying attempts: %v", err)
+		http.Error(w, "Failed to load attempts", http.StatusInternalServerError)
+		return
+	}
+
+	// Versions count up from the first attempt
+	summaries := make([]models.AttemptSummary, 0, len(attempts))
+	for i, attempt := range attempts {
+		summaries = append(summaries, models.AttemptSummary{
+			ID:          attempt.ID,
+			Version:     len(attempts) - i,
+			Action:      attempt.Action,
+			SubmittedAt: attempt.SubmittedAt,
+			Passed:      attempt.Passed,
+			TestsPassed: attempt.TestsPassed,
+			TestsTotal:  attempt.TestsTotal,
+			ExecutionMs: attempt.ExecutionMs,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// HandleAttempt returns a single attempt with its code and test results at /api/attempts/{id},
+// or the unified diff between two attempts at /api/attempts/diff?from={id}&to={id}
+func (h *APIHandler) HandleAttempt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/attempts/")
+	if path == "diff" {
+		h.diffAttempts(w, r)
+		return
+	}
+
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid attempt ID", http.StatusBadRequest)
+		return
+	}
+
+	attempt, ok := h.loadAttempt(w, id)
+	if !ok || !h.canViewAttempts(w, r, attempt.Username) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attempt)
+}
+
+// diffAttempts returns the unified diff from one attempt's code to another's
+func (h *APIHandler) diffAttempts(w http.ResponseWriter, r *http.Request) {
+	fromID, fromErr := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	toID, toErr := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+	if fromErr != nil || toErr != nil {
+		http.Error(w, "from and to must be attempt IDs", http.StatusBadRequest)
+		return
+	}
+
+	from, ok := h.loadAttempt(w, fromID)
+	if !ok || !h.canViewAttempts(w, r, from.Username) {
+		return
+	}
+	to, ok := h.loadAttempt(w, toID)
+	if !ok || !h.canViewAttempts(w, r, to.Username) {
+		return
+	}
+
+	diff := services.UnifiedDiff(
+		fmt.Sprintf("attempt-%d/%s", from.ID, attemptFileName(from)),
+		fmt.Sprintf("attempt-%d/%s", to.ID, attemptFileName(to)),
+		from.Code, to.Code,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from": from.ID,
+		"to":   to.ID,
+		"diff": diff,
+	})
+}
+
+// attemptFileName is the solution file an attempt's code would be saved as
+func attemptFileName(attempt models.Submission) string {
+	if attempt.PackageName != "" {
+		return services.SubmissionTarget{PackageName: attempt.PackageName, ChallengeID: attempt.PackageChallengeID}.FileName()
+	}
+	return services.CoreSubmissionTarget(attempt.ChallengeID).FileName()
+}
+
+// canViewAttempts reports whether the signed-in user may see a user's attempts and their code:
+// their own, or anyone's for coaches and admins. Otherwise it writes 401 or 403.
+func (h *APIHandler) canViewAttempts(w http.ResponseWriter, r *http.Request, username string) bool {
+	viewer, role := h.auth.Role(r)
+	if viewer == "" {
+		http.Error(w, "Sign in to continue", http.StatusUnauthorized)
+		return false
+	}
+	if !strings.EqualFold(viewer, username) && !role.Allows(services.RoleCoach) {
+		http.Error(w, "Only coaches can view other users' attempts", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// loadAttempt fetches an attempt, writing the error response if it cannot
+func (h *APIHandler) loadAttempt(w http.ResponseWriter, id int64) (models.Submission, bool) {
+	attempt, exists, err := h.submissionStore.Get(id)
+	if err != nil {
+		log.Printf("Error loading attempt %d: %v", id, err)
+		http.Error(w, "Failed to load attempt", http.StatusInternalServerError)
+		return attempt, false
+	}
+	if !exists {
+		http.Error(w, "Attempt not found", http.StatusNotFound)
+		return attempt, false
+	}
+	return attempt, true
+}
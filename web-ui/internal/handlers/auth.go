@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"web-ui/internal/services"
+	"web-ui/internal/utils"
+)
+
+// RequireCSRF rejects POST, PUT, PATCH and DELETE requests that do not carry the CSRF token
+// of their cookie, except on the exempt paths, and gives every visitor a token.
+func RequireCSRF(auth *services.AuthService, next http.Handler, exempt ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "HEAD", "OPTIONS":
+			auth.CSRFToken(w, r)
+		default:
+			if !isExemptPath(r.URL.Path, exempt) && !auth.CheckCSRF(r) {
+				http.Error(w, "Missing or invalid CSRF token", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole lets only signed-in users holding at least role reach a handler
+func RequireRole(auth *services.AuthService, role services.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := authorizeRole(auth, w, r, role); ok {
+			next(w, r)
+		}
+	}
+}
+
+// authorizeRole returns the signed-in user if they hold at least role. It writes 401 if no one
+// is signed in and 403 if the user's role is too low.
+func authorizeRole(auth *services.AuthService, w http.ResponseWriter, r *http.Request, role services.Role) (string, bool) {
+	username, userRole := auth.Role(r)
+	if username == "" {
+		http.Error(w, "Sign in to continue", http.StatusUnauthorized)
+		return "", false
+	}
+	if !userRole.Allows(role) {
+		http.Error(w, "Requires the "+string(role)+" role", http.StatusForbidden)
+		return "", false
+	}
+	return username, true
+}
+
+// isExemptPath reports whether a path is one of the exempt paths
+func isExemptPath(path string, exempt []string) bool {
+	for _, exemptPath := range exempt {
+		if path == exemptPath {
+			return true
+		}
+	}
+	return false
+}
+
+// localNext returns next if it is a path on this server, or "/" so sign-in cannot redirect elsewhere
+func localNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// requireUser returns the signed-in user a write acts as. It writes 401 if no one is signed in,
+// and 403 if the request claims to be someone else.
+func (h *APIHandler) requireUser(w http.ResponseWriter, r *http.Request, claimed string) (string, bool) {
+	username := h.auth.Username(r)
+	if username == "" {
+		http.Error(w, "Sign in to continue", http.StatusUnauthorized)
+		return "", false
+	}
+	if claimed != "" && !strings.EqualFold(claimed, username) {
+		http.Error(w, "Signed in as "+username+", not "+claimed, http.StatusForbidden)
+		return "", false
+	}
+	return username, true
+}
+
+// authProviderInfo describes a sign-in option
+type authProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	LoginURL    string `json:"loginUrl"`
+}
+
+// authProviders lists the enabled sign-in options
+func authProviders(auth *services.AuthService) []authProviderInfo {
+	providers := []authProviderInfo{}
+	for _, provider := range auth.Providers() {
+		providers = append(providers, authProviderInfo{
+			Name:        provider.Name(),
+			DisplayName: provider.DisplayName(),
+			LoginURL:    "/auth/" + provider.Name() + "/login",
+		})
+	}
+	return providers
+}
+
+// GetSession returns who is signed in and how to sign in
+func (h *APIHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, authenticated := h.auth.Session(r)
+	_, role := h.auth.Role(r)
+	response := struct {
+		Authenticated bool               `json:"authenticated"`
+		Username      string             `json:"username,omitempty"`
+		Provider      string             `json:"provider,omitempty"`
+		Role          services.Role      `json:"role,omitempty"`
+		Providers     []authProviderInfo `json:"providers"`
+	}{
+		Authenticated: authenticated,
+		Username:      session.Username,
+		Provider:      session.Provider,
+		Role:          role,
+		Providers:     authProviders(h.auth),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// loginPageData is what the sign-in page shows
+type loginPageData struct {
+	Providers  []authProviderInfo
+	Next       string
+	Username   string // Signed-in user, if any
+	CSRFToken  string
+	Error      string
+	Authorize  bool // The local provider's authorization form
+	State      string
+	Redirect   string
+	Suggestion string // Username from git config, prefilled in the local form
+}
+
+// LoginPage renders the sign-in page at /login?next=
+func (h *WebHandler) LoginPage(w http.ResponseWriter, r *http.Request) {
+	h.renderLogin(w, r, loginPageData{
+		Providers: authProviders(h.auth),
+		Next:      localNext(r.URL.Query().Get("next")),
+		Username:  h.auth.Username(r),
+		Error:     r.URL.Query().Get("error"),
+	})
+}
+
+// renderLogin renders the sign-in page
+func (h *WebHandler) renderLogin(w http.ResponseWriter, r *http.Request, data loginPageData) {
+	tmpl, err := template.New("").Funcs(utils.GetTemplateFuncs()).ParseFS(h.content, "templates/base.html", "templates/login.html")
+	if err != nil {
+		log.Printf("Template error: %v", err)
+		http.Error(w, "Failed to parse template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data.CSRFToken = h.auth.CSRFToken(w, r)
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		log.Printf("Template execution error: %v", err)
+		// Don't call http.Error here since headers may already be sent during template execution
+	}
+}
+
+// HandleAuth serves the sign-in flow:
+//
+//	GET      /auth/{provider}/login?next=  redirect to the provider
+//	GET      /auth/{provider}/callback     finish signing in
+//	GET/POST /auth/local/authorize         the local provider's authorization form
+//	POST     /auth/logout                  sign out
+func (h *WebHandler) HandleAuth(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/auth/"), "/"), "/")
+
+	if len(parts) == 1 && parts[0] == "logout" {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.auth.EndSession(w)
+		http.Redirect(w, r, localNext(r.PostFormValue("next")), http.StatusSeeOther)
+		return
+	}
+
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	provider, exists := h.auth.Provider(parts[0])
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch parts[1] {
+	case "login":
+		state, err := h.auth.BeginSignIn(w, provider.Name(), localNext(r.URL.Query().Get("next")))
+		if err != nil {
+			log.Printf("Error starting sign-in: %v", err)
+			http.Error(w, "Failed to start sign-in", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, provider.AuthCodeURL(state, h.auth.RedirectURI(r, provider.Name())), http.StatusFound)
+	case "callback":
+		h.finishSignIn(w, r, provider)
+	case "authorize":
+		local, ok := provider.(*services.LocalAuthProvider)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		h.localAuthorize(w, r, local)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// finishSignIn redeems the code a provider redirected back with and starts a session
+func (h *WebHandler) finishSignIn(w http.ResponseWriter, r *http.Request, provider services.AuthProvider) {
+	params := r.URL.Query()
+	authState, ok := h.auth.FinishSignIn(w, r, provider.Name(), params.Get("state"))
+	if !ok {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Sign-in expired or was started elsewhere, please try again"), http.StatusSeeOther)
+		return
+	}
+	if providerError := params.Get("error"); providerError != "" {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape(provider.DisplayName()+" sign-in failed: "+providerError), http.StatusSeeOther)
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), params.Get("code"), h.auth.RedirectURI(r, provider.Name()))
+	if err != nil {
+		log.Printf("Error signing in with %s: %v", provider.Name(), err)
+		http.Redirect(w, r, "/login?error="+url.QueryEscape(provider.DisplayName()+" sign-in failed"), http.StatusSeeOther)
+		return
+	}
+
+	h.auth.StartSession(w, identity, provider.Name())
+	log.Printf("%s signed in with %s", identity.Username, provider.Name())
+	http.Redirect(w, r, authState.Next, http.StatusSeeOther)
+}
+
+// localAuthorize shows the local provider's form on GET and issues a code for the entered username on POST
+func (h *WebHandler) localAuthorize(w http.ResponseWriter, r *http.Request, provider *services.LocalAuthProvider) {
+	data := loginPageData{
+		Authorize: true,
+		State:     r.FormValue("state"),
+		Redirect:  r.FormValue("redirect_uri"),
+	}
+
+	// Codes only go back to this server's callback, at its configured base URL
+	redirect, err := url.Parse(data.Redirect)
+	if err != nil || data.Redirect != h.auth.RedirectURI(r, provider.Name()) || data.State == "" {
+		http.Error(w, "Invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		// Git config only suggests a name; the user confirms who they are
+		data.Suggestion = utils.GetGitUsername().Username
+		h.renderLogin(w, r, data)
+	case "POST":
+		username := strings.TrimSpace(r.PostFormValue("username"))
+		code, err := provider.Authorize(username, data.Redirect)
+		if err != nil {
+			data.Suggestion = username
+			data.Error = "Enter a valid GitHub username"
+			h.renderLogin(w, r, data)
+			return
+		}
+
+		query := redirect.Query()
+		query.Set("code", code)
+		query.Set("state", data.State)
+		redirect.RawQuery = query.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusSeeOther)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
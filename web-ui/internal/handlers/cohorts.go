@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+	"web-ui/internal/services"
+)
+
+// cohortIDPattern matches a cohort ID, which appears in URLs and query parameters
+var cohortIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// cohortIDFromName derives an ID from a cohort's name, e.g. "Spring 2025" becomes "spring-2025"
+func cohortIDFromName(name string) string {
+	var id strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && id.Len() > 0 {
+				id.WriteByte('-')
+			}
+			id.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if id.Len() > 64 {
+		return strings.TrimRight(id.String()[:64], "-")
+	}
+	return id.String()
+}
+
+// cohortSummary is a cohort in the cohort list
+type cohortSummary struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	CreatedAt   time.Time `json:"createdAt"`
+	MemberCount int       `json:"memberCount"`
+}
+
+// HandleCohorts lists cohorts on GET and creates one on POST /api/cohorts with
+// {"name": "Spring 2025", "id": "spring-2025", "members": ["alice"]}; the ID defaults to one derived from the name.
+func (h *APIHandler) HandleCohorts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		cohorts, err := h.cohortStore.List()
+		if err != nil {
+			log.Printf("Error listing cohorts: %v", err)
+			http.Error(w, "Failed to load cohorts", http.StatusInternalServerError)
+			return
+		}
+
+		summaries := make([]cohortSummary, 0, len(cohorts))
+		for _, cohort := range cohorts {
+			summaries = append(summaries, cohortSummary{
+				ID:          cohort.ID,
+				Name:        cohort.Name,
+				CreatedAt:   cohort.CreatedAt,
+				MemberCount: len(cohort.Members),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(summaries)
+	case "POST":
+		h.createCohort(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createCohort validates and stores a new cohort. Only coaches and admins manage cohorts.
+func (h *APIHandler) createCohort(w http.ResponseWriter, r *http.Request) {
+	if _, ok := authorizeRole(h.auth, w, r, services.RoleCoach); !ok {
+		return
+	}
+
+	var request struct {
+		ID      string   `json:"id"`
+		Name    string   `json:"name"`
+		Members []string `json:"members"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		http.Error(w, "Cohort name is required", http.StatusBadRequest)
+		return
+	}
+	if request.ID == "" {
+		request.ID = cohortIDFromName(request.Name)
+	}
+	if !cohortIDPattern.MatchString(request.ID) {
+		http.Error(w, "Invalid cohort ID, expected lowercase letters, digits and dashes", http.StatusBadRequest)
+		return
+	}
+	members := []string{}
+	seen := make(map[string]bool)
+	for _, username := range request.Members {
+		if !services.ValidGitHubUsername(username) {
+			http.Error(w, "Invalid username: "+username, http.StatusBadRequest)
+			return
+		}
+		// GitHub usernames ignore case, so Alice and alice are one member
+		username = strings.ToLower(username)
+		if !seen[username] {
+			seen[username] = true
+			members = append(members, username)
+		}
+	}
+
+	cohort := models.Cohort{
+		ID:        request.ID,
+		Name:      request.Name,
+		CreatedAt: time.Now().UTC(),
+		Members:   members,
+	}
+	if err := h.cohortStore.Create(cohort); err != nil {
+		if errors.Is(err, services.ErrCohortExists) {
+			http.Error(w, "Cohort already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("Error creating cohort %s: %v", cohort.ID, err)
+		http.Error(w, "Failed to create cohort", http.StatusInternalServerError)
+		return
+	}
+
+	created, _, err := h.cohortStore.Get(cohort.ID)
+	if err != nil {
+		created = cohort
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// HandleCohort serves a single cohort:
+//
+//	GET    /api/cohorts/{id}                     the cohort and its stats
+//	POST   /api/cohorts/{id}/members             add {"username": "alice"}
+//	DELETE /api/cohorts/{id}/members/{username}  remove a member
+//
+// Changing members requires the coach role.
+func (h *APIHandler) HandleCohort(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/cohorts/"), "/"), "/")
+	id := parts[0]
+	if !cohortIDPattern.MatchString(id) {
+		http.Error(w, "Invalid cohort ID", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.getCohort(w, id)
+	case len(parts) == 2 && parts[1] == "members":
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if _, ok := authorizeRole(h.auth, w, r, services.RoleCoach); !ok {
+			return
+		}
+		var request struct {
+			Username string `json:"username"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		h.changeCohortMember(w, id, request.Username, true)
+	case len(parts) == 3 && parts[1] == "members":
+		if r.Method != "DELETE" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if _, ok := authorizeRole(h.auth, w, r, services.RoleCoach); !ok {
+			return
+		}
+		h.changeCohortMember(w, id, parts[2], false)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// getCohort writes a cohort and its stats
+func (h *APIHandler) getCohort(w http.ResponseWriter, id string) {
+	cohort, exists, err := h.cohortStore.Get(id)
+	if err != nil {
+		log.Printf("Error loading cohort %s: %v", id, err)
+		http.Error(w, "Failed to load cohort", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Cohort not found", http.StatusNotFound)
+		return
+	}
+
+	response := struct {
+		models.Cohort
+		Stats models.CohortStats `json:"stats"`
+	}{
+		Cohort: cohort,
+		Stats:  h.cohortStats(cohort),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// changeCohortMember adds or removes a member and writes the updated cohort
+func (h *APIHandler) changeCohortMember(w http.ResponseWriter, id, username string, add bool) {
+	if !services.ValidGitHubUsername(username) {
+		http.Error(w, "Invalid username", http.StatusBadRequest)
+		return
+	}
+
+	username = strings.ToLower(username)
+
+	var changed bool
+	var err error
+	if add {
+		changed, err = h.cohortStore.AddMember(id, username)
+	} else {
+		changed, err = h.cohortStore.RemoveMember(id, username)
+	}
+	if errors.Is(err, services.ErrCohortNotFound) {
+		http.Error(w, "Cohort not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating cohort %s member %s: %v", id, username, err)
+		http.Error(w, "Failed to update cohort", http.StatusInternalServerError)
+		return
+	}
+
+	cohort, _, err := h.cohortStore.Get(id)
+	if err != nil {
+		log.Printf("Error loading cohort %s: %v", id, err)
+		http.Error(w, "Failed to load cohort", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if add && changed {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(cohort)
+}
+
+// requestCohort returns the cohort named by the cohort query parameter, or nil if there is none.
+// It writes an error and returns false if the cohort cannot be found.
+func requestCohort(store services.CohortStore, w http.ResponseWriter, r *http.Request) (*models.Cohort, bool) {
+	id := r.URL.Query().Get("cohort")
+	if id == "" {
+		return nil, true
+	}
+
+	cohort, exists, err := store.Get(id)
+	if err != nil {
+		log.Printf("Error loading cohort %s: %v", id, err)
+		http.Error(w, "Failed to load cohort", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !exists {
+		http.Error(w, "Cohort not found", http.StatusNotFound)
+		return nil, false
+	}
+	return &cohort, true
+}
+
+// cohortLeaderboard keeps a cohort's members on a ranked leaderboard and ranks them 1 to n
+func cohortLeaderboard(leaderboard []LeaderboardUser, cohort *models.Cohort) []LeaderboardUser {
+	if cohort == nil {
+		return leaderboard
+	}
+	members := []LeaderboardUser{}
+	for _, user := range leaderboard {
+		if cohort.HasMember(user.Username) {
+			user.Rank = len(members) + 1
+			members = append(members, user)
+		}
+	}
+	return members
+}
+
+// cohortPackageLeaderboard keeps a cohort's members on a package leaderboard
+func cohortPackageLeaderboard(leaderboard []models.PackageScoreboardEntry, cohort *models.Cohort) []models.PackageScoreboardEntry {
+	if cohort == nil {
+		return leaderboard
+	}
+	members := []models.PackageScoreboardEntry{}
+	for _, entry := range leaderboard {
+		if cohort.HasMember(entry.Username) {
+			members = append(members, entry)
+		}
+	}
+	return members
+}
+
+// cohortScoreboard keeps a cohort's members on a challenge scoreboard
+func cohortScoreboard(entries []models.ScoreboardEntry, cohort *models.Cohort) []models.ScoreboardEntry {
+	if cohort == nil {
+		return entries
+	}
+	members := []models.ScoreboardEntry{}
+	for _, entry := range entries {
+		if cohort.HasMember(entry.Username) {
+			members = append(members, entry)
+		}
+	}
+	return members
+}
+
+// cohortStats aggregates the progress of a cohort's members over all time
+func (h *APIHandler) cohortStats(cohort models.Cohort) models.CohortStats {
+	stats := models.CohortStats{
+		Members:    len(cohort.Members),
+		Challenges: []models.CohortChallengeStats{},
+		Packages:   []models.CohortPackageStats{},
+	}
+
+	challenges := h.challengeService.GetChallenges()
+	boards := h.challengeScoreboards()
+
+	// Members are matched to scoreboard entries by lowercase username
+	userCompletions := make(map[string]map[int]time.Time)
+	for username, completions := range challengeCompletions(boards) {
+		username = strings.ToLower(username)
+		if userCompletions[username] == nil {
+			userCompletions[username] = make(map[int]time.Time)
+		}
+		for challengeID, completedAt := range completions {
+			userCompletions[username][challengeID] = completedAt
+		}
+	}
+
+	completedCounts := make([]float64, 0, len(cohort.Members))
+	for _, username := range cohort.Members {
+		count := len(userCompletions[strings.ToLower(username)])
+		if count > 0 {
+			stats.ActiveMembers++
+		}
+		completedCounts = append(completedCounts, float64(count))
+	}
+	stats.MedianCompleted = median(completedCounts)
+	stats.MeanCompleted = mean(completedCounts)
+	if len(challenges) > 0 {
+		stats.MedianCompletionRate = stats.MedianCompleted / float64(len(challenges)) * 100
+	}
+
+	// A member attempted a challenge if it is on its scoreboard or they stored a submit of it
+	attempted := make(map[int]map[string]bool)
+	attempt := func(challengeID int, username string) {
+		if attempted[challengeID] == nil {
+			attempted[challengeID] = make(map[string]bool)
+		}
+		attempted[challengeID][strings.ToLower(username)] = true
+	}
+	for challengeID, board := range boards {
+		for _, entry := range board.Entries {
+			if cohort.HasMember(entry.Username) {
+				attempt(challengeID, entry.Username)
+			}
+		}
+	}
+	for _, username := range cohort.Members {
+		submissions, err := h.submissionStore.Query(services.SubmissionQuery{Username: username, Action: models.ActionSubmit})
+		if err != nil {
+			log.Printf("Warning: Could not load submissions of %s for cohort %s: %v", username, cohort.ID, err)
+			continue
+		}
+		for _, submission := range submissions {
+			attempt(submission.ChallengeID, username)
+		}
+	}
+
+	for challengeID, usernames := range attempted {
+		challenge, exists := challenges[challengeID]
+		if !exists {
+			continue
+		}
+		challengeStats := models.CohortChallengeStats{
+			ID:         challengeID,
+			Title:      challenge.Title,
+			Difficulty: challenge.Difficulty,
+			Attempted:  len(usernames),
+		}
+		for username := range usernames {
+			if _, completed := userCompletions[username][challengeID]; completed {
+				challengeStats.Completed++
+			}
+		}
+		challengeStats.CompletionRate = float64(challengeStats.Completed) / float64(challengeStats.Attempted) * 100
+		stats.Challenges = append(stats.Challenges, challengeStats)
+	}
+	sort.Slice(stats.Challenges, func(i, j int) bool {
+		return stats.Challenges[i].ID < stats.Challenges[j].ID
+	})
+
+	// The hardest challenge has the lowest completion rate; among equals, the one more members attempted
+	for i := range stats.Challenges {
+		candidate := &stats.Challenges[i]
+		hardest := stats.HardestChallenge
+		if hardest == nil || candidate.CompletionRate < hardest.CompletionRate ||
+			(candidate.CompletionRate == hardest.CompletionRate && candidate.Attempted > hardest.Attempted) {
+			hardestCopy := *candidate
+			stats.HardestChallenge = &hardestCopy
+		}
+	}
+
+	packageNames := make([]string, 0, len(h.packageService.GetPackages()))
+	for packageName := range h.packageService.GetPackages() {
+		packageNames = append(packageNames, packageName)
+	}
+	sort.Strings(packageNames)
+	for _, packageName := range packageNames {
+		pkg, packageChallenges, err := h.packageLearningPath(packageName)
+		if err != nil {
+			continue
+		}
+
+		completed := make(map[string]int)
+		for _, entry := range h.createPackageLeaderboard(packageName, packageChallenges) {
+			username := strings.ToLower(entry.Username)
+			if entry.TestsPassed > completed[username] {
+				completed[username] = entry.TestsPassed
+			}
+		}
+
+		packageStats := models.CohortPackageStats{
+			Name:            pkg.Name,
+			DisplayName:     pkg.DisplayName,
+			TotalChallenges: len(packageChallenges),
+		}
+		counts := make([]float64, 0, len(cohort.Members))
+		for _, username := range cohort.Members {
+			username = strings.ToLower(username)
+			if completed[username] > 0 {
+				packageStats.StartedMembers++
+			}
+			counts = append(counts, float64(completed[username]))
+		}
+		packageStats.MedianCompleted = median(counts)
+		stats.Packages = append(stats.Packages, packageStats)
+	}
+
+	return stats
+}
+
+// median returns the median of values, or 0 if there are none
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// mean returns the mean of values, or 0 if there are none
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"web-ui/internal/models"
+	"web-ui/internal/services"
+)
+
+// eventsHeartbeatInterval is how often an idle event stream sends a comment, so proxies keep it open
+const eventsHeartbeatInterval = 25 * time.Second
+
+// StreamEvents streams live leaderboard events as Server-Sent Events at /api/events.
+// Query parameters: challenge (a core challenge ID) and package (a package name) filter the events.
+func (h *APIHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var filter services.HubFilter
+	if challenge := r.URL.Query().Get("challenge"); challenge != "" {
+		id, err := strconv.Atoi(challenge)
+		if err != nil || id <= 0 {
+			http.Error(w, "Invalid challenge", http.StatusBadRequest)
+			return
+		}
+		filter.ChallengeID = id
+	}
+	filter.PackageName = r.URL.Query().Get("package")
+
+	events, unsubscribe := h.events.Subscribe(filter)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	// Tell the browser how long to wait before reconnecting, and open the stream
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case event, open := <-events:
+			if !open {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("Error encoding %s event: %v", event.Type, err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// challengeBoardRank returns a user's position on a challenge's scoreboard as the scoreboard page
+// orders it, or 0 if they are not on it
+func (h *APIHandler) challengeBoardRank(challenge *models.Challenge, username string) int {
+	entries, _ := h.scoreboardService.GetScoreboard(challenge.ID)
+	if challenge.BenchmarkCount > 0 {
+		entries = h.scoreboardService.GetSpeedupRanking(challenge.ID)
+	}
+	for i, entry := range entries {
+		if entry.Username == username {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// packageBoardRank returns a user's position on a package's leaderboard, or 0 if they are not on it
+func (h *APIHandler) packageBoardRank(packageName, username string) int {
+	_, challenges, err := h.packageLearningPath(packageName)
+	if err != nil {
+		return 0
+	}
+	for i, entry := range h.createPackageLeaderboard(packageName, challenges) {
+		if entry.Username == username {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// publishRankChange publishes a rank-changed event if a user's rank moved
+func (h *APIHandler) publishRankChange(event services.HubEvent, previousRank, rank int) {
+	if previousRank == rank {
+		return
+	}
+	event.Type = services.HubEventRankChanged
+	event.PreviousRank = previousRank
+	event.Rank = rank
+	h.events.Publish(event)
+}
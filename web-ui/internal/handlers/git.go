@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"web-ui/internal/models"
+	"web-ui/internal/services"
+)
+
+// gitSubmissionResponse is a committed submission with links to export its branch
+type gitSubmissionResponse struct {
+	services.GitSubmission
+	PatchURL  string `json:"patchUrl"`
+	BundleURL string `json:"bundleUrl"`
+}
+
+// GetGitStatus reports whether submissions can be committed to branches
+func (h *APIHandler) GetGitStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": h.git.Enabled(),
+	})
+}
+
+// HandleGitSubmissions commits the signed-in user's solution to its submission branch.
+// Routes: POST /api/git/submissions, GET /api/git/submissions/patch and GET /api/git/submissions/bundle,
+// the last two taking the challengeId and, for a package challenge, packageName as query parameters.
+func (h *APIHandler) HandleGitSubmissions(w http.ResponseWriter, r *http.Request) {
+	if !h.git.Enabled() {
+		http.Error(w, "Git integration is not enabled", http.StatusNotFound)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, "/api/git/submissions") {
+	case "":
+		h.commitGitSubmission(w, r)
+	case "/patch":
+		h.exportGitSubmission(w, r, "patch")
+	case "/bundle":
+		h.exportGitSubmission(w, r, "bundle")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// commitGitSubmission commits a stored submission of the signed-in user, sent as {"submissionId": N}.
+// Only passing submits can be committed, so a branch never carries untested code.
+func (h *APIHandler) commitGitSubmission(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		SubmissionID int64 `json:"submissionId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.SubmissionID == 0 {
+		http.Error(w, "submissionId is required", http.StatusBadRequest)
+		return
+	}
+
+	username, ok := h.requireUser(w, r, "")
+	if !ok {
+		return
+	}
+
+	stored, exists, err := h.submissionStore.Get(request.SubmissionID)
+	if err != nil {
+		log.Printf("Error loading submission %d: %v", request.SubmissionID, err)
+		http.Error(w, "Failed to load submission", http.StatusInternalServerError)
+		return
+	}
+	if !exists || stored.Action != models.ActionSubmit {
+		http.Error(w, "Submission not found", http.StatusNotFound)
+		return
+	}
+	if !strings.EqualFold(stored.Username, username) {
+		http.Error(w, "Only your own submissions can be committed", http.StatusForbidden)
+		return
+	}
+	if !stored.Passed {
+		http.Error(w, "Only passing submissions can be committed", http.StatusConflict)
+		return
+	}
+
+	target := services.CoreSubmissionTarget(stored.ChallengeID)
+	if stored.PackageName != "" {
+		target = services.SubmissionTarget{PackageName: stored.PackageName, ChallengeID: stored.PackageChallengeID}
+	}
+	if !h.requireSubmissionTarget(w, target, username) {
+		return
+	}
+
+	submission, err := h.git.CommitSubmission(r.Context(), target, username, stored.Code)
+	if err != nil {
+		log.Printf("Error committing submission for %s: %v", username, err)
+		http.Error(w, "Failed to commit solution", http.StatusInternalServerError)
+		return
+	}
+
+	query := gitSubmissionQuery(target)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(gitSubmissionResponse{
+		GitSubmission: submission,
+		PatchURL:      "/api/git/submissions/patch?" + query,
+		BundleURL:     "/api/git/submissions/bundle?" + query,
+	})
+}
+
+// exportGitSubmission downloads the signed-in user's submission branch as a patch series or a bundle
+func (h *APIHandler) exportGitSubmission(w http.ResponseWriter, r *http.Request, format string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Users export only their own branches
+	username, ok := h.requireUser(w, r, "")
+	if !ok {
+		return
+	}
+
+	target := services.SubmissionTarget{
+		PackageName: r.URL.Query().Get("packageName"),
+		ChallengeID: r.URL.Query().Get("challengeId"),
+	}
+	if !h.requireSubmissionTarget(w, target, username) {
+		return
+	}
+
+	var data []byte
+	var err error
+	if format == "patch" {
+		data, err = h.git.Patch(r.Context(), target, username)
+	} else {
+		data, err = h.git.Bundle(r.Context(), target, username)
+	}
+	if errors.Is(err, services.ErrNoSubmissionBranch) {
+		http.Error(w, "No committed submission for this challenge", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error exporting submission %s for %s: %v", format, username, err)
+		http.Error(w, "Failed to export submission", http.StatusInternalServerError)
+		return
+	}
+
+	branch, _ := services.SubmissionBranch(target, username)
+	filename := strings.ReplaceAll(branch, "/", "-")
+	if format == "patch" {
+		w.Header().Set("Content-Type", "text/x-patch")
+		filename += ".patch"
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		filename += ".bundle"
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(data)
+}
+
+// gitSubmissionQuery encodes a target as the query parameters of the export routes
+func gitSubmissionQuery(target services.SubmissionTarget) string {
+	query := url.Values{}
+	if target.IsPackage() {
+		query.Set("packageName", target.PackageName)
+	}
+	query.Set("challengeId", target.ChallengeID)
+	return query.Encode()
+}
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"sort"
+	"time"
+
+	"web-ui/internal/models"
+	"web-ui/internal/scoreboard"
+	"web-ui/internal/services"
+)
+
+// Main leaderboard rankings
+const (
+	rankingCompletions = "completions"
+	rankingPoints      = "points"
+	rankingImproved    = "improved"
+)
+
+// mostImprovedDefaultWindow is how far back the most improved ranking looks when no since is given
+const mostImprovedDefaultWindow = 30 * 24 * time.Hour
+
+// leaderboardWindow bounds the completions a leaderboard counts. A zero bound is open.
+type leaderboardWindow struct {
+	Since time.Time // Inclusive
+	Until time.Time // Exclusive
+}
+
+// parseLeaderboardWindow reads a window from the period, since and until query parameters.
+// The week period starts on Monday and the month period on the 1st, both in UTC; since and until
+// override the period's bounds.
+func parseLeaderboardWindow(params url.Values, now time.Time) (leaderboardWindow, error) {
+	var window leaderboardWindow
+
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch period := params.Get("period"); period {
+	case "", "all":
+	case "week":
+		daysSinceMonday := (int(today.Weekday()) + 6) % 7
+		window.Since = today.AddDate(0, 0, -daysSinceMonday)
+	case "month":
+		window.Since = today.AddDate(0, 0, 1-today.Day())
+	default:
+		return window, fmt.Errorf("invalid period %q, expected week, month or all", period)
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{
+		{"since", &window.Since},
+		{"until", &window.Until},
+	} {
+		text := params.Get(bound.name)
+		if text == "" {
+			continue
+		}
+		value, err := parseWindowBound(text)
+		if err != nil {
+			return window, fmt.Errorf("invalid %s, expected an RFC 3339 time or YYYY-MM-DD date", bound.name)
+		}
+		*bound.value = value
+	}
+
+	if !window.Since.IsZero() && !window.Until.IsZero() && !window.Since.Before(window.Until) {
+		return window, fmt.Errorf("since must be before until")
+	}
+	return window, nil
+}
+
+// parseWindowBound reads an RFC 3339 time, or a date meaning its start in UTC
+func parseWindowBound(text string) (time.Time, error) {
+	if value, err := time.Parse(time.RFC3339, text); err == nil {
+		return value, nil
+	}
+	return time.Parse("2006-01-02", text)
+}
+
+// formatWindowBound formats a bound for a response, or "" for an open bound
+func formatWindowBound(bound time.Time) string {
+	if bound.IsZero() {
+		return ""
+	}
+	return bound.UTC().Format(time.RFC3339)
+}
+
+// bounded reports whether the window excludes any time
+func (w leaderboardWindow) bounded() bool {
+	return !w.Since.IsZero() || !w.Until.IsZero()
+}
+
+// contains reports whether a completion time falls in the window. Undated completions
+// only count towards unbounded windows.
+func (w leaderboardWindow) contains(t time.Time) bool {
+	if !w.bounded() {
+		return true
+	}
+	if t.IsZero() {
+		return false
+	}
+	return (w.Since.IsZero() || !t.Before(w.Since)) && (w.Until.IsZero() || t.Before(w.Until))
+}
+
+// filter returns the challenges completed in the window
+func (w leaderboardWindow) filter(completions map[int]time.Time) map[int]bool {
+	completed := make(map[int]bool)
+	for challengeID, completedAt := range completions {
+		if w.contains(completedAt) {
+			completed[challengeID] = true
+		}
+	}
+	return completed
+}
+
+// withDefaultSince gives a window without a start one that starts a duration before its end
+func (w leaderboardWindow) withDefaultSince(duration time.Duration) leaderboardWindow {
+	if w.Since.IsZero() {
+		end := w.Until
+		if end.IsZero() {
+			end = time.Now().UTC()
+		}
+		w.Since = end.Add(-duration)
+	}
+	return w
+}
+
+// previous returns the window of the same length that ends where this one starts.
+// The window must have a start; an open end is taken to be now.
+func (w leaderboardWindow) previous() leaderboardWindow {
+	end := w.Until
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	return leaderboardWindow{Since: w.Since.Add(-end.Sub(w.Since)), Until: w.Since}
+}
+
+// rankMainLeaderboard ranks the main leaderboard as the window and ranking query parameters ask,
+// returning the window it covers. Its errors describe an invalid query.
+func (h *APIHandler) rankMainLeaderboard(params url.Values) ([]LeaderboardUser, leaderboardWindow, error) {
+	window, err := parseLeaderboardWindow(params, time.Now())
+	if err != nil {
+		return nil, window, fmt.Errorf("Invalid leaderboard window: %v", err)
+	}
+
+	switch ranking := params.Get("ranking"); ranking {
+	case "", rankingCompletions, rankingPoints:
+		return h.calculateMainLeaderboard(window, ranking == rankingPoints), window, nil
+	case rankingImproved:
+		window = window.withDefaultSince(mostImprovedDefaultWindow)
+		return h.calculateMostImproved(window), window, nil
+	default:
+		return nil, window, fmt.Errorf("Invalid ranking, expected %q, %q or %q", rankingCompletions, rankingPoints, rankingImproved)
+	}
+}
+
+// calculateMostImproved ranks users by how many more challenges they completed in a window
+// than in the window of the same length before it. Only users who improved are ranked.
+func (h *APIHandler) calculateMostImproved(window leaderboardWindow) []LeaderboardUser {
+	boards := h.challengeScoreboards()
+	leaderboard := rankMostImproved(challengeCompletions(boards), window, len(h.challengeService.GetChallenges()))
+
+	points := h.userPoints(boards, window)
+	sponsors := h.LoadSponsors()
+	for i := range leaderboard {
+		user := &leaderboard[i]
+		user.IsSponsor = sponsors[user.Username]
+		if tally := points[user.Username]; tally != nil {
+			user.Points = services.RoundPoints(tally.Points)
+			user.PackageCount = tally.PackageCount
+		}
+	}
+
+	return leaderboard
+}
+
+// rankMostImproved ranks the users who completed more challenges in a window than in the window
+// before it, given when each user completed each challenge
+func rankMostImproved(userCompletions map[string]map[int]time.Time, window leaderboardWindow, totalChallenges int) []LeaderboardUser {
+	previousWindow := window.previous()
+
+	leaderboard := []LeaderboardUser{}
+	for username, completions := range userCompletions {
+		completed := window.filter(completions)
+		previousCount := len(previousWindow.filter(completions))
+		if len(completed) <= previousCount {
+			continue
+		}
+
+		leaderboard = append(leaderboard, LeaderboardUser{
+			Username:            username,
+			CompletedCount:      len(completed),
+			CompletionRate:      float64(len(completed)) / float64(totalChallenges) * 100,
+			CompletedChallenges: completed,
+			Achievement:         services.CompletionTitle(len(completions)),
+			PreviousCount:       previousCount,
+			Improvement:         len(completed) - previousCount,
+		})
+	}
+
+	// Sort by improvement, then by completions in the window (both descending), then by username
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].Improvement != leaderboard[j].Improvement {
+			return leaderboard[i].Improvement > leaderboard[j].Improvement
+		}
+		if leaderboard[i].CompletedCount != leaderboard[j].CompletedCount {
+			return leaderboard[i].CompletedCount > leaderboard[j].CompletedCount
+		}
+		return leaderboard[i].Username < leaderboard[j].Username
+	})
+
+	for i := range leaderboard {
+		leaderboard[i].Rank = i + 1
+	}
+
+	return leaderboard
+}
+
+// challengeScoreboards loads the scoreboard of every classic challenge that has one
+func (h *APIHandler) challengeScoreboards() map[int]*scoreboard.Board {
+	boards := make(map[int]*scoreboard.Board)
+
+	for challengeID := range h.challengeService.GetChallenges() {
+		board, err := scoreboard.Load(services.ChallengeDir(challengeID))
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				log.Printf("Warning: Could not load scoreboard for challenge %d: %v", challengeID, err)
+			}
+			continue
+		}
+		boards[challengeID] = board
+	}
+
+	return boards
+}
+
+// pointsTally is a user's points on the main leaderboard
+type pointsTally struct {
+	Points       float64
+	PackageCount int // Package challenges completed
+}
+
+// userPoints scores the scoreboard entries in a window of every user, from the classic challenge boards
+// and the package challenge boards. Every user with an entry in the window has a tally.
+func (h *APIHandler) userPoints(boards map[int]*scoreboard.Board, window leaderboardWindow) map[string]*pointsTally {
+	tallies := make(map[string]*pointsTally)
+	tally := func(username string) *pointsTally {
+		if tallies[username] == nil {
+			tallies[username] = &pointsTally{}
+		}
+		return tallies[username]
+	}
+
+	firstTries := h.firstTryPasses()
+	challenges := h.challengeService.GetChallenges()
+	for challengeID, board := range boards {
+		challenge, exists := challenges[challengeID]
+		if !exists {
+			continue
+		}
+		for _, entry := range board.Entries {
+			if !window.contains(entry.SubmittedAt) {
+				continue
+			}
+			firstTry := firstTries[entry.Username][services.CoreSubmissionTarget(challengeID)]
+			tally(entry.Username).Points += h.scoring.Points(challenge.Difficulty, entry.TestsPassed, entry.TestsTotal, firstTry)
+		}
+	}
+
+	for packageName := range h.packageService.GetPackages() {
+		packageChallenges, err := h.packageService.GetPackageChallenges(packageName)
+		if err != nil {
+			continue
+		}
+		for _, challenge := range packageChallenges {
+			board, err := scoreboard.Load(challenge.Dir)
+			if err != nil {
+				if !errors.Is(err, fs.ErrNotExist) {
+					log.Printf("Warning: Could not load scoreboard for %s %s: %v", packageName, challenge.ID, err)
+				}
+				continue
+			}
+			for _, entry := range board.Entries {
+				if !window.contains(entry.SubmittedAt) {
+					continue
+				}
+				userTally := tally(entry.Username)
+				firstTry := firstTries[entry.Username][services.SubmissionTarget{PackageName: packageName, ChallengeID: challenge.ID}]
+				userTally.Points += h.scoring.Points(challenge.Difficulty, entry.TestsPassed, entry.TestsTotal, firstTry)
+				if entry.Completed() {
+					userTally.PackageCount++
+				}
+			}
+		}
+	}
+
+	return tallies
+}
+
+// untimedEntries counts the scoreboard entries a bounded window leaves out because they have no
+// submission time, such as those of a challenge that only has a SCOREBOARD.md. With a cohort,
+// only its members' entries are counted.
+func (h *APIHandler) untimedEntries(window leaderboardWindow, cohort *models.Cohort) int {
+	if !window.bounded() {
+		return 0
+	}
+
+	count := 0
+	countBoard := func(board *scoreboard.Board) {
+		for _, entry := range board.Entries {
+			if entry.SubmittedAt.IsZero() && (cohort == nil || cohort.HasMember(entry.Username)) {
+				count++
+			}
+		}
+	}
+
+	for _, board := range h.challengeScoreboards() {
+		countBoard(board)
+	}
+	for packageName := range h.packageService.GetPackages() {
+		packageChallenges, err := h.packageService.GetPackageChallenges(packageName)
+		if err != nil {
+			continue
+		}
+		for _, challenge := range packageChallenges {
+			if board, err := scoreboard.Load(challenge.Dir); err == nil {
+				countBoard(board)
+			}
+		}
+	}
+	return count
+}
+
+// firstTryPasses returns the classic and package challenges each user passed on their first stored submit
+func (h *APIHandler) firstTryPasses() map[string]map[services.SubmissionTarget]bool {
+	if h.scoring.FirstTryBonusPercent == 0 {
+		return map[string]map[services.SubmissionTarget]bool{}
+	}
+
+	firstTries, err := h.submissionStore.FirstSubmitPasses()
+	if err != nil {
+		log.Printf("Error querying submissions for first-try passes: %v", err)
+		return map[string]map[services.SubmissionTarget]bool{}
+	}
+	return firstTries
+}
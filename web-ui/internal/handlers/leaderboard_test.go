@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// leaderboardTestDay returns midnight UTC of a day in March 2025
+func leaderboardTestDay(day int) time.Time {
+	return time.Date(2025, 3, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseLeaderboardWindow(t *testing.T) {
+	now := time.Date(2025, 3, 12, 15, 4, 5, 0, time.UTC) // A Wednesday
+
+	tests := []struct {
+		name    string
+		query   string
+		want    leaderboardWindow
+		wantErr string
+	}{
+		{name: "no parameters", query: ""},
+		{name: "all time", query: "period=all"},
+		{name: "week starts on Monday", query: "period=week", want: leaderboardWindow{Since: leaderboardTestDay(10)}},
+		{name: "month starts on the 1st", query: "period=month", want: leaderboardWindow{Since: leaderboardTestDay(1)}},
+		{
+			name:  "until bounds a period",
+			query: "period=month&until=2025-03-10",
+			want:  leaderboardWindow{Since: leaderboardTestDay(1), Until: leaderboardTestDay(10)},
+		},
+		{
+			name:  "since overrides a period",
+			query: "period=week&since=2025-03-11T08:00:00Z",
+			want:  leaderboardWindow{Since: time.Date(2025, 3, 11, 8, 0, 0, 0, time.UTC)},
+		},
+		{name: "invalid period", query: "period=year", wantErr: `invalid period "year", expected week, month or all`},
+		{name: "invalid since", query: "since=yesterday", wantErr: "invalid since, expected an RFC 3339 time or YYYY-MM-DD date"},
+		{name: "since not before until", query: "since=2025-03-10&until=2025-03-10", wantErr: "since must be before until"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			window, err := parseLeaderboardWindow(params, now)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseLeaderboardWindow() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLeaderboardWindow() error = %v", err)
+			}
+			if !window.Since.Equal(tt.want.Since) || !window.Until.Equal(tt.want.Until) {
+				t.Errorf("parseLeaderboardWindow() = %+v, want %+v", window, tt.want)
+			}
+			if window.bounded() != tt.want.bounded() {
+				t.Errorf("bounded() = %v, want %v", window.bounded(), tt.want.bounded())
+			}
+		})
+	}
+}
+
+func TestLeaderboardWindowContains(t *testing.T) {
+	week := leaderboardWindow{Since: leaderboardTestDay(10), Until: leaderboardTestDay(17)}
+
+	tests := []struct {
+		name   string
+		window leaderboardWindow
+		at     time.Time
+		want   bool
+	}{
+		{"open window counts undated completions", leaderboardWindow{}, time.Time{}, true},
+		{"bounded window skips undated completions", week, time.Time{}, false},
+		{"since is inclusive", week, leaderboardTestDay(10), true},
+		{"until is exclusive", week, leaderboardTestDay(17), false},
+		{"before since", week, leaderboardTestDay(9), false},
+		{"open start", leaderboardWindow{Until: leaderboardTestDay(17)}, leaderboardTestDay(1), true},
+		{"open end", leaderboardWindow{Since: leaderboardTestDay(10)}, leaderboardTestDay(31), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.contains(tt.at); got != tt.want {
+				t.Errorf("contains(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaderboardWindowFilter(t *testing.T) {
+	completions := map[int]time.Time{
+		1: leaderboardTestDay(3),
+		2: leaderboardTestDay(11),
+		3: {},
+		4: leaderboardTestDay(16),
+	}
+
+	tests := []struct {
+		name   string
+		window leaderboardWindow
+		want   map[int]bool
+	}{
+		{"all time", leaderboardWindow{}, map[int]bool{1: true, 2: true, 3: true, 4: true}},
+		{"week", leaderboardWindow{Since: leaderboardTestDay(10), Until: leaderboardTestDay(17)}, map[int]bool{2: true, 4: true}},
+		{"previous week", leaderboardWindow{Since: leaderboardTestDay(10), Until: leaderboardTestDay(17)}.previous(), map[int]bool{1: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.filter(completions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankMostImproved(t *testing.T) {
+	window := leaderboardWindow{Since: leaderboardTestDay(10), Until: leaderboardTestDay(17)}
+	userCompletions := map[string]map[int]time.Time{
+		"alice": {1: leaderboardTestDay(4), 2: leaderboardTestDay(10), 3: leaderboardTestDay(12), 4: leaderboardTestDay(16)},
+		"bob":   {1: leaderboardTestDay(11), 2: leaderboardTestDay(12)},
+		"bea":   {3: leaderboardTestDay(13), 4: leaderboardTestDay(14)},
+		"carol": {1: leaderboardTestDay(5), 2: leaderboardTestDay(11)},                            // No better than the week before
+		"dave":  {1: {}, 2: {}},                                                                   // Undated completions
+		"erin":  {1: leaderboardTestDay(1), 2: leaderboardTestDay(15), 3: leaderboardTestDay(17)}, // Completed on the 17th, after the window
+	}
+
+	type ranked struct {
+		Username                                         string
+		Rank, CompletedCount, PreviousCount, Improvement int
+	}
+	want := []ranked{
+		{"alice", 1, 3, 1, 2},
+		{"bea", 2, 2, 0, 2},
+		{"bob", 3, 2, 0, 2},
+		{"erin", 4, 1, 0, 1},
+	}
+
+	var got []ranked
+	for _, user := range rankMostImproved(userCompletions, window, 10) {
+		got = append(got, ranked{user.Username, user.Rank, user.CompletedCount, user.PreviousCount, user.Improvement})
+		if user.CompletionRate != float64(user.CompletedCount)*10 {
+			t.Errorf("%s completion rate = %v, want %v", user.Username, user.CompletionRate, float64(user.CompletedCount)*10)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rankMostImproved() =\n%+v\nwant\n%+v", got, want)
+	}
+}
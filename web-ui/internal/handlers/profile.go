@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+	"web-ui/internal/scoreboard"
+	"web-ui/internal/services"
+)
+
+// GetUserProfile returns a user's progress on the core challenges and the package tracks,
+// with their leaderboard ranks and achievement, at /api/users/{username}
+func (h *APIHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := strings.TrimPrefix(r.URL.Path, "/api/users/")
+	if !services.ValidGitHubUsername(username) {
+		http.Error(w, "Invalid username", http.StatusBadRequest)
+		return
+	}
+
+	profile, found := h.buildUserProfile(username)
+	if !found {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// buildUserProfile gathers a user's profile from the scoreboards, their stored attempts and their
+// package submissions. It reports false if the user has no activity anywhere.
+func (h *APIHandler) buildUserProfile(username string) (*models.UserProfile, bool) {
+	profile := &models.UserProfile{
+		Username: username,
+		Packages: []*models.PackageProgress{},
+		Ranks:    models.ProfileRanks{Packages: make(map[string]int)},
+	}
+
+	profile.Core = h.coreProgress(username)
+	found := profile.Core.Completed > 0 || profile.Core.Attempted > 0
+
+	packageNames := make([]string, 0, len(h.packageService.GetPackages()))
+	for name := range h.packageService.GetPackages() {
+		packageNames = append(packageNames, name)
+	}
+	sort.Strings(packageNames)
+
+	for _, name := range packageNames {
+		progress, rank := h.packageProgress(username, name)
+		if progress == nil {
+			continue
+		}
+		if len(progress.CompletedChallenges) > 0 || progress.InProgress != "" {
+			found = true
+		}
+		profile.Packages = append(profile.Packages, progress)
+		if rank > 0 {
+			profile.Ranks.Packages[name] = rank
+		}
+	}
+
+	if !found {
+		return nil, false
+	}
+
+	profile.Ranks.Main = h.calculateMainScoreboardRank(username)
+	for _, user := range h.calculateMainLeaderboard(leaderboardWindow{}, true) {
+		if user.Username == username {
+			profile.Ranks.Points = user.Rank
+			profile.Points = user.Points
+			profile.IsSponsor = user.IsSponsor
+			break
+		}
+	}
+	profile.Achievement = services.CompletionTitle(profile.Core.Completed)
+	profile.Badges = h.earnedBadges(username)
+	for _, badge := range profile.Badges {
+		for _, progress := range profile.Packages {
+			if badge.PackageName == progress.PackageName {
+				progress.Achievements = append(progress.Achievements, badge.Icon+" "+badge.Name)
+			}
+		}
+	}
+
+	return profile, true
+}
+
+// coreProgress returns a user's result on every core challenge, from the challenge scoreboards
+// and the user's stored attempts
+func (h *APIHandler) coreProgress(username string) models.CoreProgress {
+	challenges := h.challengeService.GetChallenges()
+	boards := h.challengeScoreboards()
+
+	// Attempts come newest first, so the first seen of a challenge is the latest
+	// and the last submit seen is the first
+	lastAttempts := make(map[int]time.Time)
+	firstTries := make(map[int]bool)
+	attempts, err := h.submissionStore.Query(services.SubmissionQuery{Username: username})
+	if err != nil {
+		log.Printf("Error querying attempts of %s: %v", username, err)
+	}
+	for _, attempt := range attempts {
+		if _, seen := lastAttempts[attempt.ChallengeID]; !seen {
+			lastAttempts[attempt.ChallengeID] = attempt.SubmittedAt
+		}
+		if attempt.Action == models.ActionSubmit {
+			firstTries[attempt.ChallengeID] = attempt.Passed
+		}
+	}
+
+	ids := make([]int, 0, len(challenges))
+	for id := range challenges {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	progress := models.CoreProgress{Total: len(ids), Challenges: make([]models.CoreChallengeProgress, 0, len(ids))}
+	for _, id := range ids {
+		challenge := challenges[id]
+		result := models.CoreChallengeProgress{
+			ID:         id,
+			Title:      challenge.Title,
+			Difficulty: challenge.Difficulty,
+			Status:     models.ChallengeStatusNotStarted,
+		}
+
+		if board, exists := boards[id]; exists {
+			if entry, found := board.Find(username); found {
+				result.TestsPassed = entry.TestsPassed
+				result.TestsTotal = entry.TestsTotal
+				result.LastAttempt = entry.SubmittedAt
+				result.Status = models.ChallengeStatusAttempted
+				if entry.Completed() {
+					result.Status = models.ChallengeStatusCompleted
+				}
+				firstTry := h.scoring.FirstTryBonusPercent > 0 && firstTries[id]
+				result.Points = services.RoundPoints(h.scoring.Points(challenge.Difficulty, entry.TestsPassed, entry.TestsTotal, firstTry))
+			}
+		}
+
+		if attemptedAt, exists := lastAttempts[id]; exists {
+			if attemptedAt.After(result.LastAttempt) {
+				result.LastAttempt = attemptedAt
+			}
+			if result.Status == models.ChallengeStatusNotStarted {
+				result.Status = models.ChallengeStatusAttempted
+			}
+		}
+
+		switch result.Status {
+		case models.ChallengeStatusCompleted:
+			progress.Completed++
+		case models.ChallengeStatusAttempted:
+			progress.Attempted++
+		}
+		progress.Challenges = append(progress.Challenges, result)
+	}
+
+	return progress
+}
+
+// packageProgress returns a user's progress on a package's learning path, from its challenge
+// scoreboards and the user's submissions, with their rank on the package leaderboard
+func (h *APIHandler) packageProgress(username, packageName string) (*models.PackageProgress, int) {
+	pkg, challenges, err := h.packageLearningPath(packageName)
+	if err != nil {
+		return nil, 0
+	}
+
+	progress := &models.PackageProgress{
+		Username:            username,
+		PackageName:         packageName,
+		DisplayName:         pkg.DisplayName,
+		TotalChallenges:     len(challenges),
+		CompletedChallenges: []string{},
+		Achievements:        []string{},
+	}
+
+	// Submits come newest first, so the last seen of a challenge is the first
+	firstTries := make(map[string]bool)
+	if h.scoring.FirstTryBonusPercent > 0 {
+		submits, err := h.submissionStore.Query(services.SubmissionQuery{Username: username, PackageName: packageName, Action: models.ActionSubmit})
+		if err != nil {
+			log.Printf("Error querying %s submits of %s: %v", packageName, username, err)
+		}
+		for _, submit := range submits {
+			firstTries[submit.PackageChallengeID] = submit.Passed
+		}
+	}
+
+	var points float64
+	for _, challenge := range challenges {
+		var entry scoreboard.Entry
+		onBoard := false
+		board, err := scoreboard.Load(challenge.Dir)
+		if err == nil {
+			entry, onBoard = board.Find(username)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Warning: Could not load scoreboard for %s %s: %v", packageName, challenge.ID, err)
+		}
+
+		submittedAt, submitted := packageSubmissionTime(challenge.Dir, username)
+		if !onBoard && !submitted {
+			continue
+		}
+
+		if onBoard && entry.Completed() {
+			progress.CompletedChallenges = append(progress.CompletedChallenges, challenge.ID)
+		} else if progress.InProgress == "" {
+			progress.InProgress = challenge.ID
+		}
+		if onBoard {
+			points += h.scoring.Points(challenge.Difficulty, entry.TestsPassed, entry.TestsTotal, firstTries[challenge.ID])
+			if !entry.SubmittedAt.IsZero() {
+				submittedAt = entry.SubmittedAt
+			}
+		}
+
+		if !submittedAt.IsZero() {
+			if progress.StartedAt.IsZero() || submittedAt.Before(progress.StartedAt) {
+				progress.StartedAt = submittedAt
+			}
+			if submittedAt.After(progress.LastActivity) {
+				progress.LastActivity = submittedAt
+			}
+		}
+	}
+
+	progress.Score = int(math.Round(points))
+
+	rank := 0
+	for i, entry := range h.createPackageLeaderboard(packageName, challenges) {
+		if entry.Username == username {
+			rank = i + 1
+			break
+		}
+	}
+
+	return progress, rank
+}
+
+// packageSubmissionTime returns when a user's package challenge solution was last written.
+// The username names a directory, so it must be a valid GitHub username.
+func packageSubmissionTime(challengeDir, username string) (time.Time, bool) {
+	if !services.ValidGitHubUsername(username) {
+		return time.Time{}, false
+	}
+	userDir := filepath.Join(challengeDir, "submissions", username)
+	for _, name := range []string{"solution.go", "solution-template.go"} {
+		if stat, err := os.Stat(filepath.Join(userDir, name)); err == nil {
+			return stat.ModTime(), true
+		}
+	}
+	return time.Time{}, false
+}
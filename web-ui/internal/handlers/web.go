@@ -25,6 +25,8 @@ type WebHandler struct {
 	scoreboardService *services.ScoreboardService
 	userService       *services.UserService
 	packageService    *services.PackageService
+	cohortStore       services.CohortStore
+	auth              *services.AuthService
 }
 
 // NewWebHandler creates a new web handler
@@ -34,6 +36,8 @@ func NewWebHandler(
 	scoreboardService *services.ScoreboardService,
 	userService *services.UserService,
 	packageService *services.PackageService,
+	cohortStore services.CohortStore,
+	auth *services.AuthService,
 ) *WebHandler {
 	return &WebHandler{
 		content:           content,
@@ -41,6 +45,8 @@ func NewWebHandler(
 		scoreboardService: scoreboardService,
 		userService:       userService,
 		packageService:    packageService,
+		cohortStore:       cohortStore,
+		auth:              auth,
 	}
 }
 
@@ -86,8 +92,8 @@ func (h *WebHandler) HomePage(w http.ResponseWriter, r *http.Request) {
 		return packagesList[i].Stars > packagesList[j].Stars
 	})
 
-	// Get the username from cookie if available
-	username := h.getUsernameFromCookie(r)
+	// The signed-in user, if any
+	username := h.auth.Username(r)
 
 	// Get user attempts if username is set
 	var userAttempt *models.UserAttemptedChallenges
@@ -153,18 +159,8 @@ func (h *WebHandler) ChallengePage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get username from cookie first
-	username := h.getUsernameFromCookie(r)
-
-	// If no username from cookie, try to get it from Git config
-	if username == "" {
-		gitInfo := utils.GetGitUsername()
-		if gitInfo.Username != "" {
-			username = gitInfo.Username
-			// Set the cookie for future requests
-			h.setUsernameCookie(w, username)
-		}
-	}
+	// The signed-in user, if any
+	username := h.auth.Username(r)
 
 	existingSolution := ""
 	hasAttempted := false
@@ -246,7 +242,18 @@ func (h *WebHandler) ScoreChallengeHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// ?cohort= shows only the cohort's members
+	cohort, ok := requestCohort(h.cohortStore, w, r)
+	if !ok {
+		return
+	}
+
 	scoreboard, _ := h.scoreboardService.GetScoreboard(id)
+	if challenge.BenchmarkCount > 0 {
+		// Performance challenges rank by speedup over the reference implementations
+		scoreboard = h.scoreboardService.GetSpeedupRanking(id)
+	}
+	scoreboard = cohortScoreboard(scoreboard, cohort)
 
 	tmpl, err := template.New("").Funcs(utils.GetTemplateFuncs()).ParseFS(h.content, "templates/base.html", "templates/challenge_scoreboard.html")
 	if err != nil {
@@ -258,9 +265,11 @@ func (h *WebHandler) ScoreChallengeHandler(w http.ResponseWriter, r *http.Reques
 	data := struct {
 		Challenge *models.Challenge
 		Entries   []models.ScoreboardEntry
+		Cohort    *models.Cohort
 	}{
 		Challenge: challenge,
 		Entries:   scoreboard,
+		Cohort:    cohort,
 	}
 
 	err = tmpl.ExecuteTemplate(w, "base", data)
@@ -301,8 +310,14 @@ func (h *WebHandler) PackageScoreboardPage(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
+	// ?cohort= shows only the cohort's members
+	cohort, ok := requestCohort(h.cohortStore, w, r)
+	if !ok {
+		return
+	}
+
 	// Create leaderboard
-	leaderboard := h.createPackageLeaderboard(packageName, challenges)
+	leaderboard := cohortPackageLeaderboard(h.createPackageLeaderboard(packageName, challenges), cohort)
 
 	tmpl, err := template.New("").Funcs(utils.GetTemplateFuncs()).ParseFS(h.content, "templates/base.html", "templates/package_scoreboard.html")
 	if err != nil {
@@ -315,10 +330,12 @@ func (h *WebHandler) PackageScoreboardPage(w http.ResponseWriter, r *http.Reques
 		Package         *models.Package
 		Leaderboard     []models.PackageScoreboardEntry
 		TotalChallenges int
+		Cohort          *models.Cohort
 	}{
 		Package:         pkg,
 		Leaderboard:     leaderboard,
 		TotalChallenges: len(challenges),
+		Cohort:          cohort,
 	}
 
 	err = tmpl.ExecuteTemplate(w, "base", data)
@@ -342,8 +359,8 @@ func (h *WebHandler) InterviewPage(w http.ResponseWriter, r *http.Request) {
 		challengeList = append(challengeList, challenge)
 	}
 
-	// Get username from cookie if available
-	username := h.getUsernameFromCookie(r)
+	// The signed-in user, if any
+	username := h.auth.Username(r)
 
 	data := struct {
 		Challenges []*models.Challenge
@@ -360,27 +377,32 @@ func (h *WebHandler) InterviewPage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// getUsernameFromCookie retrieves the username from cookie
-func (h *WebHandler) getUsernameFromCookie(r *http.Request) string {
-	cookie, err := r.Cookie("username")
+// UserProfilePage renders a user's profile, which loads from /api/users/{username}
+func (h *WebHandler) UserProfilePage(w http.ResponseWriter, r *http.Request) {
+	username := strings.TrimPrefix(r.URL.Path, "/users/")
+	if !services.ValidGitHubUsername(username) {
+		http.NotFound(w, r)
+		return
+	}
+
+	tmpl, err := template.New("").Funcs(utils.GetTemplateFuncs()).ParseFS(h.content, "templates/base.html", "templates/user_profile.html")
 	if err != nil {
-		return ""
+		log.Printf("Template error: %v", err)
+		http.Error(w, "Failed to parse template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Username string
+	}{
+		Username: username,
 	}
-	return cookie.Value
-}
 
-// setUsernameCookie sets the username cookie
-func (h *WebHandler) setUsernameCookie(w http.ResponseWriter, username string) {
-	// Cookie expires in 30 days
-	expiration := time.Now().Add(30 * 24 * time.Hour)
-	cookie := http.Cookie{
-		Name:     "username",
-		Value:    username,
-		Expires:  expiration,
-		Path:     "/",
-		HttpOnly: false, // Allow JavaScript to access it
-	}
-	http.SetCookie(w, &cookie)
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		log.Printf("Template execution error: %v", err)
+		// Don't call http.Error here since headers may already be sent during template execution
+	}
 }
 
 // PackageDetailPage renders the package detail page
@@ -433,8 +455,8 @@ func (h *WebHandler) PackageDetailPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the username from cookie if available
-	username := h.getUsernameFromCookie(r)
+	// The signed-in user, if any
+	username := h.auth.Username(r)
 
 	// Check which package challenges the user has attempted
 	packageAttempts := make(map[string]bool)
@@ -529,18 +551,8 @@ func (h *WebHandler) PackageChallengePage(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Get username from cookie first
-	username := h.getUsernameFromCookie(r)
-
-	// If no username from cookie, try to get it from Git config
-	if username == "" {
-		gitInfo := utils.GetGitUsername()
-		if gitInfo.Username != "" {
-			username = gitInfo.Username
-			// Set the cookie for future requests
-			h.setUsernameCookie(w, username)
-		}
-	}
+	// The signed-in user, if any
+	username := h.auth.Username(r)
 
 	// Check if user has attempted this challenge
 	hasAttempted := false
@@ -575,39 +587,40 @@ func (h *WebHandler) PackageChallengePage(w http.ResponseWriter, r *http.Request
 
 // hasUserAttemptedPackageChallenge checks if a user has attempted a package challenge
 func (h *WebHandler) hasUserAttemptedPackageChallenge(username, packageName, challengeID string) bool {
-	// Check if submission file exists in ../packages/{packageName}/{challengeID}/submissions/{username}/solution.go
-	submissionPath := filepath.Join("..", "packages", packageName, challengeID, "submissions", username, "solution.go")
-	if _, err := os.Stat(submissionPath); err == nil {
-		return true
-	}
+	return h.userPackageChallengeSolutionPath(username, packageName, challengeID) != ""
+}
 
-	// Try alternative path in case of different file naming
-	altSubmissionPath := filepath.Join("..", "packages", packageName, challengeID, "submissions", username, "solution-template.go")
-	if _, err := os.Stat(altSubmissionPath); err == nil {
-		return true
+// getUserPackageChallengeSolution retrieves a user's existing solution for a package challenge
+func (h *WebHandler) getUserPackageChallengeSolution(username, packageName, challengeID string) string {
+	path := h.userPackageChallengeSolutionPath(username, packageName, challengeID)
+	if path == "" {
+		return ""
 	}
 
-	return false
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(content)
 }
 
-// getUserPackageChallengeSolution retrieves a user's existing solution for a package challenge
-func (h *WebHandler) getUserPackageChallengeSolution(username, packageName, challengeID string) string {
-	if username == "" {
+// userPackageChallengeSolutionPath returns a user's saved solution file for a package challenge,
+// solution.go or else solution-template.go, or "" if they have none
+func (h *WebHandler) userPackageChallengeSolutionPath(username, packageName, challengeID string) string {
+	submissionFile, err := services.SubmissionPath(services.SubmissionTarget{PackageName: packageName, ChallengeID: challengeID}, username)
+	if err != nil {
 		return ""
 	}
 
-	// Try solution.go first
-	submissionPath := filepath.Join("..", "packages", packageName, challengeID, "submissions", username, "solution.go")
-	content, err := ioutil.ReadFile(submissionPath)
-	if err == nil {
-		return string(content)
+	submissionPath := services.WorkspacePath(submissionFile)
+	if _, err := os.Stat(submissionPath); err == nil {
+		return submissionPath
 	}
 
-	// Try solution-template.go as fallback
-	altSubmissionPath := filepath.Join("..", "packages", packageName, challengeID, "submissions", username, "solution-template.go")
-	content, err = ioutil.ReadFile(altSubmissionPath)
-	if err == nil {
-		return string(content)
+	// Try alternative path in case of different file naming
+	altSubmissionPath := filepath.Join(filepath.Dir(submissionPath), "solution-template.go")
+	if _, err := os.Stat(altSubmissionPath); err == nil {
+		return altSubmissionPath
 	}
 
 	return ""
@@ -615,7 +628,7 @@ func (h *WebHandler) getUserPackageChallengeSolution(username, packageName, chal
 
 // countPackageChallengeSubmissions counts the number of submissions for a package challenge
 func (h *WebHandler) countPackageChallengeSubmissions(packageName, challengeID string) int {
-	submissionsDir := filepath.Join("..", "packages", packageName, challengeID, "submissions")
+	submissionsDir := services.WorkspacePath("packages", packageName, challengeID, "submissions")
 
 	// Check if submissions directory exists
 	if _, err := os.Stat(submissionsDir); os.IsNotExist(err) {
@@ -659,7 +672,7 @@ func (h *WebHandler) createPackageLeaderboard(packageName string, challenges []*
 
 	// Collect submission data for each challenge
 	for _, challenge := range challenges {
-		submissionsDir := filepath.Join("..", "packages", packageName, challenge.ID, "submissions")
+		submissionsDir := services.WorkspacePath("packages", packageName, challenge.ID, "submissions")
 
 		// Check if submissions directory exists
 		if _, err := os.Stat(submissionsDir); os.IsNotExist(err) {
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"web-ui/internal/services"
+)
+
+const (
+	// maxWebhookBodyBytes is GitHub's cap on a delivery's payload
+	maxWebhookBodyBytes = 25 << 20
+
+	// maxPushPayloadCommits is the most commits a push payload lists; longer pushes are compared instead
+	maxPushPayloadCommits = 20
+
+	// webhookIngestTimeout bounds fetching a delivery's changed files
+	webhookIngestTimeout = 2 * time.Minute
+)
+
+// webhookRepository is the repository of a push or pull_request payload
+type webhookRepository struct {
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// pushPayload is the part of a push event the server reads
+type pushPayload struct {
+	Ref     string `json:"ref"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+	Deleted bool   `json:"deleted"`
+	Commits []struct {
+		Added    []string `json:"added"`
+		Removed  []string `json:"removed"`
+		Modified []string `json:"modified"`
+	} `json:"commits"`
+	Repository webhookRepository `json:"repository"`
+}
+
+// pullRequestPayload is the part of a pull_request event the server reads
+type pullRequestPayload struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Merged         bool   `json:"merged"`
+		MergeCommitSHA string `json:"merge_commit_sha"`
+		Base           struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	} `json:"pull_request"`
+	Repository webhookRepository `json:"repository"`
+}
+
+// GitHubWebhookHandler handles signed GitHub webhook deliveries. Sponsorship events refresh the
+// sponsor list. Pushes to the default branch and merged pull requests re-ingest the submissions and
+// scoreboards they changed, in the background, so merged submissions show up without a restart.
+func (h *APIHandler) GitHubWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.webhooks.Enabled() {
+		http.Error(w, "GitHub webhook is not configured", http.StatusNotFound)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusBadRequest)
+		return
+	}
+	if !h.webhooks.Verify(r.Header.Get("X-Hub-Signature-256"), body) {
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	delivery := r.Header.Get("X-GitHub-Delivery")
+	if delivery == "" {
+		http.Error(w, "Missing X-GitHub-Delivery header", http.StatusBadRequest)
+		return
+	}
+	isNew, err := h.webhooks.Remember(delivery)
+	if err != nil {
+		log.Printf("Error recording webhook delivery %s: %v", delivery, err)
+		http.Error(w, "Failed to record delivery", http.StatusInternalServerError)
+		return
+	}
+	if !isNew {
+		// Already handled, so a replay or a redelivery of one that succeeded
+		writeWebhookStatus(w, http.StatusOK, "duplicate")
+		return
+	}
+
+	eventType := r.Header.Get("X-GitHub-Event")
+	switch eventType {
+	case "ping":
+		writeWebhookStatus(w, http.StatusOK, "ok")
+
+	case "sponsorship":
+		// Clear the sponsor cache to force a refresh on next request
+		sponsorCache.mutex.Lock()
+		sponsorCache.sponsors = make(map[string]bool)
+		sponsorCache.lastUpdated = time.Time{} // Reset to zero time to force refresh
+		sponsorCache.mutex.Unlock()
+
+		log.Printf("Sponsor cache cleared due to webhook event: %s", eventType)
+		writeWebhookStatus(w, http.StatusOK, "ok")
+
+	case "push":
+		var payload pushPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			h.webhooks.Forget(delivery)
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		if !h.allowWebhookRepository(w, delivery, payload.Repository.FullName) {
+			return
+		}
+		if payload.Deleted || payload.Ref != "refs/heads/"+payload.Repository.DefaultBranch {
+			writeWebhookStatus(w, http.StatusOK, "ignored")
+			return
+		}
+
+		go h.ingestWebhook(delivery, eventType, payload.Repository.FullName, payload.After, func(ctx context.Context) ([]string, error) {
+			if len(payload.Commits) >= maxPushPayloadCommits {
+				return h.webhooks.CompareFiles(ctx, payload.Repository.FullName, payload.Before, payload.After)
+			}
+			var paths []string
+			for _, commit := range payload.Commits {
+				paths = append(paths, commit.Added...)
+				paths = append(paths, commit.Removed...)
+				paths = append(paths, commit.Modified...)
+			}
+			return paths, nil
+		})
+		writeWebhookStatus(w, http.StatusAccepted, "accepted")
+
+	case "pull_request":
+		var payload pullRequestPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			h.webhooks.Forget(delivery)
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		if !h.allowWebhookRepository(w, delivery, payload.Repository.FullName) {
+			return
+		}
+		pr := payload.PullRequest
+		if payload.Action != "closed" || !pr.Merged || pr.Base.Ref != payload.Repository.DefaultBranch {
+			writeWebhookStatus(w, http.StatusOK, "ignored")
+			return
+		}
+
+		go h.ingestWebhook(delivery, eventType, payload.Repository.FullName, pr.MergeCommitSHA, func(ctx context.Context) ([]string, error) {
+			return h.webhooks.PullRequestFiles(ctx, payload.Repository.FullName, payload.Number)
+		})
+		writeWebhookStatus(w, http.StatusAccepted, "accepted")
+
+	default:
+		writeWebhookStatus(w, http.StatusOK, "ignored")
+	}
+}
+
+// allowWebhookRepository rejects a delivery from a repository other than GITHUB_WEBHOOK_REPOSITORY.
+// A rejected delivery is forgotten, so it can be redelivered once the repository is configured.
+func (h *APIHandler) allowWebhookRepository(w http.ResponseWriter, delivery, repository string) bool {
+	if h.webhooks.AllowsRepository(repository) {
+		return true
+	}
+	h.webhooks.Forget(delivery)
+	log.Printf("Warning: Rejected GitHub webhook delivery %s from repository %q", delivery, repository)
+	http.Error(w, "Repository not allowed", http.StatusForbidden)
+	return false
+}
+
+// writeWebhookStatus acknowledges a delivery
+func writeWebhookStatus(w http.ResponseWriter, code int, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
+
+// ingestWebhook writes the submissions and scoreboards a delivery changed into the workspace at ref,
+// reloads the scoreboards and tells live scoreboards about ranks that moved. A failed delivery is
+// forgotten so GitHub can redeliver it.
+func (h *APIHandler) ingestWebhook(delivery, eventType, repository, ref string, changedPaths func(context.Context) ([]string, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookIngestTimeout)
+	defer cancel()
+
+	paths, err := changedPaths(ctx)
+	if err != nil {
+		log.Printf("Error listing files of %s delivery %s: %v", eventType, delivery, err)
+		h.webhooks.Forget(delivery)
+		return
+	}
+	files := services.WebhookFiles(paths)
+	if len(files) == 0 {
+		return
+	}
+
+	// Ranks before the reload, for the users whose solutions changed
+	challenges := h.challengeService.GetChallenges()
+	type userChallenge struct {
+		username    string
+		challengeID int
+	}
+	type userPackage struct {
+		username    string
+		packageName string
+	}
+	previousMainRanks := make(map[string]int)
+	previousChallengeRanks := make(map[userChallenge]int)
+	previousPackageRanks := make(map[userPackage]int)
+	for _, file := range files {
+		if file.Username != "" && file.PackageName != "" {
+			key := userPackage{file.Username, file.PackageName}
+			if _, ok := previousPackageRanks[key]; !ok {
+				previousPackageRanks[key] = h.packageBoardRank(file.PackageName, file.Username)
+			}
+		}
+		if file.Username == "" || file.ChallengeID == 0 {
+			continue
+		}
+		if _, ok := previousMainRanks[file.Username]; !ok {
+			previousMainRanks[file.Username] = h.calculateMainScoreboardRank(file.Username)
+		}
+		if challenge, ok := challenges[file.ChallengeID]; ok {
+			previousChallengeRanks[userChallenge{file.Username, file.ChallengeID}] = h.challengeBoardRank(challenge, file.Username)
+		}
+	}
+
+	result, err := h.webhooks.Ingest(ctx, repository, ref, files)
+	if err != nil {
+		log.Printf("Error re-ingesting %s delivery %s: %v", eventType, delivery, err)
+		h.webhooks.Forget(delivery)
+	}
+	if len(result.Updated) == 0 && len(result.Removed) == 0 {
+		return
+	}
+	log.Printf("GitHub %s delivery %s: updated %d and removed %d submission files", eventType, delivery, len(result.Updated), len(result.Removed))
+
+	// Scoreboards and attempt status are read from the files just written
+	if err := h.scoreboardService.LoadScoreboards(challenges); err != nil {
+		log.Printf("Error reloading scoreboards: %v", err)
+	}
+	h.userService.ClearCache()
+	h.syncAchievements(webhookUsers(files))
+
+	now := time.Now()
+	for key, previousRank := range previousChallengeRanks {
+		event := services.HubEvent{
+			Username:    key.username,
+			ChallengeID: key.challengeID,
+			Leaderboard: services.LeaderboardChallenge,
+			At:          now,
+		}
+		h.publishRankChange(event, previousRank, h.challengeBoardRank(challenges[key.challengeID], key.username))
+	}
+	for key, previousRank := range previousPackageRanks {
+		event := services.HubEvent{
+			Username:    key.username,
+			PackageName: key.packageName,
+			Leaderboard: services.LeaderboardPackage,
+			At:          now,
+		}
+		h.publishRankChange(event, previousRank, h.packageBoardRank(key.packageName, key.username))
+	}
+	for username, previousRank := range previousMainRanks {
+		event := services.HubEvent{
+			Username:    username,
+			Leaderboard: services.LeaderboardMain,
+			At:          now,
+		}
+		h.publishRankChange(event, previousRank, h.calculateMainScoreboardRank(username))
+	}
+}
+
+// webhookUsers returns the users whose badges a delivery's files can change: the authors of the
+// solutions, or everyone if a scoreboard changed
+func webhookUsers(files []services.WebhookFile) map[string]bool {
+	users := make(map[string]bool)
+	for _, file := range files {
+		if file.Username == "" {
+			return nil
+		}
+		users[file.Username] = true
+	}
+	return users
+}
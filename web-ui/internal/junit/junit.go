@@ -0,0 +1,92 @@
+// Package junit writes test results as JUnit XML, the report format CI servers and learning
+// management systems ingest.
+package junit
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// TestSuites is the root of a report
+type TestSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     float64     `xml:"time,attr"`
+	Suites   []TestSuite `xml:"testsuite"`
+}
+
+// TestSuite groups the test cases of one challenge
+type TestSuite struct {
+	Name      string     `xml:"name,attr"`
+	Tests     int        `xml:"tests,attr"`
+	Failures  int        `xml:"failures,attr"`
+	Errors    int        `xml:"errors,attr"`
+	Skipped   int        `xml:"skipped,attr"`
+	Time      float64    `xml:"time,attr"`
+	Timestamp string     `xml:"timestamp,attr,omitempty"` // ISO 8601, without a time zone
+	Cases     []TestCase `xml:"testcase"`
+}
+
+// TestCase is one test's result. A case without a failure, error or skip passed.
+type TestCase struct {
+	Name      string   `xml:"name,attr"`
+	ClassName string   `xml:"classname,attr"`
+	Time      float64  `xml:"time,attr"`
+	Failure   *Message `xml:"failure,omitempty"`
+	Error     *Message `xml:"error,omitempty"`
+	Skipped   *Message `xml:"skipped,omitempty"`
+	SystemOut string   `xml:"system-out,omitempty"`
+}
+
+// Message describes why a test case failed, errored or was skipped
+type Message struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// TimestampFormat is the layout of a suite's timestamp
+const TimestampFormat = "2006-01-02T15:04:05"
+
+// Add appends a case to the suite and updates its counts
+func (s *TestSuite) Add(testCase TestCase) {
+	s.Cases = append(s.Cases, testCase)
+	s.Tests++
+	s.Time += testCase.Time
+	switch {
+	case testCase.Failure != nil:
+		s.Failures++
+	case testCase.Error != nil:
+		s.Errors++
+	case testCase.Skipped != nil:
+		s.Skipped++
+	}
+}
+
+// Add appends a suite to the report and updates its counts
+func (r *TestSuites) Add(suite TestSuite) {
+	r.Suites = append(r.Suites, suite)
+	r.Tests += suite.Tests
+	r.Failures += suite.Failures
+	r.Errors += suite.Errors
+	r.Skipped += suite.Skipped
+	r.Time += suite.Time
+}
+
+// Write writes the report as an indented XML document
+func (r *TestSuites) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(r); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Badge is an achievement a user can earn
+type Badge struct {
+	ID          string `json:"id"` // e.g. "all-advanced" or "package-gin"
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	Color       string `json:"color"`                 // Hex color of the SVG badge, without the #
+	PackageName string `json:"packageName,omitempty"` // Set on badges for finishing a package learning path
+}
+
+// AwardedBadge records that a user earned a badge
+type AwardedBadge struct {
+	Username  string    `json:"username"`
+	BadgeID   string    `json:"badgeId"`
+	AwardedAt time.Time `json:"awardedAt"`
+}
+
+// EarnedBadge is a badge with when a user earned it
+type EarnedBadge struct {
+	Badge
+	AwardedAt time.Time `json:"awardedAt"`
+}
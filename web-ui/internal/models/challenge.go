@@ -14,24 +14,64 @@ type Challenge struct {
 	TestFile          string `json:"testFile"`
 	LearningMaterials string `json:"learningMaterials"`
 	Hints             string `json:"hints"`
+	TimeoutSeconds    int    `json:"timeoutSeconds,omitempty"` // Wall-clock limit for test runs, 0 uses the server default
+	BenchmarkCount    int    `json:"benchmarkCount,omitempty"` // Benchmark runs per submission, 0 if the challenge is not scored by speedup
+	Dir               string `json:"-"`                        // Challenge directory holding its go.mod and go.sum
 }
 
-// Submission represents a user's submitted solution
+// Actions a stored submission can record
+const (
+	ActionRun    = "run"    // Tests run from the editor
+	ActionSubmit = "submit" // A solution submitted for the scoreboard
+)
+
+// Submission represents a user's submitted solution, or one of their test runs
 type Submission struct {
-	Username    string    `json:"username"`
-	ChallengeID int       `json:"challengeId"`
-	Code        string    `json:"code"`
-	SubmittedAt time.Time `json:"submittedAt"`
-	Passed      bool      `json:"passed"`
-	TestOutput  string    `json:"testOutput"`
-	ExecutionMs int64     `json:"executionMs"`
+	ID                 int64            `json:"id"`     // Assigned by the submission store
+	Action             string           `json:"action"` // ActionRun or ActionSubmit
+	Username           string           `json:"username"`
+	ChallengeID        int              `json:"challengeId"` // 0 for a package challenge
+	PackageName        string           `json:"packageName,omitempty"`
+	PackageChallengeID string           `json:"packageChallengeId,omitempty"` // Set with PackageName
+	Code               string           `json:"code"`
+	SubmittedAt        time.Time        `json:"submittedAt"`
+	Passed             bool             `json:"passed"`
+	TestOutput         string           `json:"testOutput"`
+	ExecutionMs        int64            `json:"executionMs"`
+	TestsPassed        int              `json:"testsPassed"`
+	TestsTotal         int              `json:"testsTotal"`
+	Tests              []*TestCase      `json:"tests,omitempty"`
+	Speedup            float64          `json:"speedup,omitempty"`    // Overall speedup over the reference implementations
+	Benchmarks         *BenchmarkReport `json:"benchmarks,omitempty"` // Benchmark results for challenges scored by speedup
+	Coverage           *CoverageReport  `json:"coverage,omitempty"`   // Which lines of the submission the tests reached
+}
+
+// TestCase represents a single test or subtest reported by go test -json
+type TestCase struct {
+	Name     string      `json:"name"`     // Full test name, e.g. "TestSum/Zero_values"
+	Status   string      `json:"status"`   // "pass", "fail", "skip" or "incomplete"
+	Elapsed  float64     `json:"elapsed"`  // Duration in seconds
+	Output   string      `json:"output"`   // Output logged by this test
+	Subtests []*TestCase `json:"subtests"` // Nested t.Run cases
+}
+
+// TestSummary counts leaf test cases by status
+type TestSummary struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+	Total   int `json:"total"`
 }
 
 // ScoreboardEntry represents an entry in the scoreboard
 type ScoreboardEntry struct {
 	Username    string    `json:"username"`
 	ChallengeID int       `json:"challengeId"`
-	SubmittedAt time.Time `json:"submittedAt"`
+	SubmittedAt time.Time `json:"submittedAt"` // Zero if the scoreboard does not record it
+	TestsPassed int       `json:"testsPassed"`
+	TestsTotal  int       `json:"testsTotal"`
+	ExecutionMs int64     `json:"executionMs,omitempty"`
+	Speedup     float64   `json:"speedup,omitempty"` // Overall benchmark speedup, for challenges scored by speedup
 }
 
 // UserAttemptedChallenges tracks attempted challenges by username
@@ -49,3 +89,84 @@ type ScoreboardMap map[int][]ScoreboardEntry
 
 // UserAttemptsMap is a type alias for user attempts tracking
 type UserAttemptsMap map[string]*UserAttemptedChallenges
+
+// Diagnostic is a finding from the race detector, go vet or staticcheck
+type Diagnostic struct {
+	Tool    string `json:"tool"`              // race, vet or staticcheck
+	File    string `json:"file,omitempty"`    // Base name of the file, such as solution-template.go
+	Line    int    `json:"line,omitempty"`    // 1-based line, 0 if unknown
+	Column  int    `json:"column,omitempty"`  // 1-based column, 0 if unknown
+	Message string `json:"message"`           // One line summary
+	Code    string `json:"code,omitempty"`    // Check identifier, such as SA4006
+	Details string `json:"details,omitempty"` // Full report, such as the race detector's stack traces
+}
+
+// BenchmarkResult is the mean of a benchmark's runs as reported by go test -bench -benchmem
+type BenchmarkResult struct {
+	Name        string  `json:"name"`        // Benchmark name without its prefix and GOMAXPROCS suffix, e.g. "OptimizedSort/100"
+	Runs        int     `json:"runs"`        // How many times the benchmark ran (-count)
+	Iterations  int64   `json:"iterations"`  // Total iterations over all runs
+	NsPerOp     float64 `json:"nsPerOp"`     // Mean time per operation
+	BytesPerOp  float64 `json:"bytesPerOp"`  // Mean bytes allocated per operation
+	AllocsPerOp float64 `json:"allocsPerOp"` // Mean allocations per operation
+}
+
+// BenchmarkComparison compares an optimized function with its slow reference implementation
+type BenchmarkComparison struct {
+	Name                 string  `json:"name"`      // Optimized benchmark, e.g. "OptimizedSort/100"
+	Reference            string  `json:"reference"` // Reference benchmark, e.g. "SlowSort/100"
+	NsPerOp              float64 `json:"nsPerOp"`
+	ReferenceNsPerOp     float64 `json:"referenceNsPerOp"`
+	BytesPerOp           float64 `json:"bytesPerOp"`
+	ReferenceBytesPerOp  float64 `json:"referenceBytesPerOp"`
+	AllocsPerOp          float64 `json:"allocsPerOp"`
+	ReferenceAllocsPerOp float64 `json:"referenceAllocsPerOp"`
+	Speedup              float64 `json:"speedup"` // Reference time divided by optimized time
+}
+
+// BenchmarkReport holds the benchmark results of a run
+type BenchmarkReport struct {
+	Results     []BenchmarkResult     `json:"results"`
+	Comparisons []BenchmarkComparison `json:"comparisons"`
+	Speedup     float64               `json:"speedup"`         // Geometric mean of the comparison speedups
+	Error       string                `json:"error,omitempty"` // Why the benchmarks did not complete
+}
+
+// CoverageBlock is a basic block of the submission file from a go test coverage profile
+type CoverageBlock struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+	Statements  int `json:"statements"`
+	Count       int `json:"count"` // How often the block ran, 0 if the tests never reached it
+}
+
+// FunctionCoverage is the statement coverage of a function in the submission file
+type FunctionCoverage struct {
+	Name       string  `json:"name"` // Function name, or Type.Method for methods
+	Line       int     `json:"line"`
+	Statements int     `json:"statements"`
+	Percent    float64 `json:"percent"`
+}
+
+// CoverageReport is the coverage of the submission file by the challenge's tests
+type CoverageReport struct {
+	Percent        float64            `json:"percent"` // Statements covered in the whole file
+	Functions      []FunctionCoverage `json:"functions"`
+	Blocks         []CoverageBlock    `json:"blocks"`
+	CoveredLines   []int              `json:"coveredLines"`
+	UncoveredLines []int              `json:"uncoveredLines"` // Lines none of whose code ran
+}
+
+// AttemptSummary describes one of a user's runs or submits of a challenge, without its code
+type AttemptSummary struct {
+	ID          int64     `json:"id"`
+	Version     int       `json:"version"` // 1 for the user's first attempt at the challenge
+	Action      string    `json:"action"`
+	SubmittedAt time.Time `json:"submittedAt"`
+	Passed      bool      `json:"passed"`
+	TestsPassed int       `json:"testsPassed"`
+	TestsTotal  int       `json:"testsTotal"`
+	ExecutionMs int64     `json:"executionMs"`
+}
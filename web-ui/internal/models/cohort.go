@@ -0,0 +1,55 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Cohort is a named group of users, such as a bootcamp class, whose leaderboards can be viewed on their own
+type Cohort struct {
+	ID        string    `json:"id"` // URL-safe, e.g. "spring-2025"
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	Members   []string  `json:"members"` // Lowercase usernames in order
+}
+
+// HasMember reports whether a user belongs to the cohort. Like GitHub usernames, it ignores case.
+func (c Cohort) HasMember(username string) bool {
+	for _, member := range c.Members {
+		if strings.EqualFold(member, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// CohortStats aggregates a cohort's progress
+type CohortStats struct {
+	Members              int                    `json:"members"`
+	ActiveMembers        int                    `json:"activeMembers"` // Members who completed a core challenge
+	MedianCompleted      float64                `json:"medianCompleted"`
+	MeanCompleted        float64                `json:"meanCompleted"`
+	MedianCompletionRate float64                `json:"medianCompletionRate"` // Percent of the core challenges
+	HardestChallenge     *CohortChallengeStats  `json:"hardestChallenge"`     // Lowest completion rate among attempted challenges, nil if none
+	Challenges           []CohortChallengeStats `json:"challenges"`           // Core challenges a member attempted, in ID order
+	Packages             []CohortPackageStats   `json:"packages"`             // In package name order
+}
+
+// CohortChallengeStats is how a cohort did on one core challenge
+type CohortChallengeStats struct {
+	ID             int     `json:"id"`
+	Title          string  `json:"title"`
+	Difficulty     string  `json:"difficulty"`
+	Attempted      int     `json:"attempted"` // Members with a scoreboard entry or stored attempt, including those who completed it
+	Completed      int     `json:"completed"`
+	CompletionRate float64 `json:"completionRate"` // Percent of the members who attempted it
+}
+
+// CohortPackageStats is how a cohort did on one package's learning path
+type CohortPackageStats struct {
+	Name            string  `json:"name"`
+	DisplayName     string  `json:"displayName"`
+	TotalChallenges int     `json:"totalChallenges"`
+	StartedMembers  int     `json:"startedMembers"`
+	MedianCompleted float64 `json:"medianCompleted"`
+}
@@ -54,6 +54,7 @@ type ChallengeMetadata struct {
 	BonusPoints         []string `json:"bonus_points"`
 	Icon                string   `json:"icon,omitempty"`
 	Order               int      `json:"order"`
+	TimeoutSeconds      int      `json:"timeout_seconds,omitempty"` // Wall-clock limit for test runs
 }
 
 // PackageChallenge represents a challenge specific to a package
@@ -77,7 +78,9 @@ type PackageChallenge struct {
 	Prerequisites       []string `json:"prerequisites"`
 	Icon                string   `json:"icon,omitempty"`
 	Order               int      `json:"order"`
-	Status              string   `json:"status,omitempty"` // "available", "coming-soon", etc.
+	Status              string   `json:"status,omitempty"`          // "available", "coming-soon", etc.
+	TimeoutSeconds      int      `json:"timeout_seconds,omitempty"` // Wall-clock limit for test runs
+	Dir                 string   `json:"-"`                         // Challenge directory holding its go.mod and go.sum
 }
 
 // PackageSubmission represents a user's submitted solution for a package challenge
@@ -98,6 +101,8 @@ type PackageSubmission struct {
 type PackageProgress struct {
 	Username            string        `json:"username"`
 	PackageName         string        `json:"package_name"`
+	DisplayName         string        `json:"display_name"`
+	TotalChallenges     int           `json:"total_challenges"`
 	CompletedChallenges []string      `json:"completed_challenges"`
 	InProgress          string        `json:"in_progress"`
 	StartedAt           time.Time     `json:"started_at"`
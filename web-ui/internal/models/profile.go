@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Core challenge statuses in a user profile
+const (
+	ChallengeStatusCompleted  = "completed"
+	ChallengeStatusAttempted  = "attempted"
+	ChallengeStatusNotStarted = "not-started"
+)
+
+// UserProfile combines a user's progress on the core challenges and the package tracks
+type UserProfile struct {
+	Username    string             `json:"username"`
+	IsSponsor   bool               `json:"isSponsor"`
+	Core        CoreProgress       `json:"core"`
+	Packages    []*PackageProgress `json:"packages"` // In package name order
+	Ranks       ProfileRanks       `json:"ranks"`
+	Points      float64            `json:"points"`
+	Achievement string             `json:"achievement"` // Title for the number of completed core challenges
+	Badges      []EarnedBadge      `json:"badges"`      // Oldest first
+}
+
+// CoreProgress is a user's progress on the core challenges
+type CoreProgress struct {
+	Completed  int                     `json:"completed"`
+	Attempted  int                     `json:"attempted"` // Attempted but not completed
+	Total      int                     `json:"total"`
+	Challenges []CoreChallengeProgress `json:"challenges"` // In challenge ID order
+}
+
+// CoreChallengeProgress is a user's result on one core challenge
+type CoreChallengeProgress struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Difficulty  string    `json:"difficulty"`
+	Status      string    `json:"status"` // ChallengeStatusCompleted, ChallengeStatusAttempted or ChallengeStatusNotStarted
+	TestsPassed int       `json:"testsPassed"`
+	TestsTotal  int       `json:"testsTotal"`
+	Points      float64   `json:"points"`
+	LastAttempt time.Time `json:"lastAttempt"` // Zero if unknown
+}
+
+// ProfileRanks are a user's positions on the leaderboards, 0 where unranked
+type ProfileRanks struct {
+	Main     int            `json:"main"`     // By completed core challenges
+	Points   int            `json:"points"`   // By points
+	Packages map[string]int `json:"packages"` // Package name -> rank on its leaderboard
+}
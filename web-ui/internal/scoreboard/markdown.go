@@ -0,0 +1,124 @@
+package scoreboard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Markdown table columns, as written by RenderMarkdown
+const (
+	markdownHeader    = "| Username   | Passed Tests | Total Tests |"
+	markdownSeparator = "|------------|--------------|-------------|"
+)
+
+// ParseMarkdown reads a board from a SCOREBOARD.md table. Columns are found by their header,
+// so tables with extra columns such as Rank are read too; columns other than the username and
+// test counts are ignored.
+func ParseMarkdown(content string) (*Board, error) {
+	board := &Board{Entries: []Entry{}}
+
+	var columns map[string]int // Lowercase header name -> cell index
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+
+		if board.Title == "" && columns == nil && strings.HasPrefix(line, "# ") {
+			board.Title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+			continue
+		}
+		if !strings.HasPrefix(line, "|") {
+			continue
+		}
+
+		cells := tableCells(line)
+		if columns == nil {
+			columns = make(map[string]int)
+			for index, cell := range cells {
+				columns[strings.ToLower(cell)] = index
+			}
+			if _, ok := columns["username"]; !ok {
+				return nil, fmt.Errorf("line %d: table has no Username column", i+1)
+			}
+			continue
+		}
+		if isSeparatorRow(cells) {
+			continue
+		}
+
+		entry, err := parseRow(cells, columns)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		if entry.Username != "" {
+			board.Entries = append(board.Entries, entry)
+		}
+	}
+
+	return board, nil
+}
+
+// tableCells splits a table row into its trimmed cells
+func tableCells(line string) []string {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// isSeparatorRow reports whether cells are the |---|---| row under a table header
+func isSeparatorRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.Trim(cell, "-: ") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseRow reads an entry from a table row
+func parseRow(cells []string, columns map[string]int) (Entry, error) {
+	cell := func(name string) string {
+		if index, ok := columns[name]; ok && index < len(cells) {
+			return cells[index]
+		}
+		return ""
+	}
+
+	entry := Entry{Username: cell("username")}
+
+	for _, count := range []struct {
+		column string
+		value  *int
+	}{
+		{"passed tests", &entry.TestsPassed},
+		{"total tests", &entry.TestsTotal},
+	} {
+		text := cell(count.column)
+		if text == "" {
+			continue
+		}
+		value, err := strconv.Atoi(text)
+		if err != nil {
+			return entry, fmt.Errorf("invalid %s %q for %s", count.column, text, entry.Username)
+		}
+		*count.value = value
+	}
+
+	return entry, nil
+}
+
+// RenderMarkdown renders a board as the SCOREBOARD.md table
+func RenderMarkdown(board *Board) string {
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "# %s\n\n", board.Title)
+	out.WriteString(markdownHeader + "\n")
+	out.WriteString(markdownSeparator + "\n")
+	for _, entry := range board.Entries {
+		fmt.Fprintf(&out, "| %s | %d | %d |\n", entry.Username, entry.TestsPassed, entry.TestsTotal)
+	}
+
+	return out.String()
+}
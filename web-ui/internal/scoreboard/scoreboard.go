@@ -0,0 +1,138 @@
+// Package scoreboard reads and writes challenge scoreboards. A scoreboard's data is kept in
+// scoreboard.json next to SCOREBOARD.md, which is rendered from it for reading on GitHub.
+// Directories that only have a SCOREBOARD.md are read from its table.
+package scoreboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"web-ui/internal/utils"
+)
+
+// Scoreboard file names within a challenge directory
+const (
+	JSONFileName     = "scoreboard.json"
+	MarkdownFileName = "SCOREBOARD.md"
+)
+
+// Entry is one user's result on a challenge
+type Entry struct {
+	Username    string    `json:"username"`
+	TestsPassed int       `json:"testsPassed"`
+	TestsTotal  int       `json:"testsTotal"`
+	SubmittedAt time.Time `json:"submittedAt"`           // Zero, and left out of the JSON, for undated entries
+	ExecutionMs int64     `json:"executionMs,omitempty"` // Zero when unknown, as for entries read from a markdown table
+}
+
+// MarshalJSON leaves out the submission time of undated entries
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type entry Entry
+	if !e.SubmittedAt.IsZero() {
+		return json.Marshal(entry(e))
+	}
+	return json.Marshal(struct {
+		entry
+		SubmittedAt *time.Time `json:"submittedAt,omitempty"`
+	}{entry: entry(e)})
+}
+
+// Completed reports whether the entry passed every test
+func (e Entry) Completed() bool {
+	return e.TestsPassed > 0 && e.TestsPassed == e.TestsTotal
+}
+
+// Board is a challenge scoreboard
+type Board struct {
+	Title   string  `json:"title"` // e.g. "Scoreboard for challenge-1"
+	Entries []Entry `json:"entries"`
+}
+
+// Find returns the entry of a user. Usernames match exactly.
+func (b *Board) Find(username string) (Entry, bool) {
+	for _, entry := range b.Entries {
+		if entry.Username == username {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Record adds a user's entry, replacing any entry they already have, and keeps the board sorted
+func (b *Board) Record(entry Entry) {
+	for i := range b.Entries {
+		if b.Entries[i].Username == entry.Username {
+			b.Entries[i] = entry
+			b.Sort()
+			return
+		}
+	}
+	b.Entries = append(b.Entries, entry)
+	b.Sort()
+}
+
+// Sort orders entries by passed tests, most first, then by username
+func (b *Board) Sort() {
+	sort.SliceStable(b.Entries, func(i, j int) bool {
+		if b.Entries[i].TestsPassed != b.Entries[j].TestsPassed {
+			return b.Entries[i].TestsPassed > b.Entries[j].TestsPassed
+		}
+		return b.Entries[i].Username < b.Entries[j].Username
+	})
+}
+
+// Load reads the scoreboard in dir from scoreboard.json, or from SCOREBOARD.md if there is no JSON file.
+// The error wraps fs.ErrNotExist if the directory has neither.
+func Load(dir string) (*Board, error) {
+	data, err := os.ReadFile(filepath.Join(dir, JSONFileName))
+	if err == nil {
+		var board Board
+		if err := json.Unmarshal(data, &board); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", filepath.Join(dir, JSONFileName), err)
+		}
+		return &board, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	markdownPath := filepath.Join(dir, MarkdownFileName)
+	content, err := os.ReadFile(markdownPath)
+	if err != nil {
+		return nil, err
+	}
+	board, err := ParseMarkdown(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", markdownPath, err)
+	}
+	return board, nil
+}
+
+// Save writes the board to scoreboard.json and renders it to SCOREBOARD.md in dir. Each file is
+// replaced atomically, so the server never reads a partly written scoreboard.
+func Save(dir string, board *Board) error {
+	if err := SaveJSON(dir, board); err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(filepath.Join(dir, MarkdownFileName), []byte(RenderMarkdown(board)), 0644)
+}
+
+// SaveJSON writes the board to scoreboard.json in dir and leaves SCOREBOARD.md as it is
+func SaveJSON(dir string, board *Board) error {
+	if board.Entries == nil {
+		board.Entries = []Entry{}
+	}
+	board.Sort()
+
+	data, err := json.MarshalIndent(board, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(filepath.Join(dir, JSONFileName), append(data, '\n'), 0644)
+}
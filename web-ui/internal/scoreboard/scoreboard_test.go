@@ -0,0 +1,212 @@
+package scoreboard
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *Board
+		wantErr string
+	}{
+		{
+			name: "core table without a blank line after the title",
+			content: "# Scoreboard for challenge-1\n" +
+				"| Username   | Passed Tests | Total Tests |\n" +
+				"|------------|--------------|-------------|\n" +
+				"| bobby | 6 | 6 |\n" +
+				"| bob | 4 | 6 |\n",
+			want: &Board{Title: "Scoreboard for challenge-1", Entries: []Entry{
+				{Username: "bobby", TestsPassed: 6, TestsTotal: 6},
+				{Username: "bob", TestsPassed: 4, TestsTotal: 6},
+			}},
+		},
+		{
+			name: "package table with a rank column",
+			content: "# Scoreboard for gin challenge-1-basic-routing\n\n" +
+				"| Rank | Username | Passed Tests | Total Tests |\n" +
+				"|:----:|----------|-------------:|-------------|\n" +
+				"| 1 | bob | 11 | 11 |\n",
+			want: &Board{Title: "Scoreboard for gin challenge-1-basic-routing", Entries: []Entry{
+				{Username: "bob", TestsPassed: 11, TestsTotal: 11},
+			}},
+		},
+		{
+			name:    "empty table",
+			content: "# Scoreboard for challenge-2\n\n| Username | Passed Tests | Total Tests |\n|---|---|---|\n",
+			want:    &Board{Title: "Scoreboard for challenge-2", Entries: []Entry{}},
+		},
+		{
+			name:    "invalid count",
+			content: "| Username | Passed Tests | Total Tests |\n|---|---|---|\n| bob | six | 6 |\n",
+			wantErr: `line 3: invalid passed tests "six" for bob`,
+		},
+		{
+			name:    "no username column",
+			content: "| Name | Passed Tests |\n|---|---|\n",
+			wantErr: "line 1: table has no Username column",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseMarkdown(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("ParseMarkdown() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMarkdown() error = %v", err)
+			}
+			if !reflect.DeepEqual(board, tt.want) {
+				t.Errorf("ParseMarkdown() = %+v, want %+v", board, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		board *Board
+	}{
+		{
+			name: "prefixed usernames",
+			board: &Board{Title: "Scoreboard for challenge-1", Entries: []Entry{
+				{Username: "bob", TestsPassed: 6, TestsTotal: 6},
+				{Username: "bobby", TestsPassed: 6, TestsTotal: 6},
+				{Username: "alice-b", TestsPassed: 2, TestsTotal: 6},
+			}},
+		},
+		{
+			name:  "no entries",
+			board: &Board{Title: "Scoreboard for gin challenge-1-basic-routing", Entries: []Entry{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markdown := RenderMarkdown(tt.board)
+
+			board, err := ParseMarkdown(markdown)
+			if err != nil {
+				t.Fatalf("ParseMarkdown() error = %v", err)
+			}
+			if !reflect.DeepEqual(board, tt.board) {
+				t.Errorf("ParseMarkdown(RenderMarkdown()) = %+v, want %+v", board, tt.board)
+			}
+			if again := RenderMarkdown(board); again != markdown {
+				t.Errorf("second render =\n%s\nwant\n%s", again, markdown)
+			}
+		})
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	submittedAt := time.Date(2025, 3, 14, 9, 26, 53, 0, time.UTC)
+	tests := []struct {
+		name  string
+		entry Entry
+		keys  []string // JSON keys of the entry
+	}{
+		{
+			name:  "dated entry",
+			entry: Entry{Username: "bob", TestsPassed: 6, TestsTotal: 6, SubmittedAt: submittedAt, ExecutionMs: 412},
+			keys:  []string{"executionMs", "submittedAt", "testsPassed", "testsTotal", "username"},
+		},
+		{
+			name:  "undated entry from a markdown table",
+			entry: Entry{Username: "bobby", TestsPassed: 4, TestsTotal: 6},
+			keys:  []string{"testsPassed", "testsTotal", "username"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.entry)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+
+			var fields map[string]interface{}
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			var keys []string
+			for key := range fields {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("keys of %s = %v, want %v", data, keys, tt.keys)
+			}
+
+			var entry Entry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(entry, tt.entry) {
+				t.Errorf("round trip = %+v, want %+v", entry, tt.entry)
+			}
+		})
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	board := &Board{Title: "Scoreboard for challenge-1", Entries: []Entry{
+		{Username: "bobby", TestsPassed: 4, TestsTotal: 6},
+		{Username: "bob", TestsPassed: 6, TestsTotal: 6, SubmittedAt: time.Date(2025, 3, 14, 9, 26, 53, 0, time.UTC), ExecutionMs: 412},
+	}}
+	if err := Save(dir, board); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, board) {
+		t.Errorf("Load() = %+v, want %+v", loaded, board)
+	}
+
+	// Without the JSON file, the rendered markdown gives the same users and counts
+	if err := os.Remove(filepath.Join(dir, JSONFileName)); err != nil {
+		t.Fatal(err)
+	}
+	fromMarkdown, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() from markdown error = %v", err)
+	}
+	for i, entry := range fromMarkdown.Entries {
+		want := board.Entries[i]
+		want.SubmittedAt, want.ExecutionMs = time.Time{}, 0
+		if entry != want {
+			t.Errorf("markdown entry %d = %+v, want %+v", i, entry, want)
+		}
+	}
+
+	if bob, ok := fromMarkdown.Find("bob"); !ok || bob.TestsPassed != 6 {
+		t.Errorf("Find(bob) = %+v, %v", bob, ok)
+	}
+	if _, ok := fromMarkdown.Find("bo"); ok {
+		t.Errorf("Find(bo) matched a longer username")
+	}
+
+	// Recording bob replaces only bob's entry
+	fromMarkdown.Record(Entry{Username: "bob", TestsPassed: 3, TestsTotal: 6})
+	if got := RenderMarkdown(fromMarkdown); !strings.HasSuffix(got, "| bobby | 4 | 6 |\n| bob | 3 | 6 |\n") {
+		t.Errorf("after Record(bob) =\n%s", got)
+	}
+}
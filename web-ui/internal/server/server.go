@@ -23,6 +23,11 @@ type Server struct {
 	executionService  *services.ExecutionService
 	packageService    *services.PackageService
 	aiService         *services.AIService
+	submissionStore   services.SubmissionStore
+	achievementStore  services.AchievementStore
+	cohortStore       services.CohortStore
+	webhookDeliveries services.WebhookDeliveryStore
+	authService       *services.AuthService
 }
 
 // NewServer creates a new server instance
@@ -34,6 +39,11 @@ func NewServer(
 	executionService *services.ExecutionService,
 	packageService *services.PackageService,
 	aiService *services.AIService,
+	submissionStore services.SubmissionStore,
+	achievementStore services.AchievementStore,
+	cohortStore services.CohortStore,
+	webhookDeliveries services.WebhookDeliveryStore,
+	authService *services.AuthService,
 ) *Server {
 	return &Server{
 		content:           content,
@@ -43,11 +53,16 @@ func NewServer(
 		executionService:  executionService,
 		packageService:    packageService,
 		aiService:         aiService,
+		submissionStore:   submissionStore,
+		achievementStore:  achievementStore,
+		cohortStore:       cohortStore,
+		webhookDeliveries: webhookDeliveries,
+		authService:       authService,
 	}
 }
 
-// SetupRoutes configures all HTTP routes
-func (s *Server) SetupRoutes() *http.ServeMux {
+// SetupRoutes configures all HTTP routes, behind CSRF protection
+func (s *Server) SetupRoutes() http.Handler {
 	mux := http.NewServeMux()
 
 	// Setup static file handling
@@ -61,32 +76,63 @@ func (s *Server) SetupRoutes() *http.ServeMux {
 		s.executionService,
 		s.packageService,
 		s.aiService,
+		s.submissionStore,
+		s.achievementStore,
+		s.cohortStore,
+		s.webhookDeliveries,
+		s.authService,
 	)
 
+	// Award badges earned before they were stored, without delaying startup
+	go apiHandler.BackfillAchievements()
+
 	webHandler := handlers.NewWebHandler(
 		s.content,
 		s.challengeService,
 		s.scoreboardService,
 		s.userService,
 		s.packageService,
+		s.cohortStore,
+		s.authService,
 	)
 
+	// Sign-in routes
+	mux.HandleFunc("/login", webHandler.LoginPage)
+	mux.HandleFunc("/auth/", webHandler.HandleAuth)
+	mux.HandleFunc("/api/session", apiHandler.GetSession)
+
 	// API routes
 	mux.HandleFunc("/api/challenges", apiHandler.GetAllChallenges)
 	mux.HandleFunc("/api/challenges/", apiHandler.GetChallengeByID)
 	mux.HandleFunc("/api/submissions", apiHandler.HandleSubmissions)
+	mux.HandleFunc("/api/attempts", apiHandler.ListAttempts)
+	mux.HandleFunc("/api/attempts/", apiHandler.HandleAttempt)
 	mux.HandleFunc("/api/scoreboard/", apiHandler.GetScoreboard)
 	mux.HandleFunc("/api/run", apiHandler.RunCode)
+	mux.HandleFunc("/api/jobs", apiHandler.HandleJobs)
+	mux.HandleFunc("/api/jobs/", apiHandler.HandleJob)
 	mux.HandleFunc("/api/save-to-filesystem", apiHandler.SaveSubmissionToFilesystem)
 	mux.HandleFunc("/api/refresh-attempts", apiHandler.RefreshUserAttempts)
+	mux.HandleFunc("/api/git/status", apiHandler.GetGitStatus)
+	mux.HandleFunc("/api/git/submissions", apiHandler.HandleGitSubmissions)
+	mux.HandleFunc("/api/git/submissions/", apiHandler.HandleGitSubmissions)
 	mux.HandleFunc("/api/git-username", apiHandler.GetGitUsername)
 	mux.HandleFunc("/api/main-scoreboard-rank", apiHandler.GetMainScoreboardRank)
 	mux.HandleFunc("/api/main-leaderboard", apiHandler.GetMainLeaderboard)
+	mux.HandleFunc("/api/users/", apiHandler.GetUserProfile)
+	mux.HandleFunc("/api/events", apiHandler.StreamEvents)
+	mux.HandleFunc("/api/export/leaderboard", apiHandler.ExportMainLeaderboard)
+	mux.HandleFunc("/api/export/scoreboard/", apiHandler.ExportChallengeScoreboard)
+	mux.HandleFunc("/api/export/package-leaderboard", apiHandler.ExportPackageLeaderboard)
+	mux.HandleFunc("/api/export/junit/", apiHandler.ExportUserJUnit)
+	mux.HandleFunc("/api/cohorts", apiHandler.HandleCohorts)
+	mux.HandleFunc("/api/cohorts/", apiHandler.HandleCohort)
 
 	// Package challenge API routes
 	mux.HandleFunc("/api/package-leaderboard", apiHandler.GetPackageLeaderboard)
 	mux.HandleFunc("/api/packages/", apiHandler.HandlePackageChallenge)
-	mux.HandleFunc("/api/packages-save-to-filesystem", apiHandler.SavePackageChallengeToFilesystem)
+	// Saves package solutions the same way; kept for older clients
+	mux.HandleFunc("/api/packages-save-to-filesystem", apiHandler.SaveSubmissionToFilesystem)
 
 	// AI-powered API routes
 	mux.HandleFunc("/api/ai/code-review", apiHandler.AICodeReview)
@@ -94,12 +140,21 @@ func (s *Server) SetupRoutes() *http.ServeMux {
 	mux.HandleFunc("/api/ai/code-hint", apiHandler.AICodeHint)
 	mux.HandleFunc("/api/ai/debug", apiHandler.AIDebugResponse)
 
-	// GitHub webhook route
+	// Admin routes
+	admin := func(handler http.HandlerFunc) http.HandlerFunc {
+		return handlers.RequireRole(s.authService, services.RoleAdmin, handler)
+	}
+	mux.HandleFunc("/api/admin/reload", admin(apiHandler.ReloadChallenges))
+	mux.HandleFunc("/api/admin/clear-caches", admin(apiHandler.ClearCaches))
+	mux.HandleFunc("/api/admin/queue", admin(apiHandler.GetQueue))
+	mux.HandleFunc("/api/admin/attempts", admin(apiHandler.ListAllAttempts))
+
+	// GitHub webhook route; deliveries carry no session and are authenticated by their signature
 	mux.HandleFunc("/webhook/github", apiHandler.GitHubWebhookHandler)
 
-	// Debug route for sponsors
-	mux.HandleFunc("/api/debug/sponsors", apiHandler.GetSponsorsDebug)
-	mux.HandleFunc("/api/ai/status", func(w http.ResponseWriter, r *http.Request) {
+	// Debug routes, which expose sponsor data and part of the AI API key
+	mux.HandleFunc("/api/debug/sponsors", admin(apiHandler.GetSponsorsDebug))
+	mux.HandleFunc("/api/ai/status", admin(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		provider := os.Getenv("AI_PROVIDER")
 		if provider == "" {
@@ -134,7 +189,7 @@ func (s *Server) SetupRoutes() *http.ServeMux {
 			"has_valid_key":  hasValidKey,
 		}
 		json.NewEncoder(w).Encode(response)
-	})
+	}))
 
 	// Web routes
 	mux.HandleFunc("/", webHandler.HomePage)
@@ -142,6 +197,8 @@ func (s *Server) SetupRoutes() *http.ServeMux {
 	mux.HandleFunc("/interview", webHandler.InterviewPage)
 	mux.HandleFunc("/scoreboard", webHandler.ScoreboardPage)
 	mux.HandleFunc("/scoreboard/", webHandler.ScoreChallengeHandler)
+	mux.HandleFunc("/users/", webHandler.UserProfilePage)
+	mux.HandleFunc("/badges/", apiHandler.GetBadgeSVG)
 	mux.HandleFunc("/packages/", func(w http.ResponseWriter, r *http.Request) {
 		// Route to appropriate handler based on URL structure
 		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
@@ -161,7 +218,8 @@ func (s *Server) SetupRoutes() *http.ServeMux {
 		}
 	})
 
-	return mux
+	// GitHub signs its webhook deliveries instead
+	return handlers.RequireCSRF(s.authService, mux, "/webhook/github")
 }
 
 // setupStaticFiles configures static file serving
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// Achievement events
+const (
+	EventSubmissionPassed       = "submission-passed"        // A core challenge submission passed
+	EventPackageChallengePassed = "package-challenge-passed" // A package challenge submission passed
+	EventSync                   = "sync"                     // Progress changed elsewhere, e.g. on the scoreboards; every rule is checked
+)
+
+// AchievementEvent is something a user did that may earn them badges
+type AchievementEvent struct {
+	Type     string
+	Username string
+	At       time.Time // When it happened, and when the badges it earns are awarded
+}
+
+// AchievementFacts is what the rules know of a user's progress when an event is evaluated
+type AchievementFacts struct {
+	CoreDifficulties map[int]string             // Every core challenge -> its difficulty
+	CoreCompleted    map[int]bool               // Core challenges the user completed
+	PackagePaths     map[string][]string        // Package -> the challenges of its learning path
+	PackageCompleted map[string]map[string]bool // Package -> challenges the user completed
+	PassDates        []time.Time                // When the user passed challenges, in any order
+}
+
+// completedByDifficulty counts the user's completed core challenges and all core challenges of a difficulty
+func (f AchievementFacts) completedByDifficulty(difficulty string) (completed, total int) {
+	for id, challengeDifficulty := range f.CoreDifficulties {
+		if !strings.EqualFold(challengeDifficulty, difficulty) {
+			continue
+		}
+		total++
+		if f.CoreCompleted[id] {
+			completed++
+		}
+	}
+	return completed, total
+}
+
+// longestStreak returns the most consecutive UTC days on which the user passed a challenge
+func (f AchievementFacts) longestStreak() int {
+	days := make(map[time.Time]bool)
+	for _, passedAt := range f.PassDates {
+		if passedAt.IsZero() {
+			continue
+		}
+		passedAt = passedAt.UTC()
+		days[time.Date(passedAt.Year(), passedAt.Month(), passedAt.Day(), 0, 0, 0, 0, time.UTC)] = true
+	}
+
+	longest := 0
+	for day := range days {
+		// Count only from the first day of each run
+		if days[day.AddDate(0, 0, -1)] {
+			continue
+		}
+		length := 1
+		for days[day.AddDate(0, 0, length)] {
+			length++
+		}
+		if length > longest {
+			longest = length
+		}
+	}
+	return longest
+}
+
+// completionLevels are the achievement titles for numbers of completed core challenges, highest first
+var completionLevels = []struct {
+	Completed int
+	BadgeID   string
+	Title     string
+}{
+	{20, "level-master", "🔥 Master"},
+	{15, "level-expert", "⭐ Expert"},
+	{10, "level-advanced", "💪 Advanced"},
+	{5, "level-intermediate", "🚀 Intermediate"},
+	{0, "", "🌱 Beginner"},
+}
+
+// CompletionTitle returns the achievement title for a number of completed core challenges
+func CompletionTitle(completed int) string {
+	for _, level := range completionLevels {
+		if completed >= level.Completed {
+			return level.Title
+		}
+	}
+	return completionLevels[len(completionLevels)-1].Title
+}
+
+// LevelTitle returns the achievement title of the highest completion level among a user's badges
+func LevelTitle(badges []models.EarnedBadge) string {
+	earned := make(map[string]bool, len(badges))
+	for _, badge := range badges {
+		earned[badge.ID] = true
+	}
+	for _, level := range completionLevels {
+		if level.BadgeID == "" || earned[level.BadgeID] {
+			return level.Title
+		}
+	}
+	return completionLevels[len(completionLevels)-1].Title
+}
+
+// AchievementRule awards a badge when the facts meet its condition
+type AchievementRule struct {
+	Badge  models.Badge
+	Events []string // Events that can earn the badge; EventSync checks every rule
+	Earned func(facts AchievementFacts) bool
+}
+
+// triggeredBy reports whether an event is one the rule is checked on
+func (r AchievementRule) triggeredBy(eventType string) bool {
+	if eventType == EventSync {
+		return true
+	}
+	for _, event := range r.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// AchievementEngine evaluates events against its rules and awards the badges they earn
+type AchievementEngine struct {
+	store AchievementStore
+	rules []AchievementRule
+}
+
+// NewAchievementEngine creates an engine with the built-in rules and a rule for finishing each package's learning path
+func NewAchievementEngine(store AchievementStore, packages map[string]*models.Package) *AchievementEngine {
+	return &AchievementEngine{
+		store: store,
+		rules: defaultAchievementRules(packages),
+	}
+}
+
+// defaultAchievementRules returns the built-in rules, in the order badges are listed
+func defaultAchievementRules(packages map[string]*models.Package) []AchievementRule {
+	passed := []string{EventSubmissionPassed, EventPackageChallengePassed}
+
+	rules := []AchievementRule{
+		{
+			Badge:  models.Badge{ID: "first-pass", Name: "First Pass", Description: "Passed a challenge", Icon: "🎯", Color: "97ca00"},
+			Events: passed,
+			Earned: func(facts AchievementFacts) bool {
+				if len(facts.CoreCompleted) > 0 {
+					return true
+				}
+				for _, completed := range facts.PackageCompleted {
+					if len(completed) > 0 {
+						return true
+					}
+				}
+				return false
+			},
+		},
+	}
+
+	// The completion ladder, lowest first; the starting level needs no badge
+	for i := len(completionLevels) - 1; i >= 0; i-- {
+		level := completionLevels[i]
+		if level.BadgeID == "" {
+			continue
+		}
+		icon, name, _ := strings.Cut(level.Title, " ")
+		rules = append(rules, AchievementRule{
+			Badge: models.Badge{
+				ID:          level.BadgeID,
+				Name:        name,
+				Description: fmt.Sprintf("Completed %d core challenges", level.Completed),
+				Icon:        icon,
+				Color:       "007ec6",
+			},
+			Events: []string{EventSubmissionPassed},
+			Earned: func(facts AchievementFacts) bool {
+				return len(facts.CoreCompleted) >= level.Completed
+			},
+		})
+	}
+
+	for _, difficulty := range []struct {
+		Name, Icon, Color string
+	}{
+		{"Beginner", "🟢", "4c1"},
+		{"Intermediate", "🟡", "dfb317"},
+		{"Advanced", "🔴", "e05d44"},
+	} {
+		difficulty := difficulty
+		rules = append(rules, AchievementRule{
+			Badge: models.Badge{
+				ID:          "all-" + strings.ToLower(difficulty.Name),
+				Name:        difficulty.Name + " Sweep",
+				Description: fmt.Sprintf("Completed every %s core challenge", difficulty.Name),
+				Icon:        difficulty.Icon,
+				Color:       difficulty.Color,
+			},
+			Events: []string{EventSubmissionPassed},
+			Earned: func(facts AchievementFacts) bool {
+				completed, total := facts.completedByDifficulty(difficulty.Name)
+				return total > 0 && completed == total
+			},
+		})
+	}
+
+	for _, streak := range []struct {
+		Days     int
+		ID, Name string
+		Icon     string
+	}{
+		{3, "streak-3", "On a Roll", "📅"},
+		{7, "streak-7", "Week Streak", "🗓️"},
+	} {
+		streak := streak
+		rules = append(rules, AchievementRule{
+			Badge: models.Badge{
+				ID:          streak.ID,
+				Name:        streak.Name,
+				Description: fmt.Sprintf("Passed challenges on %d days in a row", streak.Days),
+				Icon:        streak.Icon,
+				Color:       "fe7d37",
+			},
+			Events: passed,
+			Earned: func(facts AchievementFacts) bool {
+				return facts.longestStreak() >= streak.Days
+			},
+		})
+	}
+
+	packageNames := make([]string, 0, len(packages))
+	for name := range packages {
+		packageNames = append(packageNames, name)
+	}
+	sort.Strings(packageNames)
+
+	for _, name := range packageNames {
+		name := name
+		displayName := packages[name].DisplayName
+		if displayName == "" {
+			displayName = name
+		}
+		rules = append(rules, AchievementRule{
+			Badge: models.Badge{
+				ID:          "package-" + name,
+				Name:        displayName + " Track Complete",
+				Description: fmt.Sprintf("Finished the %s learning path", displayName),
+				Icon:        "🏆",
+				Color:       "a855f7",
+				PackageName: name,
+			},
+			Events: []string{EventPackageChallengePassed},
+			Earned: func(facts AchievementFacts) bool {
+				path := facts.PackagePaths[name]
+				if len(path) == 0 {
+					return false
+				}
+				for _, challengeID := range path {
+					if !facts.PackageCompleted[name][challengeID] {
+						return false
+					}
+				}
+				return true
+			},
+		})
+	}
+
+	return rules
+}
+
+// Badges returns every badge the engine can award
+func (e *AchievementEngine) Badges() []models.Badge {
+	badges := make([]models.Badge, len(e.rules))
+	for i, rule := range e.rules {
+		badges[i] = rule.Badge
+	}
+	return badges
+}
+
+// Evaluate checks the rules an event triggers against a user's progress and awards the badges
+// they earn. It returns the newly awarded badges.
+func (e *AchievementEngine) Evaluate(event AchievementEvent, facts AchievementFacts) ([]models.EarnedBadge, error) {
+	owned, err := e.store.List(event.Username)
+	if err != nil {
+		return nil, err
+	}
+	has := make(map[string]bool, len(owned))
+	for _, badge := range owned {
+		has[badge.BadgeID] = true
+	}
+
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	awarded := []models.EarnedBadge{}
+	for _, rule := range e.rules {
+		if has[rule.Badge.ID] || !rule.triggeredBy(event.Type) || !rule.Earned(facts) {
+			continue
+		}
+
+		isNew, err := e.store.Award(models.AwardedBadge{Username: event.Username, BadgeID: rule.Badge.ID, AwardedAt: event.At.UTC()})
+		if err != nil {
+			return awarded, fmt.Errorf("failed to award %s: %v", rule.Badge.ID, err)
+		}
+		if isNew {
+			awarded = append(awarded, models.EarnedBadge{Badge: rule.Badge, AwardedAt: event.At.UTC()})
+		}
+	}
+	return awarded, nil
+}
+
+// Empty reports whether the engine has awarded no badges yet
+func (e *AchievementEngine) Empty() (bool, error) {
+	return e.store.Empty()
+}
+
+// EarnedBadges returns the badges a user has earned, oldest first. Badges whose rule no longer
+// exists, such as for a removed package, are left out.
+func (e *AchievementEngine) EarnedBadges(username string) ([]models.EarnedBadge, error) {
+	owned, err := e.store.List(username)
+	if err != nil {
+		return nil, err
+	}
+
+	badges := make(map[string]models.Badge, len(e.rules))
+	for _, rule := range e.rules {
+		badges[rule.Badge.ID] = rule.Badge
+	}
+
+	earned := []models.EarnedBadge{}
+	for _, award := range owned {
+		if badge, exists := badges[award.BadgeID]; exists {
+			earned = append(earned, models.EarnedBadge{Badge: badge, AwardedAt: award.AwardedAt})
+		}
+	}
+	return earned, nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+func TestLongestStreak(t *testing.T) {
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2025, month, day, 12, 0, 0, 0, time.UTC)
+	}
+	eastern := time.FixedZone("EST", -5*60*60)
+
+	tests := []struct {
+		name  string
+		dates []time.Time
+		want  int
+	}{
+		{"no passes", nil, 0},
+		{"undated passes", []time.Time{{}, {}}, 0},
+		{"several passes on one day", []time.Time{day(3, 10), day(3, 10).Add(time.Hour)}, 1},
+		{"consecutive days in any order", []time.Time{day(3, 12), day(3, 10), day(3, 11)}, 3},
+		{"longest of several runs", []time.Time{day(3, 1), day(3, 2), day(3, 4), day(3, 5), day(3, 6), day(3, 8)}, 3},
+		{"across the end of a month", []time.Time{day(2, 27), day(2, 28), day(3, 1)}, 3},
+		{"days are counted in UTC", []time.Time{day(3, 10), time.Date(2025, 3, 10, 23, 30, 0, 0, eastern), day(3, 12)}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			facts := AchievementFacts{PassDates: tt.dates}
+			if got := facts.longestStreak(); got != tt.want {
+				t.Errorf("longestStreak() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAchievementEngineEvaluate(t *testing.T) {
+	packages := map[string]*models.Package{"gin": {Name: "gin", DisplayName: "Gin"}}
+	difficulties := map[int]string{1: "Beginner", 2: "Beginner", 3: "Beginner", 4: "Beginner", 5: "Beginner", 6: "Advanced"}
+	fiveBeginners := map[int]bool{1: true, 2: true, 3: true, 4: true, 5: true}
+	streak := []time.Time{
+		time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 11, 9, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC),
+	}
+	ginPath := map[string][]string{"gin": {"challenge-1", "challenge-2"}}
+
+	tests := []struct {
+		name  string
+		event string
+		facts AchievementFacts
+		want  []string // Badge IDs awarded, in rule order
+	}{
+		{
+			name:  "nothing passed",
+			event: EventSubmissionPassed,
+			facts: AchievementFacts{CoreDifficulties: difficulties},
+			want:  []string{},
+		},
+		{
+			name:  "first core pass",
+			event: EventSubmissionPassed,
+			facts: AchievementFacts{CoreDifficulties: difficulties, CoreCompleted: map[int]bool{1: true}, PassDates: streak[:1]},
+			want:  []string{"first-pass"},
+		},
+		{
+			name:  "level and difficulty sweep",
+			event: EventSubmissionPassed,
+			facts: AchievementFacts{CoreDifficulties: difficulties, CoreCompleted: fiveBeginners},
+			want:  []string{"first-pass", "level-intermediate", "all-beginner"},
+		},
+		{
+			name:  "package pass checks only its rules",
+			event: EventPackageChallengePassed,
+			facts: AchievementFacts{
+				CoreDifficulties: difficulties,
+				CoreCompleted:    fiveBeginners,
+				PackagePaths:     ginPath,
+				PackageCompleted: map[string]map[string]bool{"gin": {"challenge-1": true, "challenge-2": true}},
+				PassDates:        streak,
+			},
+			want: []string{"first-pass", "streak-3", "package-gin"},
+		},
+		{
+			name:  "unfinished learning path",
+			event: EventPackageChallengePassed,
+			facts: AchievementFacts{
+				PackagePaths:     ginPath,
+				PackageCompleted: map[string]map[string]bool{"gin": {"challenge-1": true}},
+			},
+			want: []string{"first-pass"},
+		},
+		{
+			name:  "sync checks every rule",
+			event: EventSync,
+			facts: AchievementFacts{
+				CoreDifficulties: difficulties,
+				CoreCompleted:    fiveBeginners,
+				PackagePaths:     ginPath,
+				PackageCompleted: map[string]map[string]bool{"gin": {"challenge-1": true, "challenge-2": true}},
+				PassDates:        streak,
+			},
+			want: []string{"first-pass", "level-intermediate", "all-beginner", "streak-3", "package-gin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewJSONLAchievementStore(filepath.Join(t.TempDir(), "achievements.jsonl"))
+			if err != nil {
+				t.Fatalf("NewJSONLAchievementStore() error = %v", err)
+			}
+			defer store.Close()
+			engine := NewAchievementEngine(store, packages)
+
+			event := AchievementEvent{Type: tt.event, Username: "bob", At: streak[2]}
+			awarded, err := engine.Evaluate(event, tt.facts)
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			got := []string{}
+			for _, badge := range awarded {
+				got = append(got, badge.ID)
+				if !badge.AwardedAt.Equal(event.At) {
+					t.Errorf("%s awarded at %v, want %v", badge.ID, badge.AwardedAt, event.At)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() awarded %v, want %v", got, tt.want)
+			}
+
+			// Badges are awarded once
+			if again, err := engine.Evaluate(event, tt.facts); err != nil || len(again) != 0 {
+				t.Errorf("second Evaluate() = %v, %v, want nothing new", again, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package services
+
+import "web-ui/internal/models"
+
+// AchievementStore persists the badges users have earned. Implementations are safe for concurrent use.
+type AchievementStore interface {
+	// Award records a badge, reporting false if the user already had it
+	Award(badge models.AwardedBadge) (bool, error)
+	// List returns a user's badges, oldest first
+	List(username string) ([]models.AwardedBadge, error)
+	// Empty reports whether no badge has been awarded to anyone
+	Empty() (bool, error)
+	// Close releases the underlying storage
+	Close() error
+}
+
+// NewAchievementStoreFromEnv opens an achievement store with the backend selected by SUBMISSION_STORE,
+// in the submission store's database. If the SQLite database cannot be opened, badges fall back to a
+// JSON lines file.
+func NewAchievementStoreFromEnv() (AchievementStore, error) {
+	return openStoreFromEnv("Achievement",
+		func(path string) (AchievementStore, error) { return NewSQLiteAchievementStore(path) },
+		"achievements.jsonl",
+		func(path string) (AchievementStore, error) { return NewJSONLAchievementStore(path) },
+	)
+}
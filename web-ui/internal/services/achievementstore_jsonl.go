@@ -0,0 +1,110 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"web-ui/internal/models"
+)
+
+// JSONLAchievementStore appends awarded badges to a JSON lines file and keeps them in memory
+type JSONLAchievementStore struct {
+	mutex  sync.RWMutex
+	file   *os.File
+	badges map[string][]models.AwardedBadge // Username -> badges in the order they were awarded
+}
+
+// NewJSONLAchievementStore opens or creates the file at path and loads the badges it holds
+func NewJSONLAchievementStore(path string) (*JSONLAchievementStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &JSONLAchievementStore{file: file, badges: make(map[string][]models.AwardedBadge)}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var badge models.AwardedBadge
+		if err := json.Unmarshal(scanner.Bytes(), &badge); err != nil {
+			// A crash mid-write leaves at most a partial last line; skip it rather than refuse to start
+			continue
+		}
+		if !store.has(badge.Username, badge.BadgeID) {
+			store.badges[badge.Username] = append(store.badges[badge.Username], badge)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	return store, nil
+}
+
+// has reports whether a user has a badge. The caller holds the mutex.
+func (s *JSONLAchievementStore) has(username, badgeID string) bool {
+	for _, badge := range s.badges[username] {
+		if badge.BadgeID == badgeID {
+			return true
+		}
+	}
+	return false
+}
+
+// Award appends a badge, reporting false if the user already had it
+func (s *JSONLAchievementStore) Award(badge models.AwardedBadge) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.has(badge.Username, badge.BadgeID) {
+		return false, nil
+	}
+
+	data, err := json.Marshal(badge)
+	if err != nil {
+		return false, err
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return false, err
+	}
+
+	s.badges[badge.Username] = append(s.badges[badge.Username], badge)
+	return true, nil
+}
+
+// List returns a user's badges, oldest first
+func (s *JSONLAchievementStore) List(username string) ([]models.AwardedBadge, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	badges := append([]models.AwardedBadge{}, s.badges[username]...)
+	sort.SliceStable(badges, func(i, j int) bool {
+		return badges[i].AwardedAt.Before(badges[j].AwardedAt)
+	})
+	return badges, nil
+}
+
+// Empty reports whether no badge has been awarded to anyone
+func (s *JSONLAchievementStore) Empty() (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.badges) == 0, nil
+}
+
+// Close closes the file
+func (s *JSONLAchievementStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.file.Close()
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"web-ui/internal/models"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteAchievementStore keeps awarded badges in a SQLite database
+type SQLiteAchievementStore struct {
+	db *sql.DB
+}
+
+// sqliteAchievementSchema creates the achievements table; a user earns each badge once
+const sqliteAchievementSchema = `
+CREATE TABLE IF NOT EXISTS achievements (
+	username   TEXT    NOT NULL,
+	badge_id   TEXT    NOT NULL,
+	awarded_at INTEGER NOT NULL,
+	PRIMARY KEY (username, badge_id)
+);
+`
+
+// NewSQLiteAchievementStore opens or creates its table in the database at path
+func NewSQLiteAchievementStore(path string) (*SQLiteAchievementStore, error) {
+	db, err := openSQLiteDatabase(path, sqliteAchievementSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteAchievementStore{db: db}, nil
+}
+
+// Award records a badge, reporting false if the user already had it
+func (s *SQLiteAchievementStore) Award(badge models.AwardedBadge) (bool, error) {
+	result, err := s.db.Exec(
+		"INSERT OR IGNORE INTO achievements (username, badge_id, awarded_at) VALUES (?, ?, ?)",
+		badge.Username, badge.BadgeID, badge.AwardedAt.UnixNano(),
+	)
+	if err != nil {
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted > 0, nil
+}
+
+// List returns a user's badges, oldest first
+func (s *SQLiteAchievementStore) List(username string) ([]models.AwardedBadge, error) {
+	rows, err := s.db.Query(
+		"SELECT badge_id, awarded_at FROM achievements WHERE username = ? ORDER BY awarded_at, badge_id",
+		username,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	badges := []models.AwardedBadge{}
+	for rows.Next() {
+		badge := models.AwardedBadge{Username: username}
+		var awardedAt int64
+		if err := rows.Scan(&badge.BadgeID, &awardedAt); err != nil {
+			return nil, err
+		}
+		badge.AwardedAt = time.Unix(0, awardedAt).UTC()
+		badges = append(badges, badge)
+	}
+
+	return badges, rows.Err()
+}
+
+// Empty reports whether no badge has been awarded to anyone
+func (s *SQLiteAchievementStore) Empty() (bool, error) {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS (SELECT 1 FROM achievements)").Scan(&exists); err != nil {
+		return false, err
+	}
+	return !exists, nil
+}
+
+// Close closes the database
+func (s *SQLiteAchievementStore) Close() error {
+	return s.db.Close()
+}
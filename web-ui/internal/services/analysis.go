@@ -0,0 +1,214 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"web-ui/internal/models"
+)
+
+// Analysis modes a run can enable in addition to the tests
+const (
+	AnalysisRace        = "race"        // Run the tests with the race detector
+	AnalysisVet         = "vet"         // Run go vet with its full set of analyzers
+	AnalysisStaticcheck = "staticcheck" // Run staticcheck, if it is installed on the server
+)
+
+// RunOptions selects optional analyses, benchmarks and coverage for a run
+type RunOptions struct {
+	Analyses  []string          `json:"analyses,omitempty"`
+	Benchmark *BenchmarkOptions `json:"benchmark,omitempty"` // Run the challenge's benchmarks after its tests
+	Coverage  bool              `json:"coverage,omitempty"`  // Report which lines of the submission the tests reach
+
+	warm bool // Warm the challenge's build cache with its template instead, see WarmBuildCaches
+}
+
+// Validate rejects unknown analysis modes and out of range benchmark counts
+func (o RunOptions) Validate() error {
+	for _, analysis := range o.Analyses {
+		switch analysis {
+		case AnalysisRace, AnalysisVet, AnalysisStaticcheck:
+		default:
+			return fmt.Errorf("unknown analysis mode %q", analysis)
+		}
+	}
+	if o.Benchmark != nil {
+		return o.Benchmark.validate()
+	}
+	return nil
+}
+
+// Has reports whether an analysis mode is enabled
+func (o RunOptions) Has(analysis string) bool {
+	for _, enabled := range o.Analyses {
+		if enabled == analysis {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheKey describes the options for the result cache, independent of their order
+func (o RunOptions) cacheKey() string {
+	analyses := append([]string{}, o.Analyses...)
+	sort.Strings(analyses)
+	key := strings.Join(analyses, ",")
+	if o.Benchmark != nil {
+		key += fmt.Sprintf(";bench=%d", o.Benchmark.count())
+	}
+	if o.Coverage {
+		key += ";cover"
+	}
+	return key
+}
+
+// runAnalyses runs go vet and staticcheck as requested and returns their findings.
+// The race detector runs as part of the tests, so its reports are parsed from the test output.
+func (es *ExecutionService) runAnalyses(ctx context.Context, tempDir string, challenge *models.Challenge, options RunOptions) []models.Diagnostic {
+	var diagnostics []models.Diagnostic
+
+	if options.Has(AnalysisVet) {
+		output := es.runAnalysisTool(ctx, tempDir, challenge, "go", "vet", "-trimpath", ".")
+		diagnostics = append(diagnostics, parseToolDiagnostics(AnalysisVet, output)...)
+	}
+
+	if options.Has(AnalysisStaticcheck) {
+		staticcheck, err := exec.LookPath("staticcheck")
+		if err != nil {
+			diagnostics = append(diagnostics, models.Diagnostic{
+				Tool:    AnalysisStaticcheck,
+				Message: "staticcheck is not installed on this server",
+			})
+		} else {
+			output := es.runAnalysisTool(ctx, tempDir, challenge, staticcheck, ".")
+			diagnostics = append(diagnostics, parseToolDiagnostics(AnalysisStaticcheck, output)...)
+		}
+	}
+
+	return diagnostics
+}
+
+// runAnalysisTool runs an analyzer in the sandbox with the challenge's time budget and returns its output.
+// Analyzers exit non-zero when they report findings, so the exit status is ignored.
+func (es *ExecutionService) runAnalysisTool(ctx context.Context, tempDir string, challenge *models.Challenge, name string, args ...string) string {
+	toolCtx, cancel := context.WithTimeout(ctx, es.timeoutFor(challenge))
+	defer cancel()
+
+	job := es.sandboxJob(tempDir, challenge)
+	if filepath.IsAbs(name) {
+		// Tools found on the server's PATH, such as staticcheck, are not part of the toolchain
+		job.ReadOnly = append(job.ReadOnly, name)
+	}
+
+	cmd := es.sandbox.Command(toolCtx, job, name, args...)
+	cmd.Env = append(cmd.Env, es.modules.RunEnv()...)
+
+	output, _ := cmd.CombinedOutput()
+	return string(output)
+}
+
+// diagnosticPattern matches "file.go:line:col: message" as printed by the compiler, go vet and staticcheck
+var diagnosticPattern = regexp.MustCompile(`^(?:vet: )?(\S+\.go):(\d+)(?::(\d+))?: (.+)$`)
+
+// staticcheckCodePattern matches the check identifier staticcheck appends to its messages
+var staticcheckCodePattern = regexp.MustCompile(`^(.*) \(([A-Z]+[0-9]+)\)$`)
+
+// parseToolDiagnostics extracts file and line findings from analyzer output
+func parseToolDiagnostics(tool, output string) []models.Diagnostic {
+	var diagnostics []models.Diagnostic
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		match := diagnosticPattern.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+
+		line, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		diagnostic := models.Diagnostic{
+			Tool:    tool,
+			File:    path.Base(match[1]),
+			Line:    line,
+			Column:  column,
+			Message: match[4],
+		}
+		if code := staticcheckCodePattern.FindStringSubmatch(diagnostic.Message); code != nil {
+			diagnostic.Message = code[1]
+			diagnostic.Code = code[2]
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	return diagnostics
+}
+
+// raceFramePattern matches a stack frame location such as "challenge1/solution-template.go:12 +0x33"
+var raceFramePattern = regexp.MustCompile(`^(\S+\.go):(\d+)`)
+
+// parseRaceReports turns each "WARNING: DATA RACE" report in the test output into a diagnostic,
+// located at the first frame inside the submission or its tests
+func parseRaceReports(output string) []models.Diagnostic {
+	var diagnostics []models.Diagnostic
+	var report []string
+	inReport := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, "WARNING: DATA RACE"):
+			inReport = true
+			report = []string{line}
+		case inReport && strings.HasPrefix(line, "=================="):
+			diagnostics = append(diagnostics, raceDiagnostic(report))
+			inReport = false
+		case inReport:
+			report = append(report, line)
+		}
+	}
+
+	return diagnostics
+}
+
+// raceDiagnostic summarizes a single race report
+func raceDiagnostic(report []string) models.Diagnostic {
+	diagnostic := models.Diagnostic{
+		Tool:    AnalysisRace,
+		Message: "data race",
+		Details: strings.Join(report, "\n"),
+	}
+
+	for i, line := range report {
+		match := raceFramePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil || !isSubmissionFile(match[1]) {
+			continue
+		}
+
+		diagnostic.File = path.Base(match[1])
+		diagnostic.Line, _ = strconv.Atoi(match[2])
+
+		// The line above a location names its function
+		if i > 0 {
+			diagnostic.Message = "data race in " + strings.TrimSuffix(strings.TrimSpace(report[i-1]), "()")
+		}
+		break
+	}
+
+	return diagnostic
+}
+
+// isSubmissionFile reports whether a source path is one of the files written for a run
+func isSubmissionFile(file string) bool {
+	base := path.Base(file)
+	return base == "solution-template.go" || base == "solution_test.go"
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Identity providers
+const (
+	AuthProviderGitHub = "github"
+	AuthProviderLocal  = "local"
+)
+
+// Cookies, header and form field carrying sessions and CSRF tokens
+const (
+	SessionCookieName   = "session"
+	CSRFCookieName      = "csrf_token"
+	CSRFHeaderName      = "X-CSRF-Token"
+	CSRFFormField       = "csrf_token"
+	authStateCookieName = "auth_state"
+)
+
+const (
+	defaultSessionTTLHours = 30 * 24
+	authStateTTL           = 10 * time.Minute
+	csrfTokenTTL           = 365 * 24 * time.Hour
+)
+
+// githubUsernamePattern matches a GitHub username: letters, digits and single dashes between them, at most 39 characters
+var githubUsernamePattern = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9]|-[a-zA-Z0-9]){0,38}$`)
+
+// ValidGitHubUsername reports whether a name could be a GitHub username
+func ValidGitHubUsername(username string) bool {
+	return githubUsernamePattern.MatchString(username)
+}
+
+// Session is a signed-in user, kept in a signed cookie
+type Session struct {
+	Username string    `json:"username"`
+	Provider string    `json:"provider"`
+	Expires  time.Time `json:"expires"`
+}
+
+// AuthState carries a sign-in through the redirect to a provider and back
+type AuthState struct {
+	State    string    `json:"state"`
+	Provider string    `json:"provider"`
+	Next     string    `json:"next"` // Local path to return to
+	Expires  time.Time `json:"expires"`
+}
+
+// Identity is the user a provider vouches for
+type Identity struct {
+	Username string
+}
+
+// AuthProvider signs users in with the OAuth 2.0 authorization code flow
+type AuthProvider interface {
+	// Name identifies the provider in URLs, e.g. "github"
+	Name() string
+	// DisplayName is shown on sign-in buttons
+	DisplayName() string
+	// AuthCodeURL is where the browser goes to sign in; the provider redirects back to redirectURI with a code and the state
+	AuthCodeURL(state, redirectURI string) string
+	// Exchange redeems a code for the identity that signed in
+	Exchange(ctx context.Context, code, redirectURI string) (Identity, error)
+}
+
+// AuthService issues signed session cookies, guards against cross-site request forgery and
+// holds the identity providers users sign in with and the roles they are granted
+type AuthService struct {
+	secret    []byte
+	ttl       time.Duration
+	secure    bool
+	baseURL   string
+	providers []AuthProvider
+	roles     *RoleAssignments
+}
+
+// NewAuthServiceFromEnv configures authentication from the environment:
+//   - SESSION_SECRET signs cookies; without it a random key is kept in $DATA_DIR/session.key
+//   - SESSION_TTL_HOURS is how long a sign-in lasts
+//   - AUTH_COOKIE_SECURE=true marks cookies HTTPS-only
+//   - AUTH_BASE_URL is the public URL providers redirect back to, by default the request's host
+//   - GITHUB_CLIENT_ID and GITHUB_CLIENT_SECRET enable GitHub sign-in
+//   - AUTH_LOCAL_LOGIN=true enables the local stand-in provider for testing; it cannot be combined with GitHub
+//   - ADMIN_USERS and COACH_USERS grant roles, see NewRoleAssignmentsFromEnv
+func NewAuthServiceFromEnv() (*AuthService, error) {
+	secret, err := loadSessionSecret(filepath.Join(dataDirFromEnv(), "session.key"))
+	if err != nil {
+		return nil, err
+	}
+
+	service := &AuthService{
+		secret:  secret,
+		ttl:     time.Duration(getIntFromEnv("SESSION_TTL_HOURS", defaultSessionTTLHours)) * time.Hour,
+		secure:  os.Getenv("AUTH_COOKIE_SECURE") == "true",
+		baseURL: strings.TrimRight(os.Getenv("AUTH_BASE_URL"), "/"),
+		roles:   NewRoleAssignmentsFromEnv(),
+	}
+
+	clientID, clientSecret := os.Getenv("GITHUB_CLIENT_ID"), os.Getenv("GITHUB_CLIENT_SECRET")
+	if clientID != "" && clientSecret != "" {
+		service.providers = append(service.providers, NewGitHubAuthProvider(clientID, clientSecret))
+	}
+
+	if os.Getenv("AUTH_LOCAL_LOGIN") == "true" {
+		// Anyone could sign in as any GitHub user next to GitHub sign-in
+		if len(service.providers) > 0 {
+			return nil, fmt.Errorf("AUTH_LOCAL_LOGIN=true cannot be combined with GitHub sign-in")
+		}
+		service.providers = append(service.providers, NewLocalAuthProvider())
+		log.Printf("WARNING: AUTH_LOCAL_LOGIN=true: local sign-in is enabled and signs in ANY username entered, without a password.")
+		log.Printf("WARNING: Only use it for testing or offline, never where identities matter; set GITHUB_CLIENT_ID and GITHUB_CLIENT_SECRET for GitHub sign-in instead.")
+		if service.roles.Granted() {
+			log.Printf("Warning: ADMIN_USERS and COACH_USERS only apply to GitHub sign-ins, so local sign-ins are learners")
+		}
+	}
+	if len(service.providers) == 0 {
+		log.Printf("Warning: No sign-in provider is configured, so no one can sign in; set GITHUB_CLIENT_ID and GITHUB_CLIENT_SECRET, or AUTH_LOCAL_LOGIN=true for testing")
+	}
+
+	return service, nil
+}
+
+// loadSessionSecret returns SESSION_SECRET, or the key stored at path, creating it if needed
+func loadSessionSecret(path string) ([]byte, error) {
+	if secret := os.Getenv("SESSION_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		if secret, err := hex.DecodeString(strings.TrimSpace(string(data))); err == nil && len(secret) >= 32 {
+			return secret, nil
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(secret)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write session key %s: %v", path, err)
+	}
+	return secret, nil
+}
+
+// Providers returns the enabled identity providers
+func (s *AuthService) Providers() []AuthProvider {
+	return s.providers
+}
+
+// Provider returns an enabled identity provider by name
+func (s *AuthService) Provider(name string) (AuthProvider, bool) {
+	for _, provider := range s.providers {
+		if provider.Name() == name {
+			return provider, true
+		}
+	}
+	return nil, false
+}
+
+// RedirectURI is where a provider sends the browser back to after sign-in
+func (s *AuthService) RedirectURI(r *http.Request, provider string) string {
+	base := s.baseURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + "/auth/" + provider + "/callback"
+}
+
+// StartSession signs a user in
+func (s *AuthService) StartSession(w http.ResponseWriter, identity Identity, provider string) {
+	session := Session{
+		Username: identity.Username,
+		Provider: provider,
+		Expires:  time.Now().Add(s.ttl).UTC(),
+	}
+	s.setSigned(w, SessionCookieName, session, session.Expires, true)
+}
+
+// Session returns the signed-in user of a request
+func (s *AuthService) Session(r *http.Request) (Session, bool) {
+	var session Session
+	if !s.readSigned(r, SessionCookieName, &session) || time.Now().After(session.Expires) {
+		return Session{}, false
+	}
+	return session, true
+}
+
+// Username returns the signed-in user of a request, or "" if there is none
+func (s *AuthService) Username(r *http.Request) string {
+	session, _ := s.Session(r)
+	return session.Username
+}
+
+// Role returns the signed-in user of a request and their role, or "" for both if there is none.
+// Roles are only granted to GitHub sign-ins, since local sign-in trusts whatever name is entered.
+func (s *AuthService) Role(r *http.Request) (string, Role) {
+	session, ok := s.Session(r)
+	if !ok {
+		return "", ""
+	}
+	if session.Provider != AuthProviderGitHub {
+		return session.Username, RoleLearner
+	}
+	return session.Username, s.roles.Role(session.Username)
+}
+
+// EndSession signs the user out
+func (s *AuthService) EndSession(w http.ResponseWriter) {
+	s.clearCookie(w, SessionCookieName)
+}
+
+// BeginSignIn starts a sign-in with a provider, returning the state to send it
+func (s *AuthService) BeginSignIn(w http.ResponseWriter, provider, next string) (string, error) {
+	state, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	authState := AuthState{
+		State:    state,
+		Provider: provider,
+		Next:     next,
+		Expires:  time.Now().Add(authStateTTL).UTC(),
+	}
+	s.setSigned(w, authStateCookieName, authState, authState.Expires, true)
+	return state, nil
+}
+
+// FinishSignIn checks that a provider returned the state of the sign-in this browser started,
+// returning that sign-in. The state can only be used once.
+func (s *AuthService) FinishSignIn(w http.ResponseWriter, r *http.Request, provider, state string) (AuthState, bool) {
+	var authState AuthState
+	ok := s.readSigned(r, authStateCookieName, &authState)
+	s.clearCookie(w, authStateCookieName)
+	if !ok || time.Now().After(authState.Expires) || authState.Provider != provider ||
+		subtle.ConstantTimeCompare([]byte(authState.State), []byte(state)) != 1 {
+		return AuthState{}, false
+	}
+	return authState, true
+}
+
+// CSRFToken returns the request's CSRF token, setting a new token cookie if it has no valid one.
+// Pages send the token back in the X-CSRF-Token header or the csrf_token form field.
+func (s *AuthService) CSRFToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(CSRFCookieName); err == nil && s.validCSRFToken(cookie.Value) {
+		return cookie.Value
+	}
+
+	nonce, err := randomToken()
+	if err != nil {
+		log.Printf("Error creating CSRF token: %v", err)
+		return ""
+	}
+	token := nonce + "." + s.mac(CSRFCookieName, nonce)
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(csrfTokenTTL),
+		HttpOnly: false, // Scripts copy it into the header
+		Secure:   s.secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	// Later handlers of this request see the new token
+	r.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: token})
+	return token
+}
+
+// CheckCSRF reports whether a request carries the CSRF token of its cookie. Cross-site pages
+// can make the browser send the cookie but cannot read it to copy it into the request.
+func (s *AuthService) CheckCSRF(r *http.Request) bool {
+	cookie, err := r.Cookie(CSRFCookieName)
+	if err != nil || !s.validCSRFToken(cookie.Value) {
+		return false
+	}
+
+	sent := r.Header.Get(CSRFHeaderName)
+	if sent == "" {
+		sent = r.PostFormValue(CSRFFormField)
+	}
+	return subtle.ConstantTimeCompare([]byte(sent), []byte(cookie.Value)) == 1
+}
+
+// validCSRFToken reports whether this server issued a CSRF token
+func (s *AuthService) validCSRFToken(token string) bool {
+	nonce, mac, found := strings.Cut(token, ".")
+	return found && hmac.Equal([]byte(mac), []byte(s.mac(CSRFCookieName, nonce)))
+}
+
+// setSigned sets a cookie holding a value as JSON, signed so it cannot be forged or moved to another cookie
+func (s *AuthService) setSigned(w http.ResponseWriter, name string, value interface{}, expires time.Time, httpOnly bool) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Error encoding %s cookie: %v", name, err)
+		return
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    payload + "." + s.mac(name, payload),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: httpOnly,
+		Secure:   s.secure,
+		SameSite: http.SameSiteLaxMode, // Sent on the redirect back from a provider
+	})
+}
+
+// readSigned decodes a signed cookie into value, reporting false if it is missing or was tampered with
+func (s *AuthService) readSigned(r *http.Request, name string, value interface{}) bool {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return false
+	}
+	payload, mac, found := strings.Cut(cookie.Value, ".")
+	if !found || !hmac.Equal([]byte(mac), []byte(s.mac(name, payload))) {
+		return false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, value) == nil
+}
+
+// clearCookie deletes a cookie
+func (s *AuthService) clearCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.secure,
+	})
+}
+
+// mac signs a cookie's value, bound to the cookie's name
+func (s *AuthService) mac(name, value string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// randomToken returns 32 random bytes, URL-safe encoded
+func randomToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
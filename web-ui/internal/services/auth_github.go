@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GitHub OAuth endpoints
+const (
+	githubAuthorizeURL = "https://github.com/login/oauth/authorize"
+	githubTokenURL     = "https://github.com/login/oauth/access_token"
+	githubUserURL      = "https://api.github.com/user"
+)
+
+// GitHubAuthProvider signs users in with a GitHub OAuth app, using the web flow
+type GitHubAuthProvider struct {
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+}
+
+// NewGitHubAuthProvider creates a provider for an OAuth app. The app's callback URL must be
+// the server's /auth/github/callback.
+func NewGitHubAuthProvider(clientID, clientSecret string) *GitHubAuthProvider {
+	return &GitHubAuthProvider{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name identifies the provider in URLs
+func (p *GitHubAuthProvider) Name() string {
+	return AuthProviderGitHub
+}
+
+// DisplayName is shown on sign-in buttons
+func (p *GitHubAuthProvider) DisplayName() string {
+	return "GitHub"
+}
+
+// AuthCodeURL is GitHub's authorization page. No scopes are requested; the username is public.
+func (p *GitHubAuthProvider) AuthCodeURL(state, redirectURI string) string {
+	params := url.Values{
+		"client_id":    {p.clientID},
+		"redirect_uri": {redirectURI},
+		"state":        {state},
+		"allow_signup": {"true"},
+	}
+	return githubAuthorizeURL + "?" + params.Encode()
+}
+
+// Exchange redeems a code for an access token and looks up whose it is
+func (p *GitHubAuthProvider) Exchange(ctx context.Context, code, redirectURI string) (Identity, error) {
+	form := url.Values{
+		"client_id":     {p.clientID},
+		"client_secret": {p.clientSecret},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", githubTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Identity{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := p.doJSON(req, &token); err != nil {
+		return Identity{}, fmt.Errorf("failed to exchange code: %v", err)
+	}
+	if token.Error != "" {
+		return Identity{}, fmt.Errorf("GitHub rejected the code: %s %s", token.Error, token.ErrorDescription)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "GET", githubUserURL, nil)
+	if err != nil {
+		return Identity{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := p.doJSON(req, &user); err != nil {
+		return Identity{}, fmt.Errorf("failed to look up GitHub user: %v", err)
+	}
+	if !ValidGitHubUsername(user.Login) {
+		return Identity{}, fmt.Errorf("GitHub returned an invalid username %q", user.Login)
+	}
+	return Identity{Username: user.Login}, nil
+}
+
+// doJSON sends a request and decodes its JSON response
+func (p *GitHubAuthProvider) doJSON(req *http.Request, value interface{}) error {
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(value)
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// localAuthCodeTTL is how long a local authorization code can be redeemed
+const localAuthCodeTTL = time.Minute
+
+// LocalAuthProvider stands in for an OpenID Connect provider when testing or running offline.
+// It follows the same authorization code flow as GitHub, but its authorization page signs in
+// whatever username is entered, so it must not be enabled where identities matter.
+type LocalAuthProvider struct {
+	mutex sync.Mutex
+	codes map[string]localAuthCode
+}
+
+// localAuthCode is an issued authorization code
+type localAuthCode struct {
+	username    string
+	redirectURI string
+	expires     time.Time
+}
+
+// NewLocalAuthProvider creates a local provider
+func NewLocalAuthProvider() *LocalAuthProvider {
+	return &LocalAuthProvider{codes: make(map[string]localAuthCode)}
+}
+
+// Name identifies the provider in URLs
+func (p *LocalAuthProvider) Name() string {
+	return AuthProviderLocal
+}
+
+// DisplayName is shown on sign-in buttons
+func (p *LocalAuthProvider) DisplayName() string {
+	return "Local account"
+}
+
+// AuthCodeURL is the provider's own authorization page on this server
+func (p *LocalAuthProvider) AuthCodeURL(state, redirectURI string) string {
+	params := url.Values{
+		"state":        {state},
+		"redirect_uri": {redirectURI},
+	}
+	return "/auth/local/authorize?" + params.Encode()
+}
+
+// Authorize issues a code for a username, redeemable once at redirectURI
+func (p *LocalAuthProvider) Authorize(username, redirectURI string) (string, error) {
+	if !ValidGitHubUsername(username) {
+		return "", errors.New("invalid username")
+	}
+	code, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	for issued, authCode := range p.codes {
+		if now.After(authCode.expires) {
+			delete(p.codes, issued)
+		}
+	}
+	p.codes[code] = localAuthCode{username: username, redirectURI: redirectURI, expires: now.Add(localAuthCodeTTL)}
+	return code, nil
+}
+
+// Exchange redeems a code issued by Authorize
+func (p *LocalAuthProvider) Exchange(ctx context.Context, code, redirectURI string) (Identity, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	authCode, exists := p.codes[code]
+	delete(p.codes, code)
+	if !exists || time.Now().After(authCode.expires) || authCode.redirectURI != redirectURI {
+		return Identity{}, errors.New("invalid or expired code")
+	}
+	return Identity{Username: authCode.username}, nil
+}
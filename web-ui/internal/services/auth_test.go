@@ -0,0 +1,174 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestAuthService returns an auth service signing with a fixed secret
+func newTestAuthService(secret string) *AuthService {
+	return &AuthService{
+		secret: []byte(secret),
+		ttl:    time.Hour,
+		roles:  &RoleAssignments{roles: make(map[string]Role)},
+	}
+}
+
+// signedCookie returns the cookie setSigned writes for a value
+func signedCookie(t *testing.T, s *AuthService, name string, value interface{}) *http.Cookie {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	s.setSigned(recorder, name, value, time.Now().Add(time.Hour), true)
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("setSigned wrote %d cookies, want 1", len(cookies))
+	}
+	return cookies[0]
+}
+
+func TestSessionSigning(t *testing.T) {
+	s := newTestAuthService("test-secret")
+	session := Session{Username: "octocat", Provider: AuthProviderGitHub, Expires: time.Now().Add(time.Hour).UTC()}
+	valid := signedCookie(t, s, SessionCookieName, session)
+	payload, mac, _ := strings.Cut(valid.Value, ".")
+
+	// The same username signed by another server
+	otherServer := signedCookie(t, newTestAuthService("other-secret"), SessionCookieName, session)
+
+	// A payload naming another user, under the original signature
+	forged, _ := json.Marshal(Session{Username: "admin", Provider: AuthProviderGitHub, Expires: session.Expires})
+	forgedPayload := base64.RawURLEncoding.EncodeToString(forged)
+
+	// A value signed for the sign-in state cookie
+	moved := signedCookie(t, s, authStateCookieName, session)
+
+	expired := signedCookie(t, s, SessionCookieName, Session{Username: "octocat", Provider: AuthProviderGitHub, Expires: time.Now().Add(-time.Minute)})
+
+	tests := []struct {
+		name     string
+		cookie   *http.Cookie
+		wantOK   bool
+		wantUser string
+	}{
+		{"signed session", valid, true, "octocat"},
+		{"no cookie", nil, false, ""},
+		{"empty value", &http.Cookie{Name: SessionCookieName, Value: ""}, false, ""},
+		{"missing signature", &http.Cookie{Name: SessionCookieName, Value: payload}, false, ""},
+		{"forged payload", &http.Cookie{Name: SessionCookieName, Value: forgedPayload + "." + mac}, false, ""},
+		{"truncated signature", &http.Cookie{Name: SessionCookieName, Value: payload + "." + mac[:len(mac)-1]}, false, ""},
+		{"signed with another secret", &http.Cookie{Name: SessionCookieName, Value: otherServer.Value}, false, ""},
+		{"moved from another cookie", &http.Cookie{Name: SessionCookieName, Value: moved.Value}, false, ""},
+		{"expired", &http.Cookie{Name: SessionCookieName, Value: expired.Value}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.cookie != nil {
+				r.AddCookie(&http.Cookie{Name: tt.cookie.Name, Value: tt.cookie.Value})
+			}
+
+			got, ok := s.Session(r)
+			if ok != tt.wantOK || got.Username != tt.wantUser {
+				t.Errorf("Session() = %q, %v; want %q, %v", got.Username, ok, tt.wantUser, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestStartSession(t *testing.T) {
+	s := newTestAuthService("test-secret")
+	recorder := httptest.NewRecorder()
+	s.StartSession(recorder, Identity{Username: "octocat"}, AuthProviderLocal)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, cookie := range recorder.Result().Cookies() {
+		if !cookie.HttpOnly {
+			t.Errorf("cookie %s is readable by scripts", cookie.Name)
+		}
+		r.AddCookie(cookie)
+	}
+
+	session, ok := s.Session(r)
+	if !ok {
+		t.Fatal("Session() did not accept the cookie StartSession set")
+	}
+	if session.Username != "octocat" || session.Provider != AuthProviderLocal {
+		t.Errorf("Session() = %+v, want octocat signed in with %s", session, AuthProviderLocal)
+	}
+	if remaining := time.Until(session.Expires); remaining <= 0 || remaining > s.ttl {
+		t.Errorf("session expires in %v, want within %v", remaining, s.ttl)
+	}
+}
+
+func TestCheckCSRF(t *testing.T) {
+	s := newTestAuthService("test-secret")
+	token := s.CSRFToken(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if token == "" {
+		t.Fatal("CSRFToken() returned no token")
+	}
+	other := s.CSRFToken(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	nonce, _, _ := strings.Cut(token, ".")
+	unsigned := nonce + ".forged"
+	foreign := newTestAuthService("other-secret").CSRFToken(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	tests := []struct {
+		name   string
+		cookie string // "" for no cookie
+		header string
+		form   string
+		want   bool
+	}{
+		{"header matches cookie", token, token, "", true},
+		{"form field matches cookie", token, "", token, true},
+		{"header wins over form field", token, token, "wrong", true},
+		{"nothing sent", token, "", "", false},
+		{"no cookie", "", token, "", false},
+		{"another token sent", token, other, "", false},
+		{"header mismatch ignores matching form field", token, other, token, false},
+		{"cookie not signed by the server", unsigned, unsigned, "", false},
+		{"cookie signed by another server", foreign, foreign, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			if tt.form != "" {
+				form.Set(CSRFFormField, tt.form)
+			}
+			r := httptest.NewRequest("POST", "/api/submissions", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				r.Header.Set(CSRFHeaderName, tt.header)
+			}
+
+			if got := s.CheckCSRF(r); got != tt.want {
+				t.Errorf("CheckCSRF() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFTokenReusesValidCookie(t *testing.T) {
+	s := newTestAuthService("test-secret")
+	token := s.CSRFToken(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: token})
+	recorder := httptest.NewRecorder()
+	if got := s.CSRFToken(recorder, r); got != token {
+		t.Errorf("CSRFToken() = %q, want the cookie's token %q", got, token)
+	}
+	if cookies := recorder.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("CSRFToken() set %d cookies for a request that had a valid token", len(cookies))
+	}
+}
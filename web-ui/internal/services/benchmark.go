@@ -0,0 +1,351 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"web-ui/internal/models"
+)
+
+// BenchmarkOptions enables benchmark mode for a run
+type BenchmarkOptions struct {
+	Count int `json:"count"` // How many times each benchmark runs, averaged in the report
+}
+
+// maxBenchmarkCount bounds the -count of a benchmark run
+const maxBenchmarkCount = 10
+
+// benchmarkTime is the -benchtime of every benchmark, short enough to fit a challenge's time budget
+const benchmarkTime = "200ms"
+
+// referencePrefixes name the deliberately slow reference implementations an Optimized function is compared with
+var referencePrefixes = []string{"Slow", "Inefficient", "Expensive", "HighAllocation"}
+
+// validate checks the benchmark count, where 0 means a single run
+func (b *BenchmarkOptions) validate() error {
+	if b.Count < 0 || b.Count > maxBenchmarkCount {
+		return fmt.Errorf("benchmark count must be between 1 and %d, or 0 for a single run", maxBenchmarkCount)
+	}
+	return nil
+}
+
+// count returns the -count to run with
+func (b *BenchmarkOptions) count() int {
+	if b.Count == 0 {
+		return 1
+	}
+	return b.Count
+}
+
+// runBenchmarks runs every benchmark of the challenge and compares optimized functions with their references.
+// The references are measured on the challenge's own template, since a submission could rewrite them.
+func (es *ExecutionService) runBenchmarks(ctx context.Context, tempDir string, challenge *models.Challenge, options *BenchmarkOptions) *models.BenchmarkReport {
+	benchCtx, cancel := context.WithTimeout(ctx, es.timeoutFor(challenge))
+	defer cancel()
+
+	references := referenceBenchmarks(challenge.TestFile)
+	args := []string{"-bench", "."}
+	if len(references) > 0 {
+		args = append(args, "-skip", references.pattern())
+	}
+	output, err := es.benchmarkCommand(benchCtx, tempDir, challenge, options, args...).CombinedOutput()
+	results := references.filter(parseBenchmarkResults(ParseTestJSON(string(output)).Output), false)
+
+	// The submission shares its output with the benchmark results, so it could print fake result lines.
+	// Each benchmark reports once per run, so more results than runs mean some of them are forged.
+	if forged := overreportedBenchmark(results, options.count()); forged != "" {
+		return &models.BenchmarkReport{
+			Results:     []models.BenchmarkResult{},
+			Comparisons: []models.BenchmarkComparison{},
+			Error:       fmt.Sprintf("Benchmark %s reported more results than it ran, so the output was rejected", forged),
+		}
+	}
+
+	var referenceResults []models.BenchmarkResult
+	var referenceErr error
+	if err == nil && len(references) > 0 {
+		referenceResults, referenceErr = es.referenceResults(benchCtx, challenge, options, references)
+	}
+
+	report := benchmarkReport(append(referenceResults, results...))
+	switch {
+	case (err != nil || referenceErr != nil) && benchCtx.Err() == context.DeadlineExceeded:
+		report.Error = fmt.Sprintf("Benchmarks exceeded the %s time limit", es.timeoutFor(challenge))
+	case err != nil && len(results) == 0:
+		report.Error = "Benchmarks did not run: " + err.Error()
+	case referenceErr != nil && len(referenceResults) == 0:
+		report.Error = "Reference benchmarks did not run: " + referenceErr.Error()
+	}
+	return report
+}
+
+// referenceResults returns the reference benchmarks of a challenge, measured once on its template
+// and then cached, since every submission is compared with the same code
+func (es *ExecutionService) referenceResults(ctx context.Context, challenge *models.Challenge, options *BenchmarkOptions, references referenceSet) ([]models.BenchmarkResult, error) {
+	template, err := es.templateFor(challenge)
+	if err != nil {
+		return nil, err
+	}
+	key := resultKey(challenge.TestFile, template, es.goVersion, RunOptions{Benchmark: options})
+	if results, ok := es.references.get(key); ok {
+		return results, nil
+	}
+
+	results, err := es.runReferenceBenchmarks(ctx, challenge, template, options, references)
+	if err == nil && len(results) > 0 {
+		es.references.put(key, results)
+	}
+	return results, err
+}
+
+// runReferenceBenchmarks runs only the reference benchmarks, against the challenge's template
+func (es *ExecutionService) runReferenceBenchmarks(ctx context.Context, challenge *models.Challenge, template string, options *BenchmarkOptions, references referenceSet) ([]models.BenchmarkResult, error) {
+	referenceDir, err := es.prepareRunDir(ctx, template, challenge)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(referenceDir)
+
+	output, err := es.benchmarkCommand(ctx, referenceDir, challenge, options, "-bench", references.pattern()).CombinedOutput()
+	return references.filter(parseBenchmarkResults(ParseTestJSON(string(output)).Output), true), err
+}
+
+// benchmarkCommand builds the sandboxed go test command for the benchmarks selected by args
+func (es *ExecutionService) benchmarkCommand(ctx context.Context, dir string, challenge *models.Challenge, options *BenchmarkOptions, args ...string) *exec.Cmd {
+	args = append([]string{"test", "-json", "-trimpath", "-run", "^$", "-benchmem",
+		"-benchtime", benchmarkTime, "-count", strconv.Itoa(options.count())}, args...)
+	cmd := es.sandbox.Command(ctx, es.sandboxJob(dir, challenge), "go", args...)
+	cmd.Env = append(cmd.Env, es.modules.RunEnv()...)
+	return cmd
+}
+
+// referenceSet holds the names of a test file's benchmark functions that measure a reference implementation
+type referenceSet map[string]bool
+
+// referenceBenchmarks finds the benchmarks an Optimized benchmark of the test file is compared with,
+// such as BenchmarkSlowSort for BenchmarkOptimizedSort
+func referenceBenchmarks(testFile string) referenceSet {
+	file, err := parser.ParseFile(token.NewFileSet(), "", testFile, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	benchmarks := make(map[string]bool)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && strings.HasPrefix(fn.Name.Name, "Benchmark") {
+			benchmarks[fn.Name.Name] = true
+		}
+	}
+
+	references := referenceSet{}
+	for name := range benchmarks {
+		if !strings.Contains(name, "Optimized") {
+			continue
+		}
+		for _, prefix := range referencePrefixes {
+			if reference := strings.Replace(name, "Optimized", prefix, 1); benchmarks[reference] {
+				references[reference] = true
+				break
+			}
+		}
+	}
+	return references
+}
+
+// pattern matches exactly the reference benchmarks, for -bench and -skip
+func (rs referenceSet) pattern() string {
+	names := make([]string, 0, len(rs))
+	for name := range rs {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	sort.Strings(names)
+	return "^(" + strings.Join(names, "|") + ")$"
+}
+
+// filter keeps the results of reference benchmarks, or only the others. A submission's own output
+// could claim reference results, so they are only taken from the template's run.
+func (rs referenceSet) filter(results []models.BenchmarkResult, references bool) []models.BenchmarkResult {
+	var kept []models.BenchmarkResult
+	for _, result := range results {
+		top := strings.SplitN(result.Name, "/", 2)[0]
+		if rs["Benchmark"+top] == references {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// benchmarkLinePattern matches a result line such as
+// "BenchmarkOptimizedSort/100-8   123456   950.2 ns/op   896 B/op   1 allocs/op"
+var benchmarkLinePattern = regexp.MustCompile(`^(Benchmark\S+?)(?:-\d+)?\s+(\d+)\s+([\d.]+) ns/op(?:\s+([\d.]+) B/op)?(?:\s+([\d.]+) allocs/op)?`)
+
+// parseBenchmarkResults averages the results of each benchmark in a log over its runs
+func parseBenchmarkResults(output string) []models.BenchmarkResult {
+	results := []models.BenchmarkResult{}
+	index := make(map[string]int)
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		match := benchmarkLinePattern.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+
+		name := strings.TrimPrefix(match[1], "Benchmark")
+		iterations, _ := strconv.ParseInt(match[2], 10, 64)
+		nsPerOp, _ := strconv.ParseFloat(match[3], 64)
+		bytesPerOp, _ := strconv.ParseFloat(match[4], 64)
+		allocsPerOp, _ := strconv.ParseFloat(match[5], 64)
+
+		i, ok := index[name]
+		if !ok {
+			i = len(results)
+			index[name] = i
+			results = append(results, models.BenchmarkResult{Name: name})
+		}
+
+		// Keep running sums and turn them into means below
+		result := &results[i]
+		result.Runs++
+		result.Iterations += iterations
+		result.NsPerOp += nsPerOp
+		result.BytesPerOp += bytesPerOp
+		result.AllocsPerOp += allocsPerOp
+	}
+
+	for i := range results {
+		result := &results[i]
+		runs := float64(result.Runs)
+		result.NsPerOp /= runs
+		result.BytesPerOp /= runs
+		result.AllocsPerOp /= runs
+	}
+	return results
+}
+
+// overreportedBenchmark returns the name of a benchmark with more results than runs, or ""
+func overreportedBenchmark(results []models.BenchmarkResult, runs int) string {
+	for _, result := range results {
+		if result.Runs > runs {
+			return result.Name
+		}
+	}
+	return ""
+}
+
+// referenceCache keeps the reference benchmark results of each challenge template
+type referenceCache struct {
+	mutex   sync.Mutex
+	entries map[string][]models.BenchmarkResult
+}
+
+// newReferenceCache creates an empty reference cache
+func newReferenceCache() *referenceCache {
+	return &referenceCache{entries: make(map[string][]models.BenchmarkResult)}
+}
+
+// get returns the cached results for a key
+func (rc *referenceCache) get(key string) ([]models.BenchmarkResult, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	results, ok := rc.entries[key]
+	return results, ok
+}
+
+// put stores the results for a key
+func (rc *referenceCache) put(key string, results []models.BenchmarkResult) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.entries[key] = results
+}
+
+// clear drops every cached result
+func (rc *referenceCache) clear() {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.entries = make(map[string][]models.BenchmarkResult)
+}
+
+// benchmarkReport pairs every Optimized result with its reference and computes the overall speedup
+func benchmarkReport(results []models.BenchmarkResult) *models.BenchmarkReport {
+	index := make(map[string]int, len(results))
+	for i, result := range results {
+		index[result.Name] = i
+	}
+
+	report := &models.BenchmarkReport{
+		Results:     append([]models.BenchmarkResult{}, results...),
+		Comparisons: compareWithReferences(results, index),
+	}
+	report.Speedup = overallSpeedup(report.Comparisons)
+	return report
+}
+
+// compareWithReferences pairs benchmarks such as OptimizedSort/100 with SlowSort/100
+func compareWithReferences(results []models.BenchmarkResult, index map[string]int) []models.BenchmarkComparison {
+	comparisons := []models.BenchmarkComparison{}
+
+	for _, result := range results {
+		if !strings.Contains(result.Name, "Optimized") {
+			continue
+		}
+
+		for _, prefix := range referencePrefixes {
+			referenceName := strings.Replace(result.Name, "Optimized", prefix, 1)
+			i, ok := index[referenceName]
+			if !ok {
+				continue
+			}
+
+			reference := results[i]
+			comparison := models.BenchmarkComparison{
+				Name:                 result.Name,
+				Reference:            reference.Name,
+				NsPerOp:              result.NsPerOp,
+				ReferenceNsPerOp:     reference.NsPerOp,
+				BytesPerOp:           result.BytesPerOp,
+				ReferenceBytesPerOp:  reference.BytesPerOp,
+				AllocsPerOp:          result.AllocsPerOp,
+				ReferenceAllocsPerOp: reference.AllocsPerOp,
+			}
+			if result.NsPerOp > 0 {
+				comparison.Speedup = reference.NsPerOp / result.NsPerOp
+			}
+			comparisons = append(comparisons, comparison)
+			break
+		}
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool {
+		return comparisons[i].Name < comparisons[j].Name
+	})
+	return comparisons
+}
+
+// overallSpeedup is the geometric mean of the speedups, so one extreme case cannot dominate the score
+func overallSpeedup(comparisons []models.BenchmarkComparison) float64 {
+	var logSum float64
+	var count int
+	for _, comparison := range comparisons {
+		if comparison.Speedup > 0 {
+			logSum += math.Log(comparison.Speedup)
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return math.Exp(logSum / float64(count))
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"web-ui/internal/models"
+)
+
+func TestParseBenchmarkResults(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []models.BenchmarkResult
+	}{
+		{
+			name:   "no benchmarks",
+			output: "PASS\nok  \tchallenge\t0.01s\n",
+			want:   []models.BenchmarkResult{},
+		},
+		{
+			name:   "single run with memory",
+			output: "BenchmarkOptimizedSort/100-8   \t  123456\t       950.5 ns/op\t     896 B/op\t       1 allocs/op\n",
+			want: []models.BenchmarkResult{
+				{Name: "OptimizedSort/100", Runs: 1, Iterations: 123456, NsPerOp: 950.5, BytesPerOp: 896, AllocsPerOp: 1},
+			},
+		},
+		{
+			name:   "without GOMAXPROCS suffix or memory",
+			output: "BenchmarkSlowSort \t    1000\t      2000 ns/op\n",
+			want: []models.BenchmarkResult{
+				{Name: "SlowSort", Runs: 1, Iterations: 1000, NsPerOp: 2000},
+			},
+		},
+		{
+			name: "runs averaged in order of appearance",
+			output: "BenchmarkB-4   100   30 ns/op   8 B/op   2 allocs/op\n" +
+				"BenchmarkA-4   200   10 ns/op\n" +
+				"BenchmarkB-4   300   50 ns/op   16 B/op   4 allocs/op\n",
+			want: []models.BenchmarkResult{
+				{Name: "B", Runs: 2, Iterations: 400, NsPerOp: 40, BytesPerOp: 12, AllocsPerOp: 3},
+				{Name: "A", Runs: 1, Iterations: 200, NsPerOp: 10},
+			},
+		},
+		{
+			name:   "benchmark names without results",
+			output: "BenchmarkOptimizedSort\nBenchmarkOptimizedSort/100\n--- FAIL: BenchmarkBroken\n",
+			want:   []models.BenchmarkResult{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseBenchmarkResults(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseBenchmarkResults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverreportedBenchmark(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []models.BenchmarkResult
+		runs    int
+		want    string
+	}{
+		{"one result per run", []models.BenchmarkResult{{Name: "OptimizedSort", Runs: 3}, {Name: "OptimizedSort/100", Runs: 3}}, 3, ""},
+		{"fewer results than runs", []models.BenchmarkResult{{Name: "OptimizedSort", Runs: 1}}, 3, ""},
+		{"printed result line", []models.BenchmarkResult{{Name: "OptimizedSort", Runs: 1}, {Name: "OptimizedMap", Runs: 2}}, 1, "OptimizedMap"},
+		{"no results", nil, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overreportedBenchmark(tt.results, tt.runs); got != tt.want {
+				t.Errorf("overreportedBenchmark() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferenceBenchmarks(t *testing.T) {
+	testFile := `package main
+
+import "testing"
+
+func BenchmarkSlowSort(b *testing.B)           {}
+func BenchmarkOptimizedSort(b *testing.B)      {}
+func BenchmarkInefficientJoin(b *testing.B)    {}
+func BenchmarkOptimizedJoin(b *testing.B)      {}
+func BenchmarkExpensiveUnpaired(b *testing.B)  {}
+func BenchmarkOptimizedOrphan(b *testing.B)    {}
+func TestSlowSort(t *testing.T)                {}
+`
+	references := referenceBenchmarks(testFile)
+
+	want := referenceSet{"BenchmarkSlowSort": true, "BenchmarkInefficientJoin": true}
+	if !reflect.DeepEqual(references, want) {
+		t.Fatalf("referenceBenchmarks() = %v, want %v", references, want)
+	}
+	if pattern := references.pattern(); pattern != "^(BenchmarkInefficientJoin|BenchmarkSlowSort)$" {
+		t.Errorf("pattern() = %q", pattern)
+	}
+
+	results := []models.BenchmarkResult{{Name: "SlowSort/100"}, {Name: "OptimizedSort/100"}, {Name: "InefficientJoin"}, {Name: "OptimizedJoin"}}
+	if got := benchmarkNames(references.filter(results, true)); !reflect.DeepEqual(got, []string{"SlowSort/100", "InefficientJoin"}) {
+		t.Errorf("filter(references) = %v", got)
+	}
+	if got := benchmarkNames(references.filter(results, false)); !reflect.DeepEqual(got, []string{"OptimizedSort/100", "OptimizedJoin"}) {
+		t.Errorf("filter(submission) = %v", got)
+	}
+}
+
+func TestBenchmarkReport(t *testing.T) {
+	report := benchmarkReport([]models.BenchmarkResult{
+		{Name: "SlowSort/100", NsPerOp: 800},
+		{Name: "OptimizedSort/100", NsPerOp: 100},
+		{Name: "InefficientJoin", NsPerOp: 200},
+		{Name: "OptimizedJoin", NsPerOp: 100},
+		{Name: "OptimizedOrphan", NsPerOp: 50},
+	})
+
+	if len(report.Comparisons) != 2 {
+		t.Fatalf("comparisons = %+v, want 2", report.Comparisons)
+	}
+	if first := report.Comparisons[0]; first.Name != "OptimizedJoin" || first.Reference != "InefficientJoin" || first.Speedup != 2 {
+		t.Errorf("first comparison = %+v", first)
+	}
+	if second := report.Comparisons[1]; second.Name != "OptimizedSort/100" || second.Reference != "SlowSort/100" || second.Speedup != 8 {
+		t.Errorf("second comparison = %+v", second)
+	}
+	// Geometric mean of 2 and 8
+	if math.Abs(report.Speedup-4) > 1e-9 {
+		t.Errorf("speedup = %v, want 4", report.Speedup)
+	}
+}
+
+// benchmarkNames lists the names of benchmark results
+func benchmarkNames(results []models.BenchmarkResult) []string {
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	return names
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -8,6 +9,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"web-ui/internal/models"
 )
@@ -15,6 +17,7 @@ import (
 // ChallengeService handles challenge-related operations
 type ChallengeService struct {
 	challenges models.ChallengeMap
+	mutex      sync.RWMutex // Guards swapping in a reloaded map; a loaded map is never modified
 }
 
 // NewChallengeService creates a new challenge service
@@ -24,18 +27,19 @@ func NewChallengeService() *ChallengeService {
 	}
 }
 
-// LoadChallenges loads all challenges from the filesystem
+// LoadChallenges loads all challenges from the filesystem, replacing any loaded before
 func (cs *ChallengeService) LoadChallenges() error {
 	// Find challenge directories (challenge-1, challenge-2, etc.)
-	challengeDirs, err := filepath.Glob("../challenge-*")
+	challengeDirs, err := filepath.Glob(WorkspacePath("challenge-*"))
 	if err != nil {
 		return fmt.Errorf("failed to find challenge directories: %v", err)
 	}
 
+	challenges := make(models.ChallengeMap)
 	for _, dir := range challengeDirs {
 		// Extract challenge number
 		re := regexp.MustCompile(`challenge-(\d+)`)
-		match := re.FindStringSubmatch(dir)
+		match := re.FindStringSubmatch(filepath.Base(dir))
 		if len(match) < 2 {
 			continue
 		}
@@ -51,10 +55,14 @@ func (cs *ChallengeService) LoadChallenges() error {
 			continue
 		}
 
-		cs.challenges[id] = challenge
+		challenges[id] = challenge
 	}
 
-	log.Printf("Loaded %d challenges", len(cs.challenges))
+	cs.mutex.Lock()
+	cs.challenges = challenges
+	cs.mutex.Unlock()
+
+	log.Printf("Loaded %d challenges", len(challenges))
 	return nil
 }
 
@@ -70,9 +78,6 @@ func (cs *ChallengeService) loadSingleChallenge(id int, dir string) (*models.Cha
 	// Extract title from README (first heading)
 	title := cs.extractTitle(string(readmeContent), id)
 
-	// Determine difficulty level
-	difficulty := cs.determineDifficulty(id)
-
 	// Read solution template
 	templatePath := filepath.Join(dir, "solution-template.go")
 	templateContent, err := ioutil.ReadFile(templatePath)
@@ -101,6 +106,15 @@ func (cs *ChallengeService) loadSingleChallenge(id int, dir string) (*models.Cha
 		hintsContent = hintsFileContent
 	}
 
+	// Read optional execution settings
+	settings := cs.loadChallengeSettings(dir)
+
+	// Determine difficulty level, unless metadata.json sets it
+	difficulty := settings.Difficulty
+	if difficulty == "" {
+		difficulty = cs.determineDifficulty(id)
+	}
+
 	// Create challenge
 	challenge := &models.Challenge{
 		ID:                id,
@@ -111,11 +125,37 @@ func (cs *ChallengeService) loadSingleChallenge(id int, dir string) (*models.Cha
 		TestFile:          string(testContent),
 		LearningMaterials: string(learningContent),
 		Hints:             string(hintsContent),
+		TimeoutSeconds:    settings.TimeoutSeconds,
+		BenchmarkCount:    settings.BenchmarkCount,
+		Dir:               dir,
 	}
 
 	return challenge, nil
 }
 
+// challengeSettings holds optional per-challenge settings from metadata.json
+type challengeSettings struct {
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	BenchmarkCount int    `json:"benchmark_count"` // Scores submissions by benchmark speedup when set
+	Difficulty     string `json:"difficulty"`      // Overrides the difficulty determined from the ID
+}
+
+// loadChallengeSettings reads metadata.json from a challenge directory if present
+func (cs *ChallengeService) loadChallengeSettings(dir string) challengeSettings {
+	var settings challengeSettings
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return settings
+	}
+
+	if err := json.Unmarshal(content, &settings); err != nil {
+		log.Printf("Warning: Could not parse metadata.json in %s: %v", dir, err)
+	}
+
+	return settings
+}
+
 // extractTitle extracts the title from README content
 func (cs *ChallengeService) extractTitle(readmeContent string, id int) string {
 	titleRe := regexp.MustCompile(`#\s+(.+)`)
@@ -181,11 +221,15 @@ func (cs *ChallengeService) filterWebUIDescription(content string) string {
 
 // GetChallenges returns all challenges
 func (cs *ChallengeService) GetChallenges() models.ChallengeMap {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
 	return cs.challenges
 }
 
 // GetChallenge returns a specific challenge by ID
 func (cs *ChallengeService) GetChallenge(id int) (*models.Challenge, bool) {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
 	challenge, exists := cs.challenges[id]
 	return challenge, exists
 }
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"errors"
+
+	"web-ui/internal/models"
+)
+
+// Cohort store errors
+var (
+	ErrCohortExists   = errors.New("cohort already exists")
+	ErrCohortNotFound = errors.New("cohort not found")
+)
+
+// CohortStore persists cohorts and their members. Implementations are safe for concurrent use.
+type CohortStore interface {
+	// Create stores a new cohort, or returns ErrCohortExists
+	Create(cohort models.Cohort) error
+	// List returns every cohort in ID order
+	List() ([]models.Cohort, error)
+	// Get returns the cohort with an ID
+	Get(id string) (models.Cohort, bool, error)
+	// AddMember adds a user to a cohort, reporting false if they were already a member
+	AddMember(id, username string) (bool, error)
+	// RemoveMember removes a user from a cohort, reporting false if they were not a member
+	RemoveMember(id, username string) (bool, error)
+	// Close releases the underlying storage
+	Close() error
+}
+
+// NewCohortStoreFromEnv opens a cohort store with the backend selected by SUBMISSION_STORE, in the
+// submission store's database. If the SQLite database cannot be opened, cohorts fall back to a JSON file.
+func NewCohortStoreFromEnv() (CohortStore, error) {
+	return openStoreFromEnv("Cohort",
+		func(path string) (CohortStore, error) { return NewSQLiteCohortStore(path) },
+		"cohorts.json",
+		func(path string) (CohortStore, error) { return NewJSONCohortStore(path) },
+	)
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"web-ui/internal/models"
+	"web-ui/internal/utils"
+)
+
+// JSONCohortStore keeps cohorts in memory and rewrites them to a JSON file on every change.
+// Membership can shrink, so unlike the other file-backed stores this one is not append-only.
+type JSONCohortStore struct {
+	mutex   sync.RWMutex
+	path    string
+	cohorts map[string]models.Cohort
+}
+
+// NewJSONCohortStore opens the file at path, creating it on the first change, and loads the cohorts it holds
+func NewJSONCohortStore(path string) (*JSONCohortStore, error) {
+	store := &JSONCohortStore{path: path, cohorts: make(map[string]models.Cohort)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cohorts []models.Cohort
+	if err := json.Unmarshal(data, &cohorts); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	for _, cohort := range cohorts {
+		if cohort.Members == nil {
+			cohort.Members = []string{}
+		}
+		store.cohorts[cohort.ID] = cohort
+	}
+
+	return store, nil
+}
+
+// sorted returns every cohort in ID order. The caller holds the mutex.
+func (s *JSONCohortStore) sorted() []models.Cohort {
+	cohorts := make([]models.Cohort, 0, len(s.cohorts))
+	for _, cohort := range s.cohorts {
+		cohort.Members = append([]string{}, cohort.Members...)
+		cohorts = append(cohorts, cohort)
+	}
+	sort.Slice(cohorts, func(i, j int) bool {
+		return cohorts[i].ID < cohorts[j].ID
+	})
+	return cohorts
+}
+
+// save writes every cohort atomically, so a crash mid-write leaves the previous version intact.
+// The caller holds the write lock.
+func (s *JSONCohortStore) save() error {
+	data, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteFileAtomic(s.path, append(data, '\n'), 0644)
+}
+
+// Create stores a new cohort, or returns ErrCohortExists
+func (s *JSONCohortStore) Create(cohort models.Cohort) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.cohorts[cohort.ID]; exists {
+		return ErrCohortExists
+	}
+
+	members := []string{}
+	seen := make(map[string]bool)
+	for _, username := range cohort.Members {
+		if !seen[strings.ToLower(username)] {
+			seen[strings.ToLower(username)] = true
+			members = append(members, username)
+		}
+	}
+	cohort.Members = members
+
+	s.cohorts[cohort.ID] = cohort
+	if err := s.save(); err != nil {
+		delete(s.cohorts, cohort.ID)
+		return err
+	}
+	return nil
+}
+
+// List returns every cohort in ID order
+func (s *JSONCohortStore) List() ([]models.Cohort, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.sorted(), nil
+}
+
+// Get returns the cohort with an ID
+func (s *JSONCohortStore) Get(id string) (models.Cohort, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	cohort, exists := s.cohorts[id]
+	if !exists {
+		return models.Cohort{}, false, nil
+	}
+	cohort.Members = append([]string{}, cohort.Members...)
+	return cohort, true, nil
+}
+
+// AddMember adds a user to a cohort, reporting false if they were already a member
+func (s *JSONCohortStore) AddMember(id, username string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cohort, exists := s.cohorts[id]
+	if !exists {
+		return false, ErrCohortNotFound
+	}
+	if cohort.HasMember(username) {
+		return false, nil
+	}
+
+	previous := cohort
+	cohort.Members = append(append([]string{}, cohort.Members...), username)
+	s.cohorts[id] = cohort
+	if err := s.save(); err != nil {
+		s.cohorts[id] = previous
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveMember removes a user from a cohort, reporting false if they were not a member
+func (s *JSONCohortStore) RemoveMember(id, username string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cohort, exists := s.cohorts[id]
+	if !exists {
+		return false, ErrCohortNotFound
+	}
+	if !cohort.HasMember(username) {
+		return false, nil
+	}
+
+	previous := cohort
+	members := make([]string, 0, len(cohort.Members)-1)
+	for _, member := range cohort.Members {
+		if !strings.EqualFold(member, username) {
+			members = append(members, member)
+		}
+	}
+	cohort.Members = members
+	s.cohorts[id] = cohort
+	if err := s.save(); err != nil {
+		s.cohorts[id] = previous
+		return false, err
+	}
+	return true, nil
+}
+
+// Close is a no-op; every change is already on disk
+func (s *JSONCohortStore) Close() error {
+	return nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"web-ui/internal/models"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteCohortStore keeps cohorts and their members in a SQLite database
+type SQLiteCohortStore struct {
+	db *sql.DB
+}
+
+// sqliteCohortSchema creates the cohorts and cohort_members tables
+const sqliteCohortSchema = `
+CREATE TABLE IF NOT EXISTS cohorts (
+	id         TEXT    PRIMARY KEY,
+	name       TEXT    NOT NULL,
+	created_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS cohort_members (
+	cohort_id TEXT    NOT NULL REFERENCES cohorts (id),
+	username  TEXT    NOT NULL,
+	added_at  INTEGER NOT NULL,
+	PRIMARY KEY (cohort_id, username)
+);
+`
+
+// NewSQLiteCohortStore opens or creates its tables in the database at path
+func NewSQLiteCohortStore(path string) (*SQLiteCohortStore, error) {
+	db, err := openSQLiteDatabase(path, sqliteCohortSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteCohortStore{db: db}, nil
+}
+
+// Create stores a new cohort, or returns ErrCohortExists
+func (s *SQLiteCohortStore) Create(cohort models.Cohort) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT OR IGNORE INTO cohorts (id, name, created_at) VALUES (?, ?, ?)",
+		cohort.ID, cohort.Name, cohort.CreatedAt.UnixNano(),
+	)
+	if err != nil {
+		return err
+	}
+	if inserted, err := result.RowsAffected(); err != nil {
+		return err
+	} else if inserted == 0 {
+		return ErrCohortExists
+	}
+
+	for _, username := range cohort.Members {
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO cohort_members (cohort_id, username, added_at) VALUES (?, ?, ?)",
+			cohort.ID, username, cohort.CreatedAt.UnixNano(),
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// List returns every cohort in ID order
+func (s *SQLiteCohortStore) List() ([]models.Cohort, error) {
+	return s.query("")
+}
+
+// Get returns the cohort with an ID
+func (s *SQLiteCohortStore) Get(id string) (models.Cohort, bool, error) {
+	cohorts, err := s.query(id)
+	if err != nil || len(cohorts) == 0 {
+		return models.Cohort{}, false, err
+	}
+	return cohorts[0], true, nil
+}
+
+// query returns the cohort with an ID, or every cohort if id is empty, with their members
+func (s *SQLiteCohortStore) query(id string) ([]models.Cohort, error) {
+	statement := `SELECT c.id, c.name, c.created_at, COALESCE(m.username, '')
+		FROM cohorts c LEFT JOIN cohort_members m ON m.cohort_id = c.id`
+	var args []interface{}
+	if id != "" {
+		statement += " WHERE c.id = ?"
+		args = append(args, id)
+	}
+	statement += " ORDER BY c.id, m.added_at, m.username"
+
+	rows, err := s.db.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cohorts := []models.Cohort{}
+	for rows.Next() {
+		var cohort models.Cohort
+		var createdAt int64
+		var username string
+		if err := rows.Scan(&cohort.ID, &cohort.Name, &createdAt, &username); err != nil {
+			return nil, err
+		}
+
+		// Rows come grouped by cohort, one per member
+		if len(cohorts) == 0 || cohorts[len(cohorts)-1].ID != cohort.ID {
+			cohort.CreatedAt = time.Unix(0, createdAt).UTC()
+			cohort.Members = []string{}
+			cohorts = append(cohorts, cohort)
+		}
+		if username != "" {
+			last := &cohorts[len(cohorts)-1]
+			last.Members = append(last.Members, username)
+		}
+	}
+
+	return cohorts, rows.Err()
+}
+
+// AddMember adds a user to a cohort, reporting false if they were already a member
+func (s *SQLiteCohortStore) AddMember(id, username string) (bool, error) {
+	result, err := s.db.Exec(
+		`INSERT OR IGNORE INTO cohort_members (cohort_id, username, added_at) SELECT id, ?, ? FROM cohorts WHERE id = ?
+			AND NOT EXISTS (SELECT 1 FROM cohort_members WHERE cohort_id = ? AND username = ? COLLATE NOCASE)`,
+		username, time.Now().UnixNano(), id, id, username,
+	)
+	if err != nil {
+		return false, err
+	}
+	if added, err := result.RowsAffected(); err != nil || added > 0 {
+		return added > 0, err
+	}
+	return false, s.requireCohort(id)
+}
+
+// RemoveMember removes a user from a cohort, reporting false if they were not a member
+func (s *SQLiteCohortStore) RemoveMember(id, username string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM cohort_members WHERE cohort_id = ? AND username = ? COLLATE NOCASE", id, username)
+	if err != nil {
+		return false, err
+	}
+	if removed, err := result.RowsAffected(); err != nil || removed > 0 {
+		return removed > 0, err
+	}
+	return false, s.requireCohort(id)
+}
+
+// requireCohort returns ErrCohortNotFound if no cohort has an ID
+func (s *SQLiteCohortStore) requireCohort(id string) error {
+	var exists int
+	err := s.db.QueryRow("SELECT 1 FROM cohorts WHERE id = ?", id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrCohortNotFound
+	}
+	return err
+}
+
+// Close closes the database
+func (s *SQLiteCohortStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bufio"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"web-ui/internal/models"
+)
+
+// coverageProfileName is the file go test writes the coverage profile to, inside the run directory
+const coverageProfileName = "coverage.out"
+
+// submissionFileName is the file the submitted code is written to
+const submissionFileName = "solution-template.go"
+
+// readCoverage parses the coverage profile of a run for the submission file
+func readCoverage(tempDir, code string) *models.CoverageReport {
+	content, err := ioutil.ReadFile(filepath.Join(tempDir, coverageProfileName))
+	if err != nil {
+		// No profile means the tests never ran, e.g. because the code does not compile
+		return nil
+	}
+	return ParseCoverageProfile(string(content), code)
+}
+
+// ParseCoverageProfile builds the coverage report of the submission file from a profile
+// written by go test -coverprofile. The code is parsed to attribute blocks to functions.
+func ParseCoverageProfile(profile, code string) *models.CoverageReport {
+	report := &models.CoverageReport{
+		Functions:      []models.FunctionCoverage{},
+		Blocks:         []models.CoverageBlock{},
+		CoveredLines:   []int{},
+		UncoveredLines: []int{},
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(profile))
+	for scanner.Scan() {
+		block, file, ok := parseCoverageLine(scanner.Text())
+		if ok && path.Base(file) == submissionFileName {
+			report.Blocks = append(report.Blocks, block)
+		}
+	}
+
+	// Tests run with -count or several packages can report a block more than once
+	report.Blocks = mergeCoverageBlocks(report.Blocks)
+
+	var statements, covered int
+	for _, block := range report.Blocks {
+		statements += block.Statements
+		if block.Count > 0 {
+			covered += block.Statements
+		}
+	}
+	report.Percent = coveragePercent(covered, statements)
+
+	report.Functions = functionCoverage(code, report.Blocks)
+	report.CoveredLines, report.UncoveredLines = lineCoverage(report.Blocks)
+	return report
+}
+
+// parseCoverageLine parses a profile line such as "challenge1/solution-template.go:5.20,8.2 1 0"
+func parseCoverageLine(line string) (models.CoverageBlock, string, bool) {
+	var block models.CoverageBlock
+
+	colon := strings.LastIndex(line, ":")
+	if colon < 0 || strings.HasPrefix(line, "mode:") {
+		return block, "", false
+	}
+
+	fields := strings.Fields(line[colon+1:])
+	if len(fields) != 3 {
+		return block, "", false
+	}
+
+	start, end, found := strings.Cut(fields[0], ",")
+	if !found {
+		return block, "", false
+	}
+
+	var err error
+	if block.StartLine, block.StartColumn, err = parseCoveragePosition(start); err != nil {
+		return block, "", false
+	}
+	if block.EndLine, block.EndColumn, err = parseCoveragePosition(end); err != nil {
+		return block, "", false
+	}
+	if block.Statements, err = strconv.Atoi(fields[1]); err != nil {
+		return block, "", false
+	}
+	if block.Count, err = strconv.Atoi(fields[2]); err != nil {
+		return block, "", false
+	}
+
+	return block, line[:colon], true
+}
+
+// parseCoveragePosition parses "line.column"
+func parseCoveragePosition(position string) (int, int, error) {
+	lineText, columnText, _ := strings.Cut(position, ".")
+	line, err := strconv.Atoi(lineText)
+	if err != nil {
+		return 0, 0, err
+	}
+	column, err := strconv.Atoi(columnText)
+	return line, column, err
+}
+
+// mergeCoverageBlocks sums the counts of repeated blocks and sorts them by position
+func mergeCoverageBlocks(blocks []models.CoverageBlock) []models.CoverageBlock {
+	type position struct{ startLine, startColumn, endLine, endColumn int }
+
+	index := make(map[position]int)
+	merged := []models.CoverageBlock{}
+	for _, block := range blocks {
+		key := position{block.StartLine, block.StartColumn, block.EndLine, block.EndColumn}
+		if i, ok := index[key]; ok {
+			merged[i].Count += block.Count
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, block)
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].StartLine != merged[j].StartLine {
+			return merged[i].StartLine < merged[j].StartLine
+		}
+		return merged[i].StartColumn < merged[j].StartColumn
+	})
+	return merged
+}
+
+// functionCoverage totals the statements of the blocks inside each function, like go tool cover -func
+func functionCoverage(code string, blocks []models.CoverageBlock) []models.FunctionCoverage {
+	functions := []models.FunctionCoverage{}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, submissionFileName, code, 0)
+	if err != nil {
+		return functions
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		start := fset.Position(fn.Pos())
+		end := fset.Position(fn.End())
+		function := models.FunctionCoverage{
+			Name: functionName(fn),
+			Line: start.Line,
+		}
+
+		var covered int
+		for _, block := range blocks {
+			if !positionBefore(start.Line, start.Column, block.StartLine, block.StartColumn) ||
+				!positionBefore(block.EndLine, block.EndColumn, end.Line, end.Column) {
+				continue
+			}
+			function.Statements += block.Statements
+			if block.Count > 0 {
+				covered += block.Statements
+			}
+		}
+		function.Percent = coveragePercent(covered, function.Statements)
+
+		functions = append(functions, function)
+	}
+
+	return functions
+}
+
+// functionName returns "Name" for functions and "Type.Name" for methods
+func functionName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+
+	receiver := fn.Recv.List[0].Type
+	if star, ok := receiver.(*ast.StarExpr); ok {
+		receiver = star.X
+	}
+	if index, ok := receiver.(*ast.IndexExpr); ok {
+		receiver = index.X
+	}
+	if ident, ok := receiver.(*ast.Ident); ok {
+		return ident.Name + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// positionBefore reports whether line1:column1 is at or before line2:column2
+func positionBefore(line1, column1, line2, column2 int) bool {
+	return line1 < line2 || (line1 == line2 && column1 <= column2)
+}
+
+// lineCoverage splits the lines of the blocks into covered and uncovered ones.
+// A line is covered if any block on it ran, so a line is only reported as
+// uncovered when none of the code on it was reached.
+func lineCoverage(blocks []models.CoverageBlock) ([]int, []int) {
+	reached := make(map[int]bool)
+	for _, block := range blocks {
+		for line := block.StartLine; line <= block.EndLine; line++ {
+			reached[line] = reached[line] || block.Count > 0
+		}
+	}
+
+	covered := []int{}
+	uncovered := []int{}
+	for line, ran := range reached {
+		if ran {
+			covered = append(covered, line)
+		} else {
+			uncovered = append(uncovered, line)
+		}
+	}
+	sort.Ints(covered)
+	sort.Ints(uncovered)
+	return covered, uncovered
+}
+
+// coveragePercent returns the share of covered statements, 0 when there are none
+func coveragePercent(covered, statements int) float64 {
+	if statements == 0 {
+		return 0
+	}
+	return float64(covered) * 100 / float64(statements)
+}
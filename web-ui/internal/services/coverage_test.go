@@ -0,0 +1,137 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"web-ui/internal/models"
+)
+
+// coverageTestCode is a submission with a generic method and a function with an untested branch
+const coverageTestCode = `package main
+
+type Stack[T any] struct{ items []T }
+
+func (s *Stack[T]) Push(v T) {
+	s.items = append(s.items, v)
+}
+
+func Abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+`
+
+func TestParseCoverageProfile(t *testing.T) {
+	tests := []struct {
+		name           string
+		profile        string
+		code           string
+		percent        float64
+		blocks         int
+		functions      []models.FunctionCoverage
+		coveredLines   []int
+		uncoveredLines []int
+	}{
+		{
+			name: "functions and lines",
+			profile: "mode: set\n" +
+				"challenge/solution-template.go:5.30,7.2 1 1\n" +
+				"challenge/solution-template.go:9.21,10.11 1 2\n" +
+				"challenge/solution-template.go:10.11,12.3 1 0\n" +
+				"challenge/solution-template.go:13.2,13.10 1 2\n",
+			code:    coverageTestCode,
+			percent: 75,
+			blocks:  4,
+			functions: []models.FunctionCoverage{
+				{Name: "Stack.Push", Line: 5, Statements: 1, Percent: 100},
+				{Name: "Abs", Line: 9, Statements: 3, Percent: 200.0 / 3},
+			},
+			coveredLines:   []int{5, 6, 7, 9, 10, 13},
+			uncoveredLines: []int{11, 12},
+		},
+		{
+			name: "other files, repeated blocks and malformed lines",
+			profile: "mode: count\n" +
+				"challenge/solution_test.go:3.1,4.2 5 1\n" +
+				"challenge/solution-template.go:5.30,7.2 1 0\n" +
+				"not a profile line\n" +
+				"challenge/solution-template.go:5.30,7.2 1 3\n" +
+				"challenge/solution-template.go:x.1,2.2 1 1\n",
+			code:    coverageTestCode,
+			percent: 100,
+			blocks:  1,
+			functions: []models.FunctionCoverage{
+				{Name: "Stack.Push", Line: 5, Statements: 1, Percent: 100},
+				{Name: "Abs", Line: 9},
+			},
+			coveredLines:   []int{5, 6, 7},
+			uncoveredLines: []int{},
+		},
+		{
+			name:           "code that does not parse",
+			profile:        "mode: set\nchallenge/solution-template.go:3.1,3.10 2 0\n",
+			code:           "package main\n\nfunc {",
+			percent:        0,
+			blocks:         1,
+			functions:      []models.FunctionCoverage{},
+			coveredLines:   []int{},
+			uncoveredLines: []int{3},
+		},
+		{
+			name:           "empty profile",
+			profile:        "mode: set\n",
+			code:           coverageTestCode,
+			functions:      []models.FunctionCoverage{{Name: "Stack.Push", Line: 5}, {Name: "Abs", Line: 9}},
+			coveredLines:   []int{},
+			uncoveredLines: []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := ParseCoverageProfile(tt.profile, tt.code)
+
+			if math.Abs(report.Percent-tt.percent) > 1e-9 {
+				t.Errorf("percent = %v, want %v", report.Percent, tt.percent)
+			}
+			if len(report.Blocks) != tt.blocks {
+				t.Errorf("blocks = %+v, want %d", report.Blocks, tt.blocks)
+			}
+			if len(report.Functions) != len(tt.functions) {
+				t.Fatalf("functions = %+v, want %+v", report.Functions, tt.functions)
+			}
+			for i, want := range tt.functions {
+				got := report.Functions[i]
+				if got.Name != want.Name || got.Line != want.Line || got.Statements != want.Statements || math.Abs(got.Percent-want.Percent) > 1e-9 {
+					t.Errorf("function %d = %+v, want %+v", i, got, want)
+				}
+			}
+			if !reflect.DeepEqual(report.CoveredLines, tt.coveredLines) {
+				t.Errorf("covered lines = %v, want %v", report.CoveredLines, tt.coveredLines)
+			}
+			if !reflect.DeepEqual(report.UncoveredLines, tt.uncoveredLines) {
+				t.Errorf("uncovered lines = %v, want %v", report.UncoveredLines, tt.uncoveredLines)
+			}
+		})
+	}
+}
+
+func TestMergeCoverageBlocks(t *testing.T) {
+	blocks := mergeCoverageBlocks([]models.CoverageBlock{
+		{StartLine: 9, StartColumn: 2, EndLine: 9, EndColumn: 10, Statements: 1, Count: 1},
+		{StartLine: 3, StartColumn: 5, EndLine: 4, EndColumn: 2, Statements: 2, Count: 0},
+		{StartLine: 9, StartColumn: 2, EndLine: 9, EndColumn: 10, Statements: 1, Count: 4},
+	})
+
+	want := []models.CoverageBlock{
+		{StartLine: 3, StartColumn: 5, EndLine: 4, EndColumn: 2, Statements: 2, Count: 0},
+		{StartLine: 9, StartColumn: 2, EndLine: 9, EndColumn: 10, Statements: 1, Count: 5},
+	}
+	if !reflect.DeepEqual(blocks, want) {
+		t.Errorf("mergeCoverageBlocks() = %+v, want %+v", blocks, want)
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is how many unchanged lines surround each change in a unified diff
+const diffContextLines = 3
+
+// maxDiffCells bounds the line comparison table; larger changes are shown as a full replacement
+const maxDiffCells = 4000000
+
+// diffOp is one line of an edit script
+type diffOp struct {
+	kind byte // ' ' unchanged, '-' removed, '+' added
+	text string
+}
+
+// UnifiedDiff returns the unified diff turning a into b, or "" if they are equal
+func UnifiedDiff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range diffHunks(ops) {
+		out.WriteString(hunk)
+	}
+	return out.String()
+}
+
+// splitLines splits text into lines without their line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a shortest edit script between two line slices.
+// Common prefix and suffix lines are matched first, so the comparison table
+// only covers the lines in between, which for edited code is small.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// diffMiddle matches lines by their longest common subsequence
+func diffMiddle(a, b []string) []diffOp {
+	var ops []diffOp
+
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// diffHunks groups an edit script into "@@ -l,s +l,s @@" hunks with surrounding context
+func diffHunks(ops []diffOp) []string {
+	var hunks []string
+
+	for start := 0; start < len(ops); {
+		// Find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend the hunk while the gap between changes fits in the context of both
+		last := first
+		for next := first + 1; next < len(ops); next++ {
+			if ops[next].kind == ' ' {
+				continue
+			}
+			if next-last-1 > 2*diffContextLines {
+				break
+			}
+			last = next
+		}
+
+		from := first - diffContextLines
+		if from < start {
+			from = start
+		}
+		if from < 0 {
+			from = 0
+		}
+		to := last + diffContextLines + 1
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		hunks = append(hunks, formatHunk(ops, from, to))
+		start = to
+	}
+
+	return hunks
+}
+
+// formatHunk renders ops[from:to] with its header
+func formatHunk(ops []diffOp, from, to int) string {
+	// Line numbers before the hunk, 1-based
+	oldLine, newLine := 1, 1
+	for _, op := range ops[:from] {
+		if op.kind != '+' {
+			oldLine++
+		}
+		if op.kind != '-' {
+			newLine++
+		}
+	}
+
+	var body strings.Builder
+	oldCount, newCount := 0, 0
+	for _, op := range ops[from:to] {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+		body.WriteByte(op.kind)
+		body.WriteString(op.text)
+		body.WriteByte('\n')
+	}
+
+	// An empty side starts at the line before the hunk, as in diff -u
+	if oldCount == 0 {
+		oldLine--
+	}
+	if newCount == 0 {
+		newLine--
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@\n%s", oldLine, oldCount, newLine, newCount, body.String())
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// diffTestLines builds a file of lines l1 to ln, with some lines replaced
+func diffTestLines(n int, replaced map[int]string) string {
+	var out strings.Builder
+	for i := 1; i <= n; i++ {
+		if line, ok := replaced[i]; ok {
+			out.WriteString(line + "\n")
+		} else {
+			fmt.Fprintf(&out, "l%d\n", i)
+		}
+	}
+	return out.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string // Hunks after the file header
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			a:    "a\nb\nc\n",
+			b:    "a\nB\nc\n",
+			want: "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "inserted line",
+			a:    "a\nc\n",
+			b:    "a\nb\nc\n",
+			want: "@@ -1,2 +1,3 @@\n a\n+b\n c\n",
+		},
+		{
+			name: "new file",
+			a:    "",
+			b:    "a\n",
+			want: "@@ -0,0 +1,1 @@\n+a\n",
+		},
+		{
+			name: "emptied file",
+			a:    "a\nb\n",
+			b:    "",
+			want: "@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "distant changes in separate hunks",
+			a:    diffTestLines(10, nil),
+			b:    diffTestLines(10, map[int]string{1: "L1", 10: "L10"}),
+			want: "@@ -1,4 +1,4 @@\n-l1\n+L1\n l2\n l3\n l4\n" +
+				"@@ -7,4 +7,4 @@\n l7\n l8\n l9\n-l10\n+L10\n",
+		},
+		{
+			name: "changes within twice the context in one hunk",
+			a:    diffTestLines(10, nil),
+			b:    diffTestLines(10, map[int]string{1: "L1", 8: "L8"}),
+			want: "@@ -1,10 +1,10 @@\n-l1\n+L1\n l2\n l3\n l4\n l5\n l6\n l7\n-l8\n+L8\n l9\n l10\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want != "" {
+				want = "--- from.go\n+++ to.go\n" + want
+			}
+			if got := UnifiedDiff("from.go", "to.go", tt.a, tt.b); got != want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Event hub event types
+const (
+	HubEventSubmissionPassed = "submission-passed" // A core or package challenge submission passed
+	HubEventRankChanged      = "rank-changed"      // A user moved on a leaderboard
+)
+
+// Leaderboards a rank-changed event can name
+const (
+	LeaderboardMain      = "main"
+	LeaderboardChallenge = "challenge"
+	LeaderboardPackage   = "package"
+)
+
+// hubSubscriberBuffer is how many events a subscriber can fall behind before events are dropped for it
+const hubSubscriberBuffer = 32
+
+// HubEvent is a live update published to the event hub's subscribers
+type HubEvent struct {
+	ID                 int64     `json:"id"` // Assigned by the hub, increasing
+	Type               string    `json:"type"`
+	Username           string    `json:"username"`
+	ChallengeID        int       `json:"challengeId,omitempty"`        // Core challenge
+	PackageName        string    `json:"packageName,omitempty"`        // Package of a package challenge or leaderboard
+	PackageChallengeID string    `json:"packageChallengeId,omitempty"` // Package challenge
+	TestsPassed        int       `json:"testsPassed,omitempty"`
+	TestsTotal         int       `json:"testsTotal,omitempty"`
+	Leaderboard        string    `json:"leaderboard,omitempty"`  // LeaderboardMain, LeaderboardChallenge or LeaderboardPackage for rank-changed
+	PreviousRank       int       `json:"previousRank,omitempty"` // 0 if the user was unranked
+	Rank               int       `json:"rank,omitempty"`         // 0 if the user is now unranked
+	At                 time.Time `json:"at"`
+}
+
+// HubFilter selects the events a subscriber receives. Zero fields do not filter.
+type HubFilter struct {
+	ChallengeID int
+	PackageName string
+}
+
+// matches reports whether an event passes the filter
+func (f HubFilter) matches(event HubEvent) bool {
+	if f.ChallengeID != 0 && event.ChallengeID != f.ChallengeID {
+		return false
+	}
+	if f.PackageName != "" && event.PackageName != f.PackageName {
+		return false
+	}
+	return true
+}
+
+// hubSubscriber is one subscription to the event hub
+type hubSubscriber struct {
+	filter HubFilter
+	events chan HubEvent
+}
+
+// EventHub fans live events out to subscribers. A subscriber that falls behind misses events
+// rather than holding up publishers.
+type EventHub struct {
+	mutex       sync.Mutex
+	subscribers map[*hubSubscriber]bool
+	nextID      int64
+}
+
+// NewEventHub creates an event hub without subscribers
+func NewEventHub() *EventHub {
+	return &EventHub{
+		subscribers: make(map[*hubSubscriber]bool),
+		nextID:      1,
+	}
+}
+
+// Subscribe returns a channel of the events matching a filter, and a function that ends the
+// subscription and closes the channel
+func (h *EventHub) Subscribe(filter HubFilter) (<-chan HubEvent, func()) {
+	subscriber := &hubSubscriber{filter: filter, events: make(chan HubEvent, hubSubscriberBuffer)}
+
+	h.mutex.Lock()
+	h.subscribers[subscriber] = true
+	h.mutex.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mutex.Lock()
+			delete(h.subscribers, subscriber)
+			h.mutex.Unlock()
+			close(subscriber.events)
+		})
+	}
+	return subscriber.events, unsubscribe
+}
+
+// Publish sends an event to every subscriber whose filter it matches
+func (h *EventHub) Publish(event HubEvent) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	event.ID = h.nextID
+	h.nextID++
+	for subscriber := range h.subscribers {
+		if !subscriber.filter.matches(event) {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+			log.Printf("Warning: Dropped %s event %d for a slow subscriber", event.Type, event.ID)
+		}
+	}
+}
@@ -1,99 +1,289 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"go/parser"
+	"go/token"
 	"io/ioutil"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"web-ui/internal/models"
 )
 
 // ExecutionService handles code execution and testing
-type ExecutionService struct{}
+type ExecutionService struct {
+	sandbox        Sandbox
+	modules        *ModuleCache
+	results        *resultCache
+	references     *referenceCache // Reference benchmark results of challenge templates
+	buildCacheDir  string          // Root of the per-challenge GOCACHE directories
+	goVersion      string
+	defaultTimeout time.Duration
+	queue          *executionQueue
+}
+
+// defaultExecutionTimeoutSeconds bounds the wall-clock time of a test run
+const defaultExecutionTimeoutSeconds = 60
+
+// buildCacheWarmers bounds how many challenges are compiled at once while warming build caches
+const buildCacheWarmers = 2
+
+// defaultExecutionQueueSize bounds how many runs may wait for a worker
+const defaultExecutionQueueSize = 100
 
 // NewExecutionService creates a new execution service
-func NewExecutionService() *ExecutionService {
-	return &ExecutionService{}
+func NewExecutionService() (*ExecutionService, error) {
+	sandbox, err := NewSandboxFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Code execution sandbox: %s", sandbox.Name())
+
+	timeoutSeconds := getIntFromEnv("EXECUTION_TIMEOUT_SECONDS", defaultExecutionTimeoutSeconds)
+
+	// Each worker runs one go test at a time, so default to half the cores
+	workers := getIntFromEnv("EXECUTION_WORKERS", runtime.NumCPU()/2)
+	if workers < 1 {
+		workers = 1
+	}
+	queueSize := getIntFromEnv("EXECUTION_QUEUE_SIZE", defaultExecutionQueueSize)
+	log.Printf("Code execution workers: %d (queue size %d)", workers, queueSize)
+
+	modules := NewModuleCacheFromEnv()
+	log.Printf("Module cache: %s", modules.Dir())
+
+	resultCacheSize := getNonNegativeIntFromEnv("RESULT_CACHE_SIZE", defaultResultCacheSize)
+	buildCacheDir := buildCacheDirFromEnv()
+	log.Printf("Build cache: %s (result cache size %d)", buildCacheDir, resultCacheSize)
+
+	es := &ExecutionService{
+		sandbox:        sandbox,
+		modules:        modules,
+		results:        newResultCache(resultCacheSize),
+		references:     newReferenceCache(),
+		buildCacheDir:  buildCacheDir,
+		goVersion:      goEnvValue("GOVERSION"),
+		defaultTimeout: time.Duration(timeoutSeconds) * time.Second,
+	}
+	es.queue = newExecutionQueue(workers, queueSize, es.runCode)
+	return es, nil
 }
 
+// Execution statuses reported in ExecutionResult
+const (
+	ExecutionStatusPassed   = "passed"
+	ExecutionStatusFailed   = "failed"
+	ExecutionStatusError    = "error"
+	ExecutionStatusKilled   = "killed"
+	ExecutionStatusTimeout  = "timeout"
+	ExecutionStatusCanceled = "canceled"
+)
+
 // ExecutionResult represents the result of code execution
 type ExecutionResult struct {
-	Passed      bool   `json:"passed"`
-	Output      string `json:"output"`
-	ExecutionMs int64  `json:"executionMs"`
+	Passed      bool                    `json:"passed"`
+	Status      string                  `json:"status"` // One of the ExecutionStatus values
+	Output      string                  `json:"output"`
+	ExecutionMs int64                   `json:"executionMs"`
+	Killed      bool                    `json:"killed"`               // Whether the sandbox terminated the run
+	KillReason  string                  `json:"killReason,omitempty"` // Which limit the run exceeded
+	Tests       []*models.TestCase      `json:"tests"`                // Per-test results, with subtests nested
+	Summary     models.TestSummary      `json:"summary"`              // Leaf test counts
+	Cached      bool                    `json:"cached"`               // Whether the result was reused from an identical earlier run
+	Diagnostics []models.Diagnostic     `json:"diagnostics"`          // Findings of the requested analyses, separate from the tests
+	Benchmarks  *models.BenchmarkReport `json:"benchmarks,omitempty"` // Benchmark results, when benchmark mode was requested
+	Coverage    *models.CoverageReport  `json:"coverage,omitempty"`   // Coverage of the submission file, when requested
 }
 
-// RunCode executes the provided code against a challenge's tests
-func (es *ExecutionService) RunCode(code string, challenge *models.Challenge) ExecutionResult {
-	start := time.Now()
+// errorResult builds the result for a run that failed before tests could execute
+func errorResult(ctx context.Context, output string) ExecutionResult {
+	status := ExecutionStatusError
+	if errors.Is(ctx.Err(), context.Canceled) {
+		status = ExecutionStatusCanceled
+	}
+	return ExecutionResult{
+		Passed: false,
+		Status: status,
+		Output: output,
+	}
+}
 
-	// Create temporary directory for execution
-	tempDir, err := ioutil.TempDir("", "challenge-exec")
-	if err != nil {
-		return ExecutionResult{
-			Passed: false,
-			Output: fmt.Sprintf("Failed to create temporary directory: %v", err),
-		}
+// timeoutFor returns the wall-clock budget for a challenge's test run
+func (es *ExecutionService) timeoutFor(challenge *models.Challenge) time.Duration {
+	if challenge.TimeoutSeconds > 0 {
+		return time.Duration(challenge.TimeoutSeconds) * time.Second
 	}
-	defer os.RemoveAll(tempDir)
+	return es.defaultTimeout
+}
 
-	// Write the submitted code to temporary file
-	codePath := filepath.Join(tempDir, "solution-template.go")
-	err = ioutil.WriteFile(codePath, []byte(code), 0644)
-	if err != nil {
-		return ExecutionResult{
-			Passed: false,
-			Output: fmt.Sprintf("Failed to write code file: %v", err),
-		}
+// WarmModuleCache resolves the dependencies of every challenge under root into the local module cache,
+// so runs can build with GOPROXY=off
+func (es *ExecutionService) WarmModuleCache(ctx context.Context, root string) error {
+	return es.modules.Warm(ctx, FindModuleDirs(root))
+}
+
+// RunCode queues the provided code against a challenge's tests and waits for the result.
+// Cancelling ctx removes the job from the queue or stops it if it is already running.
+func (es *ExecutionService) RunCode(ctx context.Context, code string, challenge *models.Challenge, options RunOptions) ExecutionResult {
+	if result, ok := es.cachedResult(code, challenge, options); ok {
+		return result
 	}
 
-	// Write the test file to temporary directory
-	testPath := filepath.Join(tempDir, "solution_test.go")
-	err = ioutil.WriteFile(testPath, []byte(challenge.TestFile), 0644)
+	job, err := es.queue.submit(ctx, "", code, challenge, options)
 	if err != nil {
-		return ExecutionResult{
-			Passed: false,
-			Output: fmt.Sprintf("Failed to write test file: %v", err),
-		}
+		return errorResult(ctx, err.Error())
+	}
+
+	select {
+	case <-job.done:
+	case <-ctx.Done():
+		es.queue.cancelJob(job.id)
+		<-job.done
 	}
 
-	// Initialize Go module
-	err = es.initGoModule(tempDir, challenge.ID)
+	return *job.result
+}
+
+// SubmitJob queues a run for username, empty if nobody is signed in, without waiting for it.
+// Poll GetJob for its progress and result.
+func (es *ExecutionService) SubmitJob(username, code string, challenge *models.Challenge, options RunOptions) (JobStatus, error) {
+	// Unchanged code finishes immediately without waiting for a worker
+	if result, ok := es.cachedResult(code, challenge, options); ok {
+		return es.queue.submitFinished(username, code, challenge, options, result)
+	}
+
+	// Asynchronous jobs outlive the HTTP request that created them
+	job, err := es.queue.submit(context.Background(), username, code, challenge, options)
 	if err != nil {
-		return ExecutionResult{
-			Passed: false,
-			Output: fmt.Sprintf("Failed to initialize Go module: %v", err),
-		}
+		return JobStatus{}, err
+	}
+
+	status, _ := es.queue.status(job.id)
+	return status, nil
+}
+
+// GetJob returns the current state of a queued, running or recently finished job
+func (es *ExecutionService) GetJob(id string) (JobStatus, bool) {
+	return es.queue.status(id)
+}
+
+// WaitJob blocks until a job has finished or ctx is done and returns its status
+func (es *ExecutionService) WaitJob(ctx context.Context, id string) (JobStatus, bool) {
+	return es.queue.wait(ctx, id)
+}
+
+// CancelJob stops a queued or running job
+func (es *ExecutionService) CancelJob(id string) bool {
+	return es.queue.cancelJob(id)
+}
+
+// GetQueueStats returns the workers, capacity and active jobs of the execution queue
+func (es *ExecutionService) GetQueueStats() QueueStats {
+	return es.queue.stats()
+}
+
+// cachedResult returns the result of an earlier identical run
+func (es *ExecutionService) cachedResult(code string, challenge *models.Challenge, options RunOptions) (ExecutionResult, bool) {
+	result, ok := es.results.get(resultKey(challenge.TestFile, code, es.goVersion, options))
+	if !ok {
+		return ExecutionResult{}, false
+	}
+	result.Cached = true
+	return result, true
+}
+
+// ClearCaches drops the cached run and reference benchmark results. The build caches stay, as they only speed up builds.
+func (es *ExecutionService) ClearCaches() {
+	es.results.clear()
+	es.references.clear()
+}
+
+// runCode executes the provided code against a challenge's tests on a queue worker.
+// Cancelling ctx stops the run; the test step is additionally bounded by the challenge timeout.
+func (es *ExecutionService) runCode(ctx context.Context, code string, challenge *models.Challenge, options RunOptions) ExecutionResult {
+	if options.warm {
+		return es.warmBuildCache(ctx, challenge)
+	}
+
+	// An identical run may have finished while this one was queued
+	if result, ok := es.cachedResult(code, challenge, options); ok {
+		return result
+	}
+
+	result := es.executeCode(ctx, code, challenge, options)
+	if isCacheableResult(result) {
+		es.results.put(resultKey(challenge.TestFile, code, es.goVersion, options), result)
 	}
+	return result
+}
+
+// executeCode builds and tests the code in a fresh temporary module
+func (es *ExecutionService) executeCode(ctx context.Context, code string, challenge *models.Challenge, options RunOptions) ExecutionResult {
+	start := time.Now()
 
-	// Automatically detect and install dependencies based on imports
-	err = es.installDependencies(tempDir, code, challenge.ID)
+	tempDir, err := es.prepareRunDir(ctx, code, challenge)
 	if err != nil {
-		return ExecutionResult{
-			Passed: false,
-			Output: fmt.Sprintf("Failed to install dependencies: %v", err),
-		}
+		return errorResult(ctx, err.Error())
 	}
+	defer os.RemoveAll(tempDir)
 
-	// Run tests
-	cmd := exec.Command("go", "test", "-v")
-	cmd.Dir = tempDir
+	// Run tests inside the sandbox with the challenge's wall-clock budget
+	testCtx, cancel := context.WithTimeout(ctx, es.timeoutFor(challenge))
+	defer cancel()
 
+	// The tests are compiled first and the binary then runs as the job itself, so its exit status tells
+	// whether the sandbox killed it. Only running out of memory shows in its output instead.
+	job := es.sandboxJob(tempDir, challenge)
+	cmd := es.buildTestCommand(testCtx, job, options)
 	output, err := cmd.CombinedOutput()
+	kill := es.sandbox.Kill(cmd.ProcessState, output)
+	if err == nil {
+		cmd = es.runTestCommand(testCtx, job, options)
+		output, err = cmd.CombinedOutput()
+		kill = es.sandbox.Kill(cmd.ProcessState, output)
+		output = testEvents(ctx, output)
+	}
 	executionTime := time.Since(start).Milliseconds()
-	outputStr := string(output)
+
+	// Turn the event stream into a test tree and a readable log
+	report := ParseTestJSON(string(output))
+	outputStr := report.Output
 
 	result := ExecutionResult{
+		Status:      ExecutionStatusFailed,
 		Output:      outputStr,
 		ExecutionMs: executionTime,
+		Tests:       report.Tests,
+		Summary:     report.Summary,
 	}
 
 	if err == nil {
 		result.Passed = true
+		result.Status = ExecutionStatusPassed
+	} else if errors.Is(ctx.Err(), context.Canceled) {
+		// The caller went away, so nobody is waiting for the result
+		result.Status = ExecutionStatusCanceled
+	} else if errors.Is(testCtx.Err(), context.DeadlineExceeded) {
+		// Keep the partial output so the user can see where the run got stuck
+		result.Status = ExecutionStatusTimeout
+		result.Output = fmt.Sprintf("%s\nTest run exceeded the %s time limit", outputStr, es.timeoutFor(challenge))
+	} else if kill.Killed {
+		// The sandbox stopped the run because it exceeded its resource budget
+		result.Passed = false
+		result.Status = ExecutionStatusKilled
+		result.Killed = true
+		result.KillReason = kill.Reason
 	} else {
 		// Check if tests ran but failed (this is the key logic!)
 		if _, ok := err.(*exec.ExitError); ok {
@@ -102,247 +292,297 @@ func (es *ExecutionService) RunCode(code string, challenge *models.Challenge) Ex
 		} else {
 			// Command couldn't be run - this is a real error
 			result.Passed = false
+			result.Status = ExecutionStatusError
 			result.Output = fmt.Sprintf("Failed to run tests: %v\n%s", err, outputStr)
 		}
 	}
 
+	// Race reports come from the test run itself; the other analyses run once the tests are done
+	result.Diagnostics = []models.Diagnostic{}
+	if options.Has(AnalysisRace) {
+		result.Diagnostics = append(result.Diagnostics, parseRaceReports(outputStr)...)
+	}
+	if result.Status == ExecutionStatusPassed || result.Status == ExecutionStatusFailed {
+		result.Diagnostics = append(result.Diagnostics, es.runAnalyses(ctx, tempDir, challenge, options)...)
+	}
+
+	if options.Coverage && (result.Status == ExecutionStatusPassed || result.Status == ExecutionStatusFailed) {
+		result.Coverage = readCoverage(tempDir, code)
+	}
+
+	// Benchmarks only mean something for code that compiles and passes its tests
+	if options.Benchmark != nil && result.Status == ExecutionStatusPassed {
+		result.Benchmarks = es.runBenchmarks(ctx, tempDir, challenge, options.Benchmark)
+	}
 	return result
 }
 
-// initGoModule initializes a Go module in the temporary directory
-func (es *ExecutionService) initGoModule(tempDir string, challengeID int) error {
-	// Initialize go.mod
-	cmd := exec.Command("go", "mod", "init", fmt.Sprintf("challenge-%d", challengeID))
-	cmd.Dir = tempDir
-	return cmd.Run()
-}
+// prepareRunDir creates a temporary module holding the code, the challenge's tests and their dependencies
+func (es *ExecutionService) prepareRunDir(ctx context.Context, code string, challenge *models.Challenge) (string, error) {
+	tempDir, err := ioutil.TempDir("", "challenge-exec")
+	if err != nil {
+		return "", fmt.Errorf("Failed to create temporary directory: %v", err)
+	}
 
-// installDependencies installs dependencies for the given challenge
-func (es *ExecutionService) installDependencies(tempDir string, code string, challengeID int) error {
-	// Detect imports from the code
-	requiredPackages := es.detectRequiredPackages(code, challengeID)
+	// Write the submitted code to temporary file
+	codePath := filepath.Join(tempDir, "solution-template.go")
+	if err := ioutil.WriteFile(codePath, []byte(code), 0644); err != nil {
+		os.RemoveAll(tempDir)
+		return "", fmt.Errorf("Failed to write code file: %v", err)
+	}
 
-	if len(requiredPackages) == 0 {
-		return nil // No external dependencies needed
+	// Write the test file to temporary directory
+	testPath := filepath.Join(tempDir, "solution_test.go")
+	if err := ioutil.WriteFile(testPath, []byte(challenge.TestFile), 0644); err != nil {
+		os.RemoveAll(tempDir)
+		return "", fmt.Errorf("Failed to write test file: %v", err)
 	}
 
-	// Install each required package
-	for _, pkg := range requiredPackages {
-		fmt.Printf("Installing dependency: %s\n", pkg)
-		cmd := exec.Command("go", "get", pkg)
-		cmd.Dir = tempDir
+	// Start from the challenge's own go.mod and go.sum so versions match run_tests.sh and CI
+	if err := es.prepareModule(ctx, tempDir, challenge); err != nil {
+		os.RemoveAll(tempDir)
+		return "", fmt.Errorf("Failed to initialize Go module: %v", err)
+	}
 
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return fmt.Errorf("failed to install package %s: %v\nOutput: %s", pkg, err, string(output))
-		}
+	// Resolve any imports the challenge module does not already provide
+	if err := es.installDependencies(ctx, tempDir, code, challenge.TestFile); err != nil {
+		os.RemoveAll(tempDir)
+		return "", fmt.Errorf("Failed to install dependencies: %v", err)
 	}
 
-	// Run go mod tidy to clean up dependencies
-	tidyCmd := exec.Command("go", "mod", "tidy")
-	tidyCmd.Dir = tempDir
-	tidyCmd.Run() // Ignore errors for tidy
+	return tempDir, nil
+}
 
-	return nil
+// testBinaryName is the compiled tests of a run, inside its run directory
+const testBinaryName = "challenge.test"
+
+// sandboxJob returns the sandbox job for a run directory of a challenge
+func (es *ExecutionService) sandboxJob(tempDir string, challenge *models.Challenge) SandboxJob {
+	return SandboxJob{
+		Dir:        tempDir,
+		ReadOnly:   []string{es.modules.Dir()},
+		BuildCache: es.buildCacheFor(challenge),
+	}
 }
 
-// detectRequiredPackages analyzes the code to detect required external packages
-func (es *ExecutionService) detectRequiredPackages(code string, challengeID int) []string {
-	packages := make(map[string]bool)
-
-	// Common package mappings for known dependencies
-	knownPackages := map[string][]string{
-		"github.com/mattn/go-sqlite3": {"github.com/mattn/go-sqlite3"},
-		"database/sql":                {"github.com/mattn/go-sqlite3"}, // SQLite driver needed for database/sql
-		"google.golang.org/grpc":      {"google.golang.org/grpc", "google.golang.org/grpc/codes", "google.golang.org/grpc/status"},
-		"github.com/google/uuid":      {"github.com/google/uuid"},
-		"github.com/gorilla/mux":      {"github.com/gorilla/mux"},
-		"github.com/gin-gonic/gin":    {"github.com/gin-gonic/gin"},
-		"github.com/stretchr/testify": {"github.com/stretchr/testify"},
-		"go.mongodb.org/mongo-driver": {"go.mongodb.org/mongo-driver/mongo", "go.mongodb.org/mongo-driver/bson"},
-		"github.com/redis/go-redis":   {"github.com/redis/go-redis/v9"},
-		"gorm.io/gorm":                {"gorm.io/gorm", "gorm.io/driver/sqlite"},
-	}
-
-	// Challenge-specific package requirements
-	challengePackages := map[int][]string{
-		13: {"github.com/mattn/go-sqlite3"},                                                             // SQL Database Operations
-		14: {"google.golang.org/grpc", "google.golang.org/grpc/codes", "google.golang.org/grpc/status"}, // gRPC
-		9:  {"github.com/google/uuid"},                                                                  // RESTful API with UUID
-	}
-
-	// Add challenge-specific packages
-	if challengePkgs, exists := challengePackages[challengeID]; exists {
-		for _, pkg := range challengePkgs {
-			packages[pkg] = true
-		}
+// buildTestCommand builds the sandboxed command that compiles a run's tests into testBinaryName.
+// Like go test, it also runs go vet's default checks.
+func (es *ExecutionService) buildTestCommand(ctx context.Context, job SandboxJob, options RunOptions) *exec.Cmd {
+	// -trimpath keeps the temporary directory out of the build cache keys, so a challenge's
+	// GOCACHE stays warm across runs and edited code rebuilds incrementally
+	args := []string{"test", "-c", "-o", testBinaryName, "-trimpath"}
+	if options.Has(AnalysisRace) {
+		args = append(args, "-race")
+	}
+	if options.Coverage {
+		args = append(args, "-cover")
 	}
 
-	// Scan code for import statements
-	lines := strings.Split(code, "\n")
-	inImportBlock := false
+	cmd := es.sandbox.Command(ctx, job, "go", args...)
+	cmd.Env = append(cmd.Env, es.modules.RunEnv()...)
+	return cmd
+}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+// runTestCommand builds the sandboxed command that runs the compiled tests, printing the framed
+// output that test2json turns into go test -json events
+func (es *ExecutionService) runTestCommand(ctx context.Context, job SandboxJob, options RunOptions) *exec.Cmd {
+	args := []string{"-test.v=test2json", "-test.paniconexit0"}
+	if options.Coverage {
+		args = append(args, "-test.coverprofile="+coverageProfileName)
+	}
+	return es.sandbox.Command(ctx, job, "./"+testBinaryName, args...)
+}
 
-		// Handle import blocks
-		if strings.HasPrefix(line, "import (") {
-			inImportBlock = true
-			continue
-		}
-		if inImportBlock && line == ")" {
-			inImportBlock = false
-			continue
-		}
+// testEvents converts a test binary's output into go test -json events, or returns it unchanged
+// if test2json fails, since it is still readable as a plain log
+func testEvents(ctx context.Context, output []byte) []byte {
+	cmd := exec.CommandContext(ctx, "go", "tool", "test2json", "-t")
+	cmd.Stdin = bytes.NewReader(output)
+	events, err := cmd.Output()
+	if err != nil {
+		return output
+	}
+	return events
+}
 
-		// Handle single import statements
-		if strings.HasPrefix(line, "import ") || inImportBlock {
-			// Extract import path
-			importPath := es.extractImportPath(line)
-			if importPath != "" {
-				// Check if this import requires external packages
-				if deps, exists := knownPackages[importPath]; exists {
-					for _, dep := range deps {
-						packages[dep] = true
-					}
-				} else if es.isExternalPackage(importPath) {
-					packages[importPath] = true
+// buildCacheFor returns the GOCACHE directory shared by all runs of a challenge
+func (es *ExecutionService) buildCacheFor(challenge *models.Challenge) string {
+	return filepath.Join(es.buildCacheDir, buildCacheName(challenge.Dir, challenge.ID))
+}
+
+// WarmBuildCaches runs each challenge's tests against its starter template, so the first
+// run of a challenge only rebuilds the submission instead of the standard library and dependencies.
+// The runs wait their turn in the execution queue like any other, a few at a time.
+func (es *ExecutionService) WarmBuildCaches(ctx context.Context, challenges []*models.Challenge) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, buildCacheWarmers)
+
+	for _, challenge := range challenges {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(challenge *models.Challenge) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			job, err := es.queue.submit(ctx, "", "", challenge, RunOptions{warm: true})
+			if err == nil {
+				<-job.done
+				if job.result.Status == ExecutionStatusError {
+					err = errors.New(job.result.Output)
 				}
 			}
-		}
+			if err != nil {
+				log.Printf("Warning: Could not warm build cache for %s: %v", buildCacheName(challenge.Dir, challenge.ID), err)
+			}
+		}(challenge)
 	}
+	wg.Wait()
+}
 
-	// Convert map to slice
-	result := make([]string, 0, len(packages))
-	for pkg := range packages {
-		result = append(result, pkg)
+// warmBuildCache runs one challenge's tests against its starter template on a queue worker.
+// Only these runs write the challenge's shared build cache.
+func (es *ExecutionService) warmBuildCache(ctx context.Context, challenge *models.Challenge) ExecutionResult {
+	template, err := es.templateFor(challenge)
+	if err != nil {
+		return errorResult(ctx, err.Error())
 	}
 
-	return result
-}
-
-// extractImportPath extracts the import path from an import line
-func (es *ExecutionService) extractImportPath(line string) string {
-	// Remove 'import' keyword
-	line = strings.TrimPrefix(line, "import")
-	line = strings.TrimSpace(line)
-
-	// Remove quotes and comments
-	if strings.Contains(line, "\"") {
-		parts := strings.Split(line, "\"")
-		if len(parts) >= 2 {
-			return parts[1]
-		}
+	tempDir, err := es.prepareRunDir(ctx, template, challenge)
+	if err != nil {
+		return errorResult(ctx, err.Error())
 	}
+	defer os.RemoveAll(tempDir)
 
-	return ""
-}
+	// Run the tests exactly as a submission would, since some tests build code themselves.
+	// Templates usually fail or do not compile yet; the dependencies are cached regardless.
+	testCtx, cancel := context.WithTimeout(ctx, es.timeoutFor(challenge))
+	defer cancel()
 
-// isExternalPackage determines if an import path is an external package
-func (es *ExecutionService) isExternalPackage(importPath string) bool {
-	// Standard library packages (don't need go get)
-	standardLibs := map[string]bool{
-		"fmt": true, "strings": true, "strconv": true, "time": true,
-		"os": true, "io": true, "bufio": true, "bytes": true,
-		"encoding/json": true, "encoding/xml": true, "encoding/csv": true,
-		"net/http": true, "net/url": true, "net": true,
-		"database/sql": true, "context": true, "sync": true,
-		"regexp": true, "sort": true, "math": true, "crypto": true,
-		"log": true, "errors": true, "flag": true, "path": true,
-		"path/filepath": true, "testing": true, "reflect": true,
+	job := es.sandboxJob(tempDir, challenge)
+	job.WriteBuildCache = true
+	if err := es.buildTestCommand(testCtx, job, RunOptions{}).Run(); err == nil {
+		es.runTestCommand(testCtx, job, RunOptions{}).Run()
 	}
+	return ExecutionResult{Status: ExecutionStatusPassed, Passed: true}
+}
 
-	// Check if it's a standard library package
-	if standardLibs[importPath] {
-		return false
+// templateFor returns the starter template of a challenge, as shipped in its directory
+func (es *ExecutionService) templateFor(challenge *models.Challenge) (string, error) {
+	if challenge.Template != "" || challenge.Dir == "" {
+		return challenge.Template, nil
 	}
-
-	// Check if it starts with standard library prefixes
-	standardPrefixes := []string{
-		"crypto/", "encoding/", "net/", "os/", "path/",
-		"text/", "html/", "image/", "go/", "runtime/",
-		"unicode/", "mime/", "archive/", "compress/",
-		"container/", "debug/", "index/", "internal/",
+	content, err := ioutil.ReadFile(filepath.Join(challenge.Dir, submissionFileName))
+	if err != nil {
+		return "", err
 	}
+	return string(content), nil
+}
 
-	for _, prefix := range standardPrefixes {
-		if strings.HasPrefix(importPath, prefix) {
-			return false
+// prepareModule copies the challenge's go.mod and go.sum into the temporary directory,
+// or initializes a fresh module for challenges that do not ship one
+func (es *ExecutionService) prepareModule(ctx context.Context, tempDir string, challenge *models.Challenge) error {
+	if challenge.Dir != "" {
+		goMod, err := ioutil.ReadFile(filepath.Join(challenge.Dir, "go.mod"))
+		if err == nil {
+			if err := ioutil.WriteFile(filepath.Join(tempDir, "go.mod"), goMod, 0644); err != nil {
+				return err
+			}
+
+			// go.sum is absent for challenges without dependencies
+			if goSum, err := ioutil.ReadFile(filepath.Join(challenge.Dir, "go.sum")); err == nil {
+				if err := ioutil.WriteFile(filepath.Join(tempDir, "go.sum"), goSum, 0644); err != nil {
+					return err
+				}
+			}
+			return nil
 		}
 	}
 
-	// If it contains a dot, it's likely an external package
-	return strings.Contains(importPath, ".")
-}
-
-// SaveSubmissionRequest represents a request to save a submission to filesystem
-type SaveSubmissionRequest struct {
-	Username    string `json:"username"`
-	ChallengeID int    `json:"challengeId"`
-	Code        string `json:"code"`
+	cmd := es.modules.goCommand(ctx, tempDir, es.modules.ResolveEnv(), "mod", "init", fmt.Sprintf("challenge-%d", challenge.ID))
+	return cmd.Run()
 }
 
-// SaveSubmissionResponse represents the response from saving a submission
-type SaveSubmissionResponse struct {
-	Success     bool     `json:"success"`
-	Message     string   `json:"message"`
-	FilePath    string   `json:"filePath"`
-	GitCommands []string `json:"gitCommands"`
-}
+// installDependencies adds the modules for imports that the run's go.mod does not require yet
+func (es *ExecutionService) installDependencies(ctx context.Context, tempDir string, sources ...string) error {
+	goMod, err := es.modules.readGoMod(ctx, tempDir)
+	if err != nil {
+		return fmt.Errorf("failed to read go.mod: %v", err)
+	}
 
-// SaveSubmissionToFilesystem saves a user's submission to the filesystem
-func (es *ExecutionService) SaveSubmissionToFilesystem(request SaveSubmissionRequest) SaveSubmissionResponse {
-	// Get working directory for correct relative paths
-	workDir, _ := os.Getwd()
+	provided := []string{goMod.Module.Path}
+	for _, require := range goMod.Require {
+		provided = append(provided, require.Path)
+	}
 
-	// Try different path approaches to handle potential path issues
-	var submissionDir string
-	var fileSaved bool
+	missing := make(map[string]bool)
+	for _, source := range sources {
+		for _, importPath := range parseImports(source) {
+			if !isStandardImport(importPath) && !isProvidedImport(importPath, provided) {
+				missing[importPath] = true
+			}
+		}
+	}
 
-	// Try multiple path options to ensure it works in different environments
-	pathOptions := []string{
-		// Option 1: From web-ui directory (standard case)
-		filepath.Join("..", fmt.Sprintf("challenge-%d", request.ChallengeID), "submissions", request.Username),
-		// Option 2: From root workspace
-		filepath.Join(fmt.Sprintf("challenge-%d", request.ChallengeID), "submissions", request.Username),
-		// Option 3: Absolute path from detected workspace root
-		filepath.Join(workDir, "..", fmt.Sprintf("challenge-%d", request.ChallengeID), "submissions", request.Username),
+	if len(missing) == 0 {
+		return nil // The challenge module already covers every import
 	}
 
-	for _, dirPath := range pathOptions {
-		err := os.MkdirAll(dirPath, 0755)
-		if err != nil {
-			continue
+	// Install each missing package from the module cache, together with the versions the challenges pin
+	for pkg := range missing {
+		targets := []string{pkg}
+		if requirements, ok := es.modules.Requirements(pkg); ok {
+			targets = requirements
 		}
 
-		solutionFile := filepath.Join(dirPath, "solution-template.go")
-		err = ioutil.WriteFile(solutionFile, []byte(request.Code), 0644)
+		log.Printf("Installing dependency: %s", pkg)
+		cmd := es.modules.goCommand(ctx, tempDir, es.modules.ResolveEnv(), append([]string{"get"}, targets...)...)
+
+		output, err := cmd.CombinedOutput()
 		if err != nil {
-			continue
+			return fmt.Errorf("failed to install package %s: %v\nOutput: %s", pkg, err, string(output))
 		}
+	}
+
+	// Run go mod tidy to clean up dependencies
+	tidyCmd := es.modules.goCommand(ctx, tempDir, es.modules.ResolveEnv(), "mod", "tidy")
+	tidyCmd.Run() // Ignore errors for tidy
 
-		submissionDir = dirPath
-		fileSaved = true
-		break
+	return nil
+}
+
+// parseImports returns the import paths of a Go source file.
+// Unparseable code yields no imports; the compiler reports the syntax error during the test run.
+func parseImports(source string) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), "", source, parser.ImportsOnly)
+	if err != nil {
+		return nil
 	}
 
-	if !fileSaved {
-		return SaveSubmissionResponse{
-			Success: false,
-			Message: "Failed to save solution to any available path",
+	imports := make([]string, 0, len(file.Imports))
+	for _, spec := range file.Imports {
+		if importPath, err := strconv.Unquote(spec.Path.Value); err == nil {
+			imports = append(imports, importPath)
 		}
 	}
+	return imports
+}
 
-	// Return success response with git commands
-	return SaveSubmissionResponse{
-		Success:  true,
-		Message:  "Solution saved to filesystem",
-		FilePath: filepath.Join(submissionDir, "solution-template.go"),
-		GitCommands: []string{
-			"cd " + filepath.Join(workDir, ".."),
-			fmt.Sprintf("git add %s", filepath.Join(fmt.Sprintf("challenge-%d", request.ChallengeID), "submissions", request.Username, "solution-template.go")),
-			fmt.Sprintf("git commit -m \"Add solution for Challenge %d\"", request.ChallengeID),
-			"git push origin main",
-		},
+// isStandardImport reports whether an import path belongs to the standard library.
+// Like the go command, it treats paths whose first element has no dot as standard.
+func isStandardImport(importPath string) bool {
+	first := importPath
+	if i := strings.Index(importPath, "/"); i >= 0 {
+		first = importPath[:i]
+	}
+	return !strings.Contains(first, ".")
+}
+
+// isProvidedImport reports whether an import path lies inside one of the given modules
+func isProvidedImport(importPath string, modules []string) bool {
+	for _, module := range modules {
+		if importPath == module || strings.HasPrefix(importPath, module+"/") {
+			return true
+		}
 	}
+	return false
 }
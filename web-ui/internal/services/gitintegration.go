@@ -0,0 +1,252 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNoSubmissionBranch is returned when a user has not committed a solution for a challenge
+var ErrNoSubmissionBranch = errors.New("no submission branch")
+
+// GitService commits saved solutions to a branch per submission in the local checkout and exports
+// those branches as patch series or bundles. It writes objects and refs directly, so the working
+// tree and the checked-out branch the server runs from are never touched.
+type GitService struct {
+	enabled bool
+	root    string
+	mutex   sync.Mutex // Serializes branch updates
+}
+
+// GitSubmission describes a submission branch after a commit
+type GitSubmission struct {
+	Branch      string `json:"branch"`
+	Commit      string `json:"commit"`
+	Base        string `json:"base"`      // Where the branch leaves the checked-out branch
+	Commits     int    `json:"commits"`   // Commits on the branch since Base
+	Unchanged   bool   `json:"unchanged"` // The solution matched the branch, so nothing was committed
+	PushCommand string `json:"pushCommand"`
+}
+
+// NewGitServiceFromEnv enables the git integration when GIT_INTEGRATION is "true" and the workspace
+// root is a git work tree. Otherwise the service reports itself disabled.
+func NewGitServiceFromEnv() *GitService {
+	gs := &GitService{root: WorkspaceRoot()}
+	if os.Getenv("GIT_INTEGRATION") != "true" {
+		return gs
+	}
+
+	if _, err := gs.git(context.Background(), nil, "", "rev-parse", "--is-inside-work-tree"); err != nil {
+		log.Printf("Warning: GIT_INTEGRATION is set but %s is not a usable git repository: %v", gs.root, err)
+		return gs
+	}
+
+	gs.enabled = true
+	log.Printf("Git integration: committing submissions to branches in %s", gs.root)
+	return gs
+}
+
+// Enabled reports whether submissions can be committed
+func (gs *GitService) Enabled() bool {
+	return gs.enabled
+}
+
+// SubmissionBranch returns the branch holding a user's solutions to a challenge, such as
+// submissions/alice/challenge-3 or submissions/alice/cobra/challenge-1-basic-cli
+func SubmissionBranch(target SubmissionTarget, username string) (string, error) {
+	if _, err := SubmissionPath(target, username); err != nil {
+		return "", err
+	}
+	if target.IsPackage() {
+		return path.Join("submissions", username, target.PackageName, target.ChallengeID), nil
+	}
+	dir, _ := target.challengeDir()
+	return path.Join("submissions", username, dir), nil
+}
+
+// CommitSubmission commits a user's solution onto their submission branch, creating the branch from
+// the checked-out commit on their first submission. Later submissions add commits, so the branch
+// carries the series of solutions.
+func (gs *GitService) CommitSubmission(ctx context.Context, target SubmissionTarget, username, code string) (GitSubmission, error) {
+	relativePath, err := SubmissionPath(target, username)
+	if err != nil {
+		return GitSubmission{}, err
+	}
+	branch, err := SubmissionBranch(target, username)
+	if err != nil {
+		return GitSubmission{}, err
+	}
+	ref := "refs/heads/" + branch
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	// Build on the branch if it exists, otherwise on the checked-out commit
+	parent, err := gs.git(ctx, nil, "", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	existed := err == nil
+	if !existed {
+		if parent, err = gs.git(ctx, nil, "", "rev-parse", "--verify", "HEAD^{commit}"); err != nil {
+			return GitSubmission{}, err
+		}
+	}
+
+	// Stage the solution in a scratch index so the real one is left alone
+	indexDir, err := os.MkdirTemp("", "submission-index")
+	if err != nil {
+		return GitSubmission{}, err
+	}
+	defer os.RemoveAll(indexDir)
+	indexEnv := []string{"GIT_INDEX_FILE=" + filepath.Join(indexDir, "index")}
+
+	blob, err := gs.git(ctx, nil, code, "hash-object", "-w", "--stdin")
+	if err != nil {
+		return GitSubmission{}, err
+	}
+	if _, err := gs.git(ctx, indexEnv, "", "read-tree", parent); err != nil {
+		return GitSubmission{}, err
+	}
+	cacheInfo := "100644," + blob + "," + filepath.ToSlash(relativePath)
+	if _, err := gs.git(ctx, indexEnv, "", "update-index", "--add", "--cacheinfo", cacheInfo); err != nil {
+		return GitSubmission{}, err
+	}
+	tree, err := gs.git(ctx, indexEnv, "", "write-tree")
+	if err != nil {
+		return GitSubmission{}, err
+	}
+
+	parentTree, err := gs.git(ctx, nil, "", "rev-parse", parent+"^{tree}")
+	if err != nil {
+		return GitSubmission{}, err
+	}
+
+	commit := parent
+	unchanged := existed && tree == parentTree
+	if !unchanged {
+		// Commits are authored by the signed-in user, with GitHub's no-reply address
+		email := username + "@users.noreply.github.com"
+		authorEnv := []string{
+			"GIT_AUTHOR_NAME=" + username, "GIT_AUTHOR_EMAIL=" + email,
+			"GIT_COMMITTER_NAME=" + username, "GIT_COMMITTER_EMAIL=" + email,
+		}
+		if commit, err = gs.git(ctx, authorEnv, "", "commit-tree", tree, "-p", parent, "-m", target.commitMessage(username)); err != nil {
+			return GitSubmission{}, err
+		}
+
+		// The old value guards against a concurrent update from outside the server
+		oldValue := ""
+		if existed {
+			oldValue = parent
+		}
+		if _, err := gs.git(ctx, nil, "", "update-ref", "-m", "submission", ref, commit, oldValue); err != nil {
+			return GitSubmission{}, err
+		}
+	}
+
+	base, commits, err := gs.branchBase(ctx, ref)
+	if err != nil {
+		return GitSubmission{}, err
+	}
+
+	return GitSubmission{
+		Branch:      branch,
+		Commit:      commit,
+		Base:        base,
+		Commits:     commits,
+		Unchanged:   unchanged,
+		PushCommand: "git push origin " + branch,
+	}, nil
+}
+
+// Patch returns a user's submission branch as a patch series in mbox format, for git am
+func (gs *GitService) Patch(ctx context.Context, target SubmissionTarget, username string) ([]byte, error) {
+	ref, base, err := gs.submissionRef(ctx, target, username)
+	if err != nil {
+		return nil, err
+	}
+	return gs.gitOutput(ctx, nil, "", "format-patch", "--stdout", base+".."+ref)
+}
+
+// Bundle returns a user's submission branch as a git bundle holding the commits since it left
+// the checked-out branch, for git fetch or git clone
+func (gs *GitService) Bundle(ctx context.Context, target SubmissionTarget, username string) ([]byte, error) {
+	ref, base, err := gs.submissionRef(ctx, target, username)
+	if err != nil {
+		return nil, err
+	}
+
+	bundleDir, err := os.MkdirTemp("", "submission-bundle")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(bundleDir)
+
+	bundlePath := filepath.Join(bundleDir, "submission.bundle")
+	if _, err := gs.git(ctx, nil, "", "bundle", "create", bundlePath, ref, "^"+base); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(bundlePath)
+}
+
+// submissionRef returns a user's submission branch ref and its base, or ErrNoSubmissionBranch
+func (gs *GitService) submissionRef(ctx context.Context, target SubmissionTarget, username string) (string, string, error) {
+	branch, err := SubmissionBranch(target, username)
+	if err != nil {
+		return "", "", err
+	}
+	ref := "refs/heads/" + branch
+
+	if _, err := gs.git(ctx, nil, "", "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return "", "", ErrNoSubmissionBranch
+	}
+	base, _, err := gs.branchBase(ctx, ref)
+	if err != nil {
+		return "", "", err
+	}
+	return ref, base, nil
+}
+
+// branchBase returns where a branch leaves the checked-out branch and how many commits it has since
+func (gs *GitService) branchBase(ctx context.Context, ref string) (string, int, error) {
+	base, err := gs.git(ctx, nil, "", "merge-base", "HEAD", ref)
+	if err != nil {
+		return "", 0, err
+	}
+	count, err := gs.git(ctx, nil, "", "rev-list", "--count", base+".."+ref)
+	if err != nil {
+		return "", 0, err
+	}
+	commits, _ := strconv.Atoi(count)
+	return base, commits, nil
+}
+
+// git runs a git command in the workspace root and returns its trimmed output
+func (gs *GitService) git(ctx context.Context, env []string, stdin string, args ...string) (string, error) {
+	output, err := gs.gitOutput(ctx, env, stdin, args...)
+	return strings.TrimSpace(string(output)), err
+}
+
+// gitOutput runs a git command in the workspace root, with extra environment variables and input
+func (gs *GitService) gitOutput(ctx context.Context, env []string, stdin string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = gs.root
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/semver"
+)
+
+// ModuleCache is a local module cache that lets code runs resolve dependencies without network access
+type ModuleCache struct {
+	dir     string              // GOMODCACHE used by every run
+	offline bool                // Never contact a module proxy, even while warming
+	pins    map[string][]string // Module path to the full requirement list of the challenge that pins it
+	mutex   sync.RWMutex
+}
+
+// moduleCacheWarmers bounds how many challenge modules are resolved at once
+const moduleCacheWarmers = 4
+
+// NewModuleCacheFromEnv creates the module cache configured through environment variables
+func NewModuleCacheFromEnv() *ModuleCache {
+	dir := os.Getenv("MODULE_CACHE_DIR")
+	if dir == "" {
+		cacheRoot, err := os.UserCacheDir()
+		if err != nil {
+			cacheRoot = os.TempDir()
+		}
+		dir = filepath.Join(cacheRoot, "go-interview-practice", "mod")
+	}
+
+	if absDir, err := filepath.Abs(dir); err == nil {
+		dir = absDir
+	}
+
+	return &ModuleCache{
+		dir:     dir,
+		offline: strings.ToLower(os.Getenv("MODULE_CACHE_OFFLINE")) == "true",
+		pins:    make(map[string][]string),
+	}
+}
+
+// Dir returns the GOMODCACHE directory
+func (mc *ModuleCache) Dir() string {
+	return mc.dir
+}
+
+// ResolveEnv returns the environment for resolving modules. The cache itself
+// serves as a file:// GOPROXY, so version queries work without network access.
+func (mc *ModuleCache) ResolveEnv() []string {
+	return []string{
+		"GOMODCACHE=" + mc.dir,
+		"GOPROXY=" + mc.proxyURL(),
+		"GOSUMDB=off",
+		"GOFLAGS=-mod=mod",
+	}
+}
+
+// RunEnv returns the environment for building and testing against the cache only
+func (mc *ModuleCache) RunEnv() []string {
+	return []string{
+		"GOMODCACHE=" + mc.dir,
+		"GOPROXY=off",
+		"GOSUMDB=off",
+		"GOFLAGS=-mod=mod",
+	}
+}
+
+// proxyURL returns the cache's download directory as a GOPROXY URL
+func (mc *ModuleCache) proxyURL() string {
+	return "file://" + filepath.ToSlash(filepath.Join(mc.dir, "cache", "download"))
+}
+
+// Requirements returns the module@version list a challenge resolved alongside the module providing
+// an import path. Getting the whole list keeps every transitive dependency at a cached version.
+func (mc *ModuleCache) Requirements(importPath string) ([]string, bool) {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	// The providing module is the longest pinned prefix of the import path
+	for candidate := importPath; candidate != "." && candidate != ""; candidate = pathDir(candidate) {
+		if requirements, ok := mc.pins[candidate]; ok {
+			return requirements, true
+		}
+	}
+	return nil, false
+}
+
+// pathDir returns the parent of a slash separated import path
+func pathDir(importPath string) string {
+	if i := strings.LastIndex(importPath, "/"); i >= 0 {
+		return importPath[:i]
+	}
+	return ""
+}
+
+// FindModuleDirs lists every challenge directory with its own go.mod
+func FindModuleDirs(root string) []string {
+	var dirs []string
+
+	patterns := []string{
+		filepath.Join(root, "challenge-*", "go.mod"),
+		filepath.Join(root, "packages", "*", "challenge-*", "go.mod"),
+	}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			dirs = append(dirs, filepath.Dir(match))
+		}
+	}
+
+	return dirs
+}
+
+// Warm resolves the dependencies of every given challenge module into the cache
+func (mc *ModuleCache) Warm(ctx context.Context, moduleDirs []string) error {
+	if err := os.MkdirAll(mc.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create module cache directory: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var failedMutex sync.Mutex
+	var failed []string
+	slots := make(chan struct{}, moduleCacheWarmers)
+
+	for _, dir := range moduleDirs {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(dir string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := mc.warmModule(ctx, dir); err != nil {
+				log.Printf("Warning: Could not cache dependencies for %s: %v", dir, err)
+				failedMutex.Lock()
+				failed = append(failed, dir)
+				failedMutex.Unlock()
+			}
+		}(dir)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d challenge modules could not be cached", len(failed), len(moduleDirs))
+	}
+	return nil
+}
+
+// warmModule resolves one challenge module in a scratch copy so the repository is never modified
+func (mc *ModuleCache) warmModule(ctx context.Context, dir string) error {
+	scratchDir, err := ioutil.TempDir("", "modcache-warm")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratchDir)
+
+	// go.mod and go.sum pin the versions; the sources let tidy find anything they miss
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	files = append(files, filepath.Join(dir, "go.mod"), filepath.Join(dir, "go.sum"))
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join(scratchDir, filepath.Base(file)), content, 0644); err != nil {
+			return err
+		}
+	}
+
+	// Try the cache alone first so restarts and air-gapped machines never touch the network
+	err = mc.resolveModule(ctx, scratchDir, mc.ResolveEnv())
+	if err != nil && !mc.offline {
+		err = mc.resolveModule(ctx, scratchDir, []string{"GOMODCACHE=" + mc.dir, "GOFLAGS=-mod=mod"})
+	}
+	if err != nil {
+		return err
+	}
+
+	return mc.recordPins(ctx, scratchDir)
+}
+
+// resolveModule tidies a module and downloads its whole build list
+func (mc *ModuleCache) resolveModule(ctx context.Context, moduleDir string, env []string) error {
+	if output, err := mc.goCommand(ctx, moduleDir, env, "mod", "tidy").CombinedOutput(); err != nil {
+		return fmt.Errorf("%v\n%s", err, output)
+	}
+
+	// tidy skips the .info files that version queries like `go get module@version` read
+	if output, err := mc.goCommand(ctx, moduleDir, env, "mod", "download", "all").CombinedOutput(); err != nil {
+		return fmt.Errorf("%v\n%s", err, output)
+	}
+	return nil
+}
+
+// goModFile is the part of `go mod edit -json` output the cache needs
+type goModFile struct {
+	Module struct {
+		Path string
+	}
+	Require []struct {
+		Path    string
+		Version string
+	}
+}
+
+// readGoMod parses the go.mod in a module directory
+func (mc *ModuleCache) readGoMod(ctx context.Context, moduleDir string) (*goModFile, error) {
+	output, err := mc.goCommand(ctx, moduleDir, mc.ResolveEnv(), "mod", "edit", "-json").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var goMod goModFile
+	if err := json.Unmarshal(output, &goMod); err != nil {
+		return nil, err
+	}
+	return &goMod, nil
+}
+
+// recordPins remembers the resolved requirements of a module
+func (mc *ModuleCache) recordPins(ctx context.Context, moduleDir string) error {
+	goMod, err := mc.readGoMod(ctx, moduleDir)
+	if err != nil {
+		return err
+	}
+
+	requirements := make([]string, 0, len(goMod.Require))
+	for _, require := range goMod.Require {
+		requirements = append(requirements, require.Path+"@"+require.Version)
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	for _, require := range goMod.Require {
+		// Keep the challenge with the newest version when challenges disagree
+		current, ok := mc.pins[require.Path]
+		if !ok || semver.Compare(require.Version, pinnedVersion(current, require.Path)) > 0 {
+			mc.pins[require.Path] = requirements
+		}
+	}
+	return nil
+}
+
+// pinnedVersion finds the version of a module in a requirement list
+func pinnedVersion(requirements []string, module string) string {
+	for _, requirement := range requirements {
+		if strings.HasPrefix(requirement, module+"@") {
+			return strings.TrimPrefix(requirement, module+"@")
+		}
+	}
+	return ""
+}
+
+// goCommand builds a go command with extra environment on top of the server's own
+func (mc *ModuleCache) goCommand(ctx context.Context, dir string, env []string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	return cmd
+}
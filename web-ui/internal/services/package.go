@@ -25,7 +25,7 @@ func NewPackageService() *PackageService {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		packagesPath:   "../packages", // Relative to web-ui directory
+		packagesPath:   WorkspacePath("packages"),
 		cachedPackages: nil,
 	}
 }
@@ -365,9 +365,13 @@ func (s *PackageService) loadChallenge(challengePath, challengeName string) *mod
 
 	// Determine difficulty - try to load from metadata first, then infer from challenge name
 	difficulty := "Beginner" // default fallback
+	timeoutSeconds := 0
 
-	// Try to load metadata.json for difficulty
+	// Try to load metadata.json for difficulty and execution settings
 	metadata := s.loadChallengeMetadata(challengePath)
+	if metadata != nil {
+		timeoutSeconds = metadata.TimeoutSeconds
+	}
 	if metadata != nil && metadata.Difficulty != "" {
 		difficulty = metadata.Difficulty
 	} else {
@@ -389,6 +393,8 @@ func (s *PackageService) loadChallenge(challengePath, challengeName string) *mod
 		TestFile:          testFile,
 		Hints:             hints,
 		LearningMaterials: learningMaterials, // Use learning.md for learning materials tab
+		TimeoutSeconds:    timeoutSeconds,
+		Dir:               challengePath,
 	}
 }
 
@@ -521,3 +527,29 @@ func (s *PackageService) GetPackageChallenge(packageID, challengeID string) (*mo
 
 	return challenge, nil
 }
+
+// ExecutionChallenge converts a package challenge to the format ExecutionService runs
+func ExecutionChallenge(challenge *models.PackageChallenge) *models.Challenge {
+	return &models.Challenge{
+		ID:             0, // Package challenges don't use numeric IDs
+		Title:          challenge.Title,
+		TestFile:       challenge.TestFile,
+		TimeoutSeconds: challenge.TimeoutSeconds,
+		Dir:            challenge.Dir,
+	}
+}
+
+// GetExecutionChallenges returns every package challenge in the format ExecutionService runs
+func (s *PackageService) GetExecutionChallenges() []*models.Challenge {
+	var challenges []*models.Challenge
+	for packageID := range s.GetPackages() {
+		packageChallenges, err := s.GetPackageChallenges(packageID)
+		if err != nil {
+			continue
+		}
+		for _, challenge := range packageChallenges {
+			challenges = append(challenges, ExecutionChallenge(challenge))
+		}
+	}
+	return challenges
+}
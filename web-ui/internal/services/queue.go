@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// Job statuses reported by the execution queue
+const (
+	JobStatusQueued   = "queued"
+	JobStatusRunning  = "running"
+	JobStatusDone     = "done"
+	JobStatusCanceled = "canceled"
+)
+
+// ErrQueueFull is returned when the execution queue has no room for another job
+var ErrQueueFull = errors.New("execution queue is full, please try again shortly")
+
+// finishedJobRetention is how long completed jobs stay available for polling
+const finishedJobRetention = 15 * time.Minute
+
+// executionJob is a single code run waiting for or being processed by a worker
+type executionJob struct {
+	id          string
+	username    string // Who queued the job, empty if nobody was signed in
+	code        string
+	challenge   *models.Challenge
+	options     RunOptions
+	ctx         context.Context
+	cancel      context.CancelFunc
+	status      string
+	submittedAt time.Time
+	startedAt   time.Time
+	finishedAt  time.Time
+	result      *ExecutionResult
+	done        chan struct{}
+}
+
+// JobStatus is a snapshot of a job for API responses
+type JobStatus struct {
+	ID            string           `json:"id"`
+	Username      string           `json:"username,omitempty"`
+	Status        string           `json:"status"`
+	QueuePosition int              `json:"queuePosition"` // 1-based position while queued, 0 otherwise
+	ChallengeID   int              `json:"challengeId"`
+	Title         string           `json:"title"`
+	SubmittedAt   time.Time        `json:"submittedAt"`
+	StartedAt     *time.Time       `json:"startedAt,omitempty"`
+	FinishedAt    *time.Time       `json:"finishedAt,omitempty"`
+	Result        *ExecutionResult `json:"result,omitempty"`
+}
+
+// QueueStats summarizes the state of the execution queue
+type QueueStats struct {
+	Workers  int         `json:"workers"`
+	Capacity int         `json:"capacity"`
+	Queued   int         `json:"queued"`
+	Running  int         `json:"running"`
+	Jobs     []JobStatus `json:"jobs"`
+}
+
+// executionQueue is a bounded FIFO of jobs served by a fixed pool of workers
+type executionQueue struct {
+	mutex    sync.Mutex
+	ready    *sync.Cond
+	pending  []*executionJob
+	jobs     map[string]*executionJob
+	workers  int
+	capacity int
+	running  int
+	run      func(ctx context.Context, code string, challenge *models.Challenge, options RunOptions) ExecutionResult
+}
+
+// newExecutionQueue creates a queue and starts its workers
+func newExecutionQueue(workers, capacity int, run func(ctx context.Context, code string, challenge *models.Challenge, options RunOptions) ExecutionResult) *executionQueue {
+	q := &executionQueue{
+		jobs:     make(map[string]*executionJob),
+		workers:  workers,
+		capacity: capacity,
+		run:      run,
+	}
+	q.ready = sync.NewCond(&q.mutex)
+
+	for i := 0; i < workers; i++ {
+		go q.worker()
+	}
+
+	return q
+}
+
+// submit adds a job queued by username to the end of the queue
+func (q *executionQueue) submit(ctx context.Context, username, code string, challenge *models.Challenge, options RunOptions) (*executionJob, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	job := &executionJob{
+		id:          id,
+		username:    username,
+		code:        code,
+		challenge:   challenge,
+		options:     options,
+		ctx:         jobCtx,
+		cancel:      cancel,
+		status:      JobStatusQueued,
+		submittedAt: time.Now(),
+		done:        make(chan struct{}),
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.pruneFinished()
+
+	if len(q.pending) >= q.capacity {
+		cancel()
+		return nil, ErrQueueFull
+	}
+
+	q.pending = append(q.pending, job)
+	q.jobs[id] = job
+	q.ready.Signal()
+
+	return job, nil
+}
+
+// submitFinished records a job queued by username whose result is already known, such as a cached run
+func (q *executionQueue) submitFinished(username, code string, challenge *models.Challenge, options RunOptions, result ExecutionResult) (JobStatus, error) {
+	id, err := newJobID()
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	now := time.Now()
+	job := &executionJob{
+		id:          id,
+		username:    username,
+		code:        code,
+		challenge:   challenge,
+		options:     options,
+		ctx:         context.Background(),
+		cancel:      func() {},
+		submittedAt: now,
+		startedAt:   now,
+		done:        make(chan struct{}),
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.pruneFinished()
+	q.jobs[id] = job
+	q.finish(job, &result)
+
+	return q.snapshot(job), nil
+}
+
+// worker runs queued jobs one at a time until the process exits
+func (q *executionQueue) worker() {
+	for {
+		q.mutex.Lock()
+		for len(q.pending) == 0 {
+			q.ready.Wait()
+		}
+		job := q.pending[0]
+		q.pending = q.pending[1:]
+		job.status = JobStatusRunning
+		job.startedAt = time.Now()
+		q.running++
+		q.mutex.Unlock()
+
+		result := q.run(job.ctx, job.code, job.challenge, job.options)
+
+		q.mutex.Lock()
+		q.running--
+		q.finish(job, &result)
+		q.mutex.Unlock()
+	}
+}
+
+// finish records a job's result and wakes anyone waiting on it. Must hold q.mutex.
+func (q *executionQueue) finish(job *executionJob, result *ExecutionResult) {
+	job.result = result
+	job.finishedAt = time.Now()
+	if result.Status == ExecutionStatusCanceled {
+		job.status = JobStatusCanceled
+	} else {
+		job.status = JobStatusDone
+	}
+	job.cancel()
+	close(job.done)
+}
+
+// wait blocks until a job has finished or ctx is done
+func (q *executionQueue) wait(ctx context.Context, id string) (JobStatus, bool) {
+	q.mutex.Lock()
+	job, exists := q.jobs[id]
+	q.mutex.Unlock()
+	if !exists {
+		return JobStatus{}, false
+	}
+
+	select {
+	case <-job.done:
+	case <-ctx.Done():
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.snapshot(job), true
+}
+
+// cancel stops a queued or running job
+func (q *executionQueue) cancelJob(id string) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return false
+	}
+
+	switch job.status {
+	case JobStatusQueued:
+		// Never started, so drop it from the queue directly
+		for i, pending := range q.pending {
+			if pending == job {
+				q.pending = append(q.pending[:i], q.pending[i+1:]...)
+				break
+			}
+		}
+		q.finish(job, &ExecutionResult{
+			Status: ExecutionStatusCanceled,
+			Output: "Run was cancelled before it started",
+		})
+	case JobStatusRunning:
+		// The worker records the result once the sandbox has stopped
+		job.cancel()
+	}
+
+	return true
+}
+
+// status returns a snapshot of a job
+func (q *executionQueue) status(id string) (JobStatus, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return JobStatus{}, false
+	}
+	return q.snapshot(job), true
+}
+
+// stats returns a snapshot of the whole queue
+func (q *executionQueue) stats() QueueStats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	stats := QueueStats{
+		Workers:  q.workers,
+		Capacity: q.capacity,
+		Queued:   len(q.pending),
+		Running:  q.running,
+		Jobs:     []JobStatus{},
+	}
+	for _, job := range q.jobs {
+		if job.status == JobStatusQueued || job.status == JobStatusRunning {
+			stats.Jobs = append(stats.Jobs, q.snapshot(job))
+		}
+	}
+	return stats
+}
+
+// snapshot copies a job's public state. Must hold q.mutex.
+func (q *executionQueue) snapshot(job *executionJob) JobStatus {
+	status := JobStatus{
+		ID:          job.id,
+		Username:    job.username,
+		Status:      job.status,
+		ChallengeID: job.challenge.ID,
+		Title:       job.challenge.Title,
+		SubmittedAt: job.submittedAt,
+		Result:      job.result,
+	}
+
+	if job.status == JobStatusQueued {
+		for i, pending := range q.pending {
+			if pending == job {
+				status.QueuePosition = i + 1
+				break
+			}
+		}
+	}
+	if !job.startedAt.IsZero() {
+		startedAt := job.startedAt
+		status.StartedAt = &startedAt
+	}
+	if !job.finishedAt.IsZero() {
+		finishedAt := job.finishedAt
+		status.FinishedAt = &finishedAt
+	}
+
+	return status
+}
+
+// pruneFinished forgets jobs whose results have been available long enough. Must hold q.mutex.
+func (q *executionQueue) pruneFinished() {
+	for id, job := range q.jobs {
+		if !job.finishedAt.IsZero() && time.Since(job.finishedAt) > finishedJobRetention {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// newJobID generates a random job identifier
+func newJobID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
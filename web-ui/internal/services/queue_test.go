@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// queueTestRunner is a run function whose runs block until released or canceled, reporting the code of
+// each run as it starts
+type queueTestRunner struct {
+	started chan string
+	release chan struct{}
+}
+
+func newQueueTestRunner() *queueTestRunner {
+	return &queueTestRunner{started: make(chan string, 16), release: make(chan struct{})}
+}
+
+func (r *queueTestRunner) run(ctx context.Context, code string, challenge *models.Challenge, options RunOptions) ExecutionResult {
+	r.started <- code
+	select {
+	case <-r.release:
+		return ExecutionResult{Status: ExecutionStatusPassed, Passed: true}
+	case <-ctx.Done():
+		return ExecutionResult{Status: ExecutionStatusCanceled}
+	}
+}
+
+// waitStarted returns the code of the next run to start
+func (r *queueTestRunner) waitStarted(t *testing.T) string {
+	t.Helper()
+	select {
+	case code := <-r.started:
+		return code
+	case <-time.After(5 * time.Second):
+		t.Fatal("no run started")
+		return ""
+	}
+}
+
+// waitJob waits for a job to finish and returns its final status
+func waitJob(t *testing.T, q *executionQueue, id string) JobStatus {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, ok := q.wait(ctx, id)
+	if !ok {
+		t.Fatalf("job %s not found", id)
+	}
+	if status.FinishedAt == nil {
+		t.Fatalf("job %s did not finish: %+v", id, status)
+	}
+	return status
+}
+
+func TestExecutionQueueRunsJobsInOrder(t *testing.T) {
+	runner := newQueueTestRunner()
+	q := newExecutionQueue(1, 10, runner.run)
+	challenge := &models.Challenge{ID: 1, Title: "Sum"}
+
+	var ids []string
+	for _, code := range []string{"a", "b", "c"} {
+		job, err := q.submit(context.Background(), "bob", code, challenge, RunOptions{})
+		if err != nil {
+			t.Fatalf("submit(%s) error = %v", code, err)
+		}
+		ids = append(ids, job.id)
+	}
+
+	if code := runner.waitStarted(t); code != "a" {
+		t.Fatalf("first run = %s, want a", code)
+	}
+	for i, want := range []int{0, 1, 2} {
+		if status, _ := q.status(ids[i]); status.QueuePosition != want {
+			t.Errorf("job %d queue position = %d, want %d", i, status.QueuePosition, want)
+		}
+	}
+
+	for _, want := range []string{"b", "c"} {
+		runner.release <- struct{}{}
+		if code := runner.waitStarted(t); code != want {
+			t.Fatalf("next run = %s, want %s", code, want)
+		}
+	}
+	runner.release <- struct{}{}
+
+	for i, id := range ids {
+		if status := waitJob(t, q, id); status.Status != JobStatusDone || status.Result == nil || !status.Result.Passed {
+			t.Errorf("job %d = %+v, want a passed done job", i, status)
+		}
+	}
+}
+
+func TestExecutionQueueCancel(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel int    // Index of the job to cancel; job 0 is running and job 1 queued
+		output string // Output of the canceled job's result
+	}{
+		{"running job", 0, ""},
+		{"queued job", 1, "Run was cancelled before it started"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := newQueueTestRunner()
+			q := newExecutionQueue(1, 10, runner.run)
+			challenge := &models.Challenge{ID: 1, Title: "Sum"}
+
+			var ids []string
+			for _, code := range []string{"a", "b"} {
+				job, err := q.submit(context.Background(), "bob", code, challenge, RunOptions{})
+				if err != nil {
+					t.Fatalf("submit(%s) error = %v", code, err)
+				}
+				ids = append(ids, job.id)
+			}
+			runner.waitStarted(t)
+
+			if !q.cancelJob(ids[tt.cancel]) {
+				t.Fatalf("cancelJob() = false")
+			}
+			if status := waitJob(t, q, ids[tt.cancel]); status.Status != JobStatusCanceled || status.Result.Output != tt.output {
+				t.Errorf("canceled job = %+v, result %+v", status, status.Result)
+			}
+
+			// Canceling the running job frees the worker for the queued one
+			if tt.cancel == 0 {
+				if code := runner.waitStarted(t); code != "b" {
+					t.Errorf("next run = %s, want b", code)
+				}
+			}
+			runner.release <- struct{}{}
+			if status := waitJob(t, q, ids[1-tt.cancel]); status.Status != JobStatusDone {
+				t.Errorf("other job status = %s, want %s", status.Status, JobStatusDone)
+			}
+			select {
+			case code := <-runner.started:
+				t.Errorf("canceled job %s was run", code)
+			default:
+			}
+
+			if q.cancelJob("missing") {
+				t.Errorf("cancelJob(missing) = true")
+			}
+		})
+	}
+}
+
+func TestExecutionQueueFull(t *testing.T) {
+	runner := newQueueTestRunner()
+	q := newExecutionQueue(1, 1, runner.run)
+	challenge := &models.Challenge{ID: 1, Title: "Sum"}
+
+	running, err := q.submit(context.Background(), "bob", "a", challenge, RunOptions{})
+	if err != nil {
+		t.Fatalf("submit(a) error = %v", err)
+	}
+	runner.waitStarted(t)
+	queued, err := q.submit(context.Background(), "bob", "b", challenge, RunOptions{})
+	if err != nil {
+		t.Fatalf("submit(b) error = %v", err)
+	}
+	if _, err := q.submit(context.Background(), "bob", "c", challenge, RunOptions{}); err != ErrQueueFull {
+		t.Errorf("submit(c) error = %v, want ErrQueueFull", err)
+	}
+
+	if stats := q.stats(); stats.Queued != 1 || stats.Running != 1 || len(stats.Jobs) != 2 {
+		t.Errorf("stats() = %+v, want 1 queued and 1 running", stats)
+	}
+
+	q.cancelJob(queued.id)
+	q.cancelJob(running.id)
+	waitJob(t, q, running.id)
+}
+
+func TestExecutionQueuePrunesFinishedJobs(t *testing.T) {
+	q := newExecutionQueue(0, 10, nil)
+	challenge := &models.Challenge{ID: 1, Title: "Sum"}
+
+	tests := []struct {
+		name     string
+		finished time.Duration // How long ago the job finished
+		kept     bool
+	}{
+		{"recent", time.Minute, true},
+		{"past retention", finishedJobRetention + time.Minute, false},
+	}
+
+	ids := make([]string, len(tests))
+	for i, tt := range tests {
+		status, err := q.submitFinished("bob", "a", challenge, RunOptions{}, ExecutionResult{Status: ExecutionStatusPassed})
+		if err != nil {
+			t.Fatalf("submitFinished() error = %v", err)
+		}
+		if status.Status != JobStatusDone {
+			t.Fatalf("finished job status = %s, want %s", status.Status, JobStatusDone)
+		}
+		ids[i] = status.ID
+
+		q.mutex.Lock()
+		q.jobs[status.ID].finishedAt = time.Now().Add(-tt.finished)
+		q.mutex.Unlock()
+	}
+
+	// Any new job prunes the finished ones
+	if _, err := q.submitFinished("bob", "b", challenge, RunOptions{}, ExecutionResult{}); err != nil {
+		t.Fatalf("submitFinished() error = %v", err)
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := q.status(ids[i]); ok != tt.kept {
+				t.Errorf("status() found = %v, want %v", ok, tt.kept)
+			}
+		})
+	}
+}
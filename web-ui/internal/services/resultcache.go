@@ -0,0 +1,133 @@
+package services
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaultResultCacheSize bounds how many run results are kept in memory
+const defaultResultCacheSize = 500
+
+// resultCache remembers the results of deterministic runs, evicting the least recently used
+type resultCache struct {
+	mutex    sync.Mutex
+	capacity int
+	order    *list.List // Front is the most recently used entry
+	entries  map[string]*list.Element
+}
+
+// resultCacheEntry is a cached result and its key
+type resultCacheEntry struct {
+	key    string
+	result ExecutionResult
+}
+
+// newResultCache creates a cache holding up to capacity results. A capacity of 0 disables caching.
+func newResultCache(capacity int) *resultCache {
+	return &resultCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached result for a key
+func (rc *resultCache) get(key string) (ExecutionResult, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	element, ok := rc.entries[key]
+	if !ok {
+		return ExecutionResult{}, false
+	}
+	rc.order.MoveToFront(element)
+	return element.Value.(*resultCacheEntry).result, true
+}
+
+// put stores a result, evicting the least recently used one when full
+func (rc *resultCache) put(key string, result ExecutionResult) {
+	if rc.capacity <= 0 {
+		return
+	}
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	if element, ok := rc.entries[key]; ok {
+		element.Value.(*resultCacheEntry).result = result
+		rc.order.MoveToFront(element)
+		return
+	}
+
+	rc.entries[key] = rc.order.PushFront(&resultCacheEntry{key: key, result: result})
+	for rc.order.Len() > rc.capacity {
+		oldest := rc.order.Back()
+		rc.order.Remove(oldest)
+		delete(rc.entries, oldest.Value.(*resultCacheEntry).key)
+	}
+}
+
+// clear drops every cached result
+func (rc *resultCache) clear() {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	rc.order.Init()
+	rc.entries = make(map[string]*list.Element)
+}
+
+// resultKey hashes everything that determines the outcome of a run
+func resultKey(testFile, code, goVersion string, options RunOptions) string {
+	hash := sha256.New()
+	for _, part := range []string{testFile, code, goVersion, options.cacheKey()} {
+		// Length prefixes keep different splits of the same bytes apart
+		fmt.Fprintf(hash, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// isCacheableResult reports whether running the same code again would give the same result.
+// Timeouts, sandbox kills, cancellations and setup errors depend on the machine and are retried.
+func isCacheableResult(result ExecutionResult) bool {
+	return result.Status == ExecutionStatusPassed || result.Status == ExecutionStatusFailed
+}
+
+// buildCacheName turns a challenge directory such as ../packages/gin/challenge-1-basic-routing
+// into a flat directory name for its build cache
+func buildCacheName(challengeDir string, challengeID int) string {
+	if challengeDir == "" {
+		return fmt.Sprintf("challenge-%d", challengeID)
+	}
+
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(challengeDir)), "/")
+	kept := parts[:0]
+	for _, part := range parts {
+		if part != ".." && part != "." && part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "-")
+}
+
+// buildCacheDirFromEnv returns the root for the per-challenge GOCACHE directories
+func buildCacheDirFromEnv() string {
+	dir := os.Getenv("BUILD_CACHE_DIR")
+	if dir == "" {
+		cacheRoot, err := os.UserCacheDir()
+		if err != nil {
+			cacheRoot = os.TempDir()
+		}
+		dir = filepath.Join(cacheRoot, "go-interview-practice", "build")
+	}
+
+	if absDir, err := filepath.Abs(dir); err == nil {
+		dir = absDir
+	}
+	return dir
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"log"
+	"os"
+	"strings"
+)
+
+// Role is what a signed-in user may do. Each role includes the ones below it.
+type Role string
+
+// Roles, from least to most privileged
+const (
+	RoleLearner Role = "learner" // Every signed-in user: runs and submits their own solutions
+	RoleCoach   Role = "coach"   // Also manages cohorts and reviews other users' attempts
+	RoleAdmin   Role = "admin"   // Also operates the server: reloads, caches, the queue and diagnostics
+)
+
+// roleLevels orders the roles for Allows
+var roleLevels = map[Role]int{
+	RoleLearner: 1,
+	RoleCoach:   2,
+	RoleAdmin:   3,
+}
+
+// Allows reports whether a user with this role may act with the required one
+func (r Role) Allows(required Role) bool {
+	return roleLevels[r] >= roleLevels[required]
+}
+
+// RoleAssignments maps usernames to roles granted beyond learner
+type RoleAssignments struct {
+	roles map[string]Role // Keyed by lowercase username, as GitHub usernames are case-insensitive
+}
+
+// NewRoleAssignmentsFromEnv reads comma-separated GitHub usernames from ADMIN_USERS and COACH_USERS.
+// A user listed in both is an admin.
+func NewRoleAssignmentsFromEnv() *RoleAssignments {
+	assignments := &RoleAssignments{roles: make(map[string]Role)}
+	assignments.grant(os.Getenv("COACH_USERS"), RoleCoach)
+	assignments.grant(os.Getenv("ADMIN_USERS"), RoleAdmin)
+
+	if admins := assignments.count(RoleAdmin); admins == 0 {
+		log.Printf("Warning: ADMIN_USERS is not set, so admin routes are unavailable")
+	} else {
+		log.Printf("Roles: %d admins, %d coaches", admins, assignments.count(RoleCoach))
+	}
+	return assignments
+}
+
+// grant gives role to each username in a comma-separated list
+func (a *RoleAssignments) grant(usernames string, role Role) {
+	for _, username := range strings.Split(usernames, ",") {
+		username = strings.TrimSpace(username)
+		if username == "" {
+			continue
+		}
+		if !ValidGitHubUsername(username) {
+			log.Printf("Warning: Ignoring invalid username %q in the %s list", username, role)
+			continue
+		}
+		a.roles[strings.ToLower(username)] = role
+	}
+}
+
+// count returns how many users hold exactly role
+func (a *RoleAssignments) count(role Role) int {
+	count := 0
+	for _, assigned := range a.roles {
+		if assigned == role {
+			count++
+		}
+	}
+	return count
+}
+
+// Granted reports whether any user was granted a role beyond learner
+func (a *RoleAssignments) Granted() bool {
+	return len(a.roles) > 0
+}
+
+// Role returns a user's role, learner unless they were granted another
+func (a *RoleAssignments) Role(username string) Role {
+	if role, ok := a.roles[strings.ToLower(username)]; ok {
+		return role
+	}
+	return RoleLearner
+}
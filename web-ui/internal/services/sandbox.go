@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sandbox isolates the commands that build and run user submitted code
+type Sandbox interface {
+	// Name returns the identifier of the sandbox backend
+	Name() string
+	// Command builds a command that runs inside the sandbox on a job's files.
+	// The whole job is killed when ctx is done.
+	Command(ctx context.Context, job SandboxJob, name string, args ...string) *exec.Cmd
+	// Kill reports whether the sandbox terminated a finished job, judged from how its process ended
+	// and, for limits the kernel enforces by failing allocations, from what it printed
+	Kill(state *os.ProcessState, output []byte) SandboxKill
+}
+
+// SandboxJob names the files a sandboxed command works on
+type SandboxJob struct {
+	Dir        string   // Working directory with the run's own copy of the code, the only one the job may write to
+	ReadOnly   []string // Files and directories the job may read besides the toolchain, such as the module cache
+	BuildCache string   // GOCACHE of the job
+	// WriteBuildCache lets the job add to BuildCache. Only the server's own warming runs do; other jobs
+	// read it through a private layer in the namespace sandbox, or a private copy in the process
+	// sandbox, so they cannot poison later builds.
+	WriteBuildCache bool
+}
+
+// SandboxLimits holds the resource budget applied to each sandboxed job
+type SandboxLimits struct {
+	CPUSeconds int // Maximum CPU time per process, in seconds
+	MemoryMB   int // Maximum address space per process, in megabytes
+}
+
+// SandboxConfig holds configuration for the execution sandbox
+type SandboxConfig struct {
+	Backend string // "namespace" or "process"
+	Limits  SandboxLimits
+	Env     []string // Scrubbed environment passed to sandboxed jobs
+}
+
+const (
+	SandboxBackendNamespace = "namespace"
+	SandboxBackendProcess   = "process"
+)
+
+// Default resource budget for sandboxed jobs
+const (
+	defaultSandboxCPUSeconds = 60
+	defaultSandboxMemoryMB   = 2048
+)
+
+// NewSandboxFromEnv creates the sandbox configured through environment variables. Submitted code must
+// not reach the network, so when the namespace backend cannot be set up this fails instead of falling
+// back; the process backend runs only when SANDBOX_BACKEND=process asks for it.
+func NewSandboxFromEnv() (Sandbox, error) {
+	config := SandboxConfig{
+		Backend: strings.ToLower(os.Getenv("SANDBOX_BACKEND")),
+		Limits: SandboxLimits{
+			CPUSeconds: getIntFromEnv("SANDBOX_CPU_SECONDS", defaultSandboxCPUSeconds),
+			MemoryMB:   getIntFromEnv("SANDBOX_MEMORY_MB", defaultSandboxMemoryMB),
+		},
+		Env: scrubbedGoEnv(),
+	}
+
+	switch config.Backend {
+	case "", SandboxBackendNamespace:
+		config.Backend = SandboxBackendNamespace
+		sandbox, err := newNamespaceSandbox(config)
+		if err != nil {
+			return nil, fmt.Errorf("namespace sandbox unavailable, set SANDBOX_BACKEND=process to run submissions without isolation: %v", err)
+		}
+		return sandbox, nil
+	case SandboxBackendProcess:
+		log.Printf("Warning: The process sandbox isolates neither the network nor the filesystem, so submitted code can reach what the server can")
+		return &processSandbox{config: config}, nil
+	default:
+		return nil, fmt.Errorf("unknown SANDBOX_BACKEND %q", config.Backend)
+	}
+}
+
+// processSandbox runs jobs as child processes with rlimits and a scrubbed environment
+type processSandbox struct {
+	config SandboxConfig
+}
+
+// Name returns the identifier of the sandbox backend
+func (ps *processSandbox) Name() string {
+	return SandboxBackendProcess
+}
+
+// Command builds a command bounded by the configured rlimits
+func (ps *processSandbox) Command(ctx context.Context, job SandboxJob, name string, args ...string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, name, args...)
+	} else {
+		// The shell applies the rlimits and then replaces itself with the real command
+		script := ps.limitScript() + `exec "$@"`
+		cmd = exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", script, "sandbox", name}, args...)...)
+	}
+	cmd.Dir = job.Dir
+	cmd.Env = ps.env(job.Dir, ps.buildCache(job))
+
+	// Test binaries are grandchildren of go test, so cancellation must reach the whole group
+	killProcessGroupOnCancel(cmd)
+	cmd.WaitDelay = jobWaitDelay
+	return cmd
+}
+
+// env returns the scrubbed environment of a job in dir that builds with the given GOCACHE
+func (ps *processSandbox) env(dir, buildCache string) []string {
+	env := append(append([]string{}, ps.config.Env...), "HOME="+dir)
+	if buildCache != "" {
+		env = append(env, "GOCACHE="+buildCache)
+	}
+	return env
+}
+
+// privateBuildCacheName is where a process sandbox job keeps its copy of the shared build cache, in
+// its own directory. go ignores directories starting with a dot, so ./... patterns skip it.
+const privateBuildCacheName = ".gocache"
+
+// buildCache returns the GOCACHE of a job. Without namespaces the shared cache cannot be layered, so
+// jobs that may not write it get a copy of their own, which disappears with the run.
+func (ps *processSandbox) buildCache(job SandboxJob) string {
+	if job.BuildCache == "" || job.WriteBuildCache {
+		return job.BuildCache
+	}
+
+	private := filepath.Join(job.Dir, privateBuildCacheName)
+	if _, err := os.Stat(private); err == nil {
+		// An earlier command of the same run made the copy
+		return private
+	}
+	if err := copyTree(job.BuildCache, private); err != nil {
+		log.Printf("Warning: Could not copy build cache %s, so the run builds without it: %v", job.BuildCache, err)
+		os.RemoveAll(private)
+		os.MkdirAll(private, 0755)
+	}
+	return private
+}
+
+// copyTree copies the directories and regular files under src to dst. A missing src copies as empty.
+func copyTree(src, dst string) error {
+	if _, err := os.Stat(src); errors.Is(err, fs.ErrNotExist) {
+		return os.MkdirAll(dst, 0755)
+	}
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relative)
+
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+// copyFile copies a regular file, keeping its permissions
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Kill reports whether a job ended on a resource limit signal or ran out of memory
+func (ps *processSandbox) Kill(state *os.ProcessState, output []byte) SandboxKill {
+	if kill := signalKill(state, ps.config.Limits); kill.Killed {
+		return kill
+	}
+	return memoryKill(state, output, ps.config.Limits)
+}
+
+// outOfMemoryPattern matches how a Go program, or the go command on behalf of the compiler, dies
+// when mmap fails under the address space limit
+var outOfMemoryPattern = regexp.MustCompile(`fatal error: (runtime: )?(out of memory|cannot map pages)|fatal error: failed to reserve|runtime: mmap\(.*\) failed|cannot allocate memory`)
+
+// memoryKill reports a job that failed because it reached its address space limit. The kernel sends no
+// signal for it: mmap fails and the Go runtime exits on its own with a fatal error and exit code 2.
+func memoryKill(state *os.ProcessState, output []byte, limits SandboxLimits) SandboxKill {
+	if state == nil || state.Success() || limits.MemoryMB <= 0 {
+		return SandboxKill{}
+	}
+	if !outOfMemoryPattern.Match(output) {
+		return SandboxKill{}
+	}
+	return SandboxKill{Killed: true, Reason: "memory limit exceeded"}
+}
+
+// jobWaitDelay bounds how long a cancelled job may keep its output pipes open
+const jobWaitDelay = 5 * time.Second
+
+// limitScript builds the ulimit prefix for the configured budget
+func (ps *processSandbox) limitScript() string {
+	var script strings.Builder
+	if ps.config.Limits.CPUSeconds > 0 {
+		fmt.Fprintf(&script, "ulimit -t %d || exit 125; ", ps.config.Limits.CPUSeconds)
+	}
+	if ps.config.Limits.MemoryMB > 0 {
+		fmt.Fprintf(&script, "ulimit -v %d || exit 125; ", ps.config.Limits.MemoryMB*1024)
+	}
+	return script.String()
+}
+
+// SandboxKill describes why the sandbox terminated a job
+type SandboxKill struct {
+	Killed bool
+	Reason string
+}
+
+// scrubbedGoEnv builds a minimal environment containing only what the Go toolchain needs
+func scrubbedGoEnv() []string {
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"GOTOOLCHAIN=local",
+		"CGO_ENABLED=" + goEnvValue("CGO_ENABLED"),
+	}
+
+	// Pin the toolchain locations so they do not depend on HOME inside the sandbox
+	for _, key := range []string{"GOROOT", "GOPATH", "GOMODCACHE", "GOCACHE"} {
+		if value := goEnvValue(key); value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+
+	return env
+}
+
+// goEnvValue reads a value from `go env`
+func goEnvValue(key string) string {
+	output, err := exec.Command("go", "env", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// getIntFromEnv reads a positive integer from the environment with a default
+func getIntFromEnv(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getNonNegativeIntFromEnv reads an integer from the environment with a default, for settings where 0 turns a feature off
+func getNonNegativeIntFromEnv(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
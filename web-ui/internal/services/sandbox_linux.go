@@ -0,0 +1,668 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// namespaceSandbox additionally isolates each job in its own user, mount, PID, network, IPC and UTS
+// namespaces. A job's root filesystem holds only its own directory, read-write, and the toolchain,
+// read-only, so it cannot see the server's data directory, the workspace or other runs.
+type namespaceSandbox struct {
+	processSandbox
+	rootDir     string   // Empty directory each job mounts its own root on, inside its mount namespace
+	systemPaths []string // Read-only paths every job sees: system binaries and libraries, and GOROOT
+	identity    sandboxIdentity
+}
+
+// sandboxIdentity is who jobs run as
+type sandboxIdentity struct {
+	dropUser bool // Switch to sandboxUID, mapped to hostUID; otherwise stay the server's user without capabilities
+	hostUID  int
+	hostGID  int
+}
+
+// sandboxUID is the unprivileged identity jobs run as inside their user namespace
+const sandboxUID = 65534
+
+// sandboxInitName is the argv[0] the server re-executes itself with to set up a job's namespaces
+const sandboxInitName = "go-interview-sandbox-init"
+
+// sandboxSetupFailed is the exit code of the setup helper when it cannot run a job, like a failed ulimit in the process sandbox
+const sandboxSetupFailed = 125
+
+// sandboxCacheLayer is where a job's private layer over the shared build cache lives, in its own /tmp
+const sandboxCacheLayer = "/tmp/.gocache"
+
+// sandboxSetup is what the setup helper prepares before it runs a job
+type sandboxSetup struct {
+	Root       string   `json:"root"`
+	Dir        string   `json:"dir"`
+	ReadOnly   []string `json:"readOnly"`
+	BuildCache string   `json:"buildCache,omitempty"`
+	WriteCache bool     `json:"writeCache,omitempty"`
+	UID        int      `json:"uid"` // 0 keeps uid 0 inside the namespace, without capabilities
+	GID        int      `json:"gid"`
+	CPUSeconds int      `json:"cpuSeconds"`
+	MemoryMB   int      `json:"memoryMB"`
+	Probe      bool     `json:"probe"` // Check the setup and exit instead of running a command
+
+	cacheErr error // Why the build cache could not be layered, so the job builds without it
+}
+
+func init() {
+	if len(os.Args) > 2 && os.Args[0] == sandboxInitName {
+		runSandboxInit(os.Args[1], os.Args[2:])
+	}
+}
+
+// newNamespaceSandbox creates the namespace sandbox, checking that this machine can set one up
+func newNamespaceSandbox(config SandboxConfig) (Sandbox, error) {
+	rootDir, err := os.MkdirTemp("", "sandbox-root")
+	if err != nil {
+		return nil, err
+	}
+
+	ns := &namespaceSandbox{
+		processSandbox: processSandbox{config: config},
+		rootDir:        rootDir,
+		systemPaths:    systemPaths(),
+		identity:       sandboxIdentityFromEnv(),
+	}
+	if err := ns.probe(); err != nil {
+		os.Remove(rootDir)
+		return nil, err
+	}
+	return ns, nil
+}
+
+// systemPaths lists the read-only system directories and GOROOT that exist on this machine
+func systemPaths() []string {
+	var paths []string
+	for _, path := range []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32"} {
+		if _, err := os.Lstat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	if goroot := goEnvValue("GOROOT"); goroot != "" {
+		paths = append(paths, goroot)
+	}
+	return paths
+}
+
+// sandboxIdentityFromEnv maps jobs to SANDBOX_UID and SANDBOX_GID, 65534 (nobody) by default, when the
+// server runs as root. An unprivileged server can only map its own user, so jobs keep it.
+func sandboxIdentityFromEnv() sandboxIdentity {
+	if os.Getuid() != 0 {
+		log.Printf("Warning: The server is not root, so sandboxed jobs run as its own user, without capabilities")
+		return sandboxIdentity{hostUID: os.Getuid(), hostGID: os.Getgid()}
+	}
+	return sandboxIdentity{
+		dropUser: true,
+		hostUID:  getIntFromEnv("SANDBOX_UID", sandboxUID),
+		hostGID:  getIntFromEnv("SANDBOX_GID", sandboxUID),
+	}
+}
+
+// Name returns the identifier of the sandbox backend
+func (ns *namespaceSandbox) Name() string {
+	return SandboxBackendNamespace
+}
+
+// Command builds a command that runs in fresh namespaces on a root filesystem of its own
+func (ns *namespaceSandbox) Command(ctx context.Context, job SandboxJob, name string, args ...string) *exec.Cmd {
+	return ns.command(ctx, ns.setup(job), name, args...)
+}
+
+// setup describes the root filesystem and limits of a job
+func (ns *namespaceSandbox) setup(job SandboxJob) sandboxSetup {
+	setup := sandboxSetup{
+		Root:       ns.rootDir,
+		Dir:        job.Dir,
+		ReadOnly:   append(append([]string{}, ns.systemPaths...), job.ReadOnly...),
+		BuildCache: job.BuildCache,
+		WriteCache: job.WriteBuildCache,
+		CPUSeconds: ns.config.Limits.CPUSeconds,
+		MemoryMB:   ns.config.Limits.MemoryMB,
+	}
+	// Warming runs build the repository's own templates, so they keep the server's user, which
+	// then stays the only owner of the shared build cache
+	if ns.identity.dropUser && !job.WriteBuildCache {
+		setup.UID, setup.GID = sandboxUID, sandboxUID
+	}
+	return setup
+}
+
+// command re-executes the server as the setup helper, which runs name once the job's root is ready
+func (ns *namespaceSandbox) command(ctx context.Context, setup sandboxSetup, name string, args ...string) *exec.Cmd {
+	encoded, _ := json.Marshal(setup)
+
+	cmd := exec.CommandContext(ctx, "/proc/self/exe")
+	cmd.Args = append([]string{sandboxInitName, string(encoded), name}, args...)
+	cmd.Dir = setup.Dir
+	cmd.Env = append(ns.env(setup.Dir, setup.BuildCache), "TMPDIR=/tmp")
+	cmd.SysProcAttr = ns.identity.sysProcAttr()
+
+	killProcessGroupOnCancel(cmd)
+	cmd.WaitDelay = jobWaitDelay
+	return cmd
+}
+
+// sysProcAttr returns the process attributes that unshare the job's namespaces. The helper starts as
+// uid 0 in the new user namespace, which lets it mount the job's root before it drops to the job's user.
+func (id sandboxIdentity) sysProcAttr() *syscall.SysProcAttr {
+	attr := &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER |
+			syscall.CLONE_NEWNS |
+			syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNET |
+			syscall.CLONE_NEWIPC |
+			syscall.CLONE_NEWUTS,
+		Pdeathsig: syscall.SIGKILL,
+	}
+
+	if !id.dropUser {
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: id.hostUID, Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: id.hostGID, Size: 1}}
+		return attr
+	}
+
+	// Root inside maps to root outside only until the helper switches to the job's user
+	attr.UidMappings = []syscall.SysProcIDMap{
+		{ContainerID: 0, HostID: 0, Size: 1},
+		{ContainerID: sandboxUID, HostID: id.hostUID, Size: 1},
+	}
+	attr.GidMappings = []syscall.SysProcIDMap{
+		{ContainerID: 0, HostID: 0, Size: 1},
+		{ContainerID: sandboxUID, HostID: id.hostGID, Size: 1},
+	}
+	// Lets the helper drop the server's supplementary groups
+	attr.GidMappingsEnableSetgroups = true
+	return attr
+}
+
+// probe sets up an empty job to check that namespaces work here. Problems the sandbox works around,
+// such as a missing loopback interface, are logged as warnings.
+func (ns *namespaceSandbox) probe() error {
+	dir, err := os.MkdirTemp("", "sandbox-probe")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	setup := ns.setup(SandboxJob{Dir: dir, BuildCache: filepath.Join(dir, "cache")})
+	setup.Probe = true
+	cmd := ns.command(context.Background(), setup, "true")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not set up namespaces: %v: %s", err, output)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			log.Printf("Warning: %s", line)
+		}
+	}
+	return nil
+}
+
+// runSandboxInit is the setup helper: it builds the job's root filesystem, raises loopback, applies
+// the limits, drops privileges and replaces itself with the job's command. It never returns.
+func runSandboxInit(encoded string, command []string) {
+	// Capabilities and securebits are per thread, and exec keeps those of the calling thread
+	runtime.LockOSThread()
+
+	var setup sandboxSetup
+	if err := json.Unmarshal([]byte(encoded), &setup); err != nil {
+		sandboxInitFail(fmt.Errorf("invalid setup: %v", err))
+	}
+	if err := setup.mountRoot(); err != nil {
+		sandboxInitFail(err)
+	}
+	syscall.Sethostname([]byte("sandbox"))
+
+	// The network namespace has no other interfaces, so loopback gives no outside access
+	loopbackErr := raiseLoopback()
+	if loopbackErr != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: loopback interface unavailable, so tests cannot serve on localhost: %v\n", loopbackErr)
+	}
+
+	if err := setup.applyLimits(); err != nil {
+		sandboxInitFail(err)
+	}
+	if err := setup.dropPrivileges(); err != nil {
+		sandboxInitFail(err)
+	}
+
+	if setup.Probe {
+		if setup.cacheErr != nil {
+			fmt.Fprintf(os.Stderr, "sandbox: could not layer the build cache, so runs build without it: %v\n", setup.cacheErr)
+		}
+		os.Exit(0)
+	}
+
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		sandboxInitFail(err)
+	}
+	sandboxInitFail(syscall.Exec(path, command, os.Environ()))
+}
+
+// sandboxInitFail reports a setup error on the job's output and exits
+func sandboxInitFail(err error) {
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(sandboxSetupFailed)
+}
+
+// mountRoot builds the job's root on a tmpfs and pivots into it, detaching the server's filesystem
+func (s *sandboxSetup) mountRoot() error {
+	// Keep every mount made below out of the server's mount namespace
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %v", err)
+	}
+	if err := syscall.Mount("tmpfs", s.Root, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0755,size=16m"); err != nil {
+		return fmt.Errorf("mount root: %v", err)
+	}
+
+	// A private /tmp first, since the job's directory and caches usually live below it
+	tmp := filepath.Join(s.Root, "tmp")
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", tmp, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777,size=512m"); err != nil {
+		return fmt.Errorf("mount /tmp: %v", err)
+	}
+
+	for _, path := range s.ReadOnly {
+		// A cache that was never filled has nothing to show
+		if _, err := os.Lstat(path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err := bindMount(s.Root, path, true); err != nil {
+			return err
+		}
+	}
+	if err := s.mountWritable(); err != nil {
+		return err
+	}
+	if err := mountDevices(s.Root); err != nil {
+		return err
+	}
+	if err := writeSandboxEtc(s.Root); err != nil {
+		return err
+	}
+
+	// Without a /proc of its own the job still runs; GOROOT is passed in, so go does not need /proc/self/exe
+	proc := filepath.Join(s.Root, "proc")
+	if err := os.MkdirAll(proc, 0755); err != nil {
+		return err
+	}
+	syscall.Mount("proc", proc, "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "")
+
+	// pivot_root(".", ".") stacks the old root on the new one, so unmounting "." leaves only the new root
+	if err := syscall.Chdir(s.Root); err != nil {
+		return err
+	}
+	if err := syscall.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("pivot root: %v", err)
+	}
+	if err := syscall.Unmount(".", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach old root: %v", err)
+	}
+	if err := syscall.Mount("", "/", "", syscall.MS_REMOUNT|syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("make root read-only: %v", err)
+	}
+	return syscall.Chdir(s.Dir)
+}
+
+// mountWritable binds the job's directory and gives it to the job's user, then mounts the build cache
+func (s *sandboxSetup) mountWritable() error {
+	if err := bindMount(s.Root, s.Dir, false); err != nil {
+		return err
+	}
+	if err := chownTree(s.Dir, s.UID, s.GID); err != nil {
+		return err
+	}
+
+	if s.BuildCache == "" {
+		return nil
+	}
+	if err := os.MkdirAll(s.BuildCache, 0755); err != nil {
+		return err
+	}
+	if s.WriteCache {
+		return bindMount(s.Root, s.BuildCache, false)
+	}
+
+	if s.cacheErr = s.mountCacheLayer(); s.cacheErr != nil {
+		// Build from scratch rather than give the job the shared cache
+		private := filepath.Join(sandboxCacheLayer, "private")
+		if err := os.MkdirAll(filepath.Join(s.Root, private), 0755); err != nil {
+			return err
+		}
+		if err := chownIfNeeded(filepath.Join(s.Root, private), s.UID, s.GID); err != nil {
+			return err
+		}
+		return os.Setenv("GOCACHE", private)
+	}
+	return nil
+}
+
+// mountCacheLayer shows the job the shared build cache through an overlay whose upper layer is in
+// the job's /tmp, so what the job adds or changes disappears with it
+func (s *sandboxSetup) mountCacheLayer() error {
+	layer := filepath.Join(s.Root, sandboxCacheLayer)
+	upper, work := filepath.Join(layer, "upper"), filepath.Join(layer, "work")
+	target := filepath.Join(s.Root, s.BuildCache)
+	for _, dir := range []string{upper, work, target} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	if err := chownIfNeeded(upper, s.UID, s.GID); err != nil {
+		return err
+	}
+
+	source, err := filepath.EvalSymlinks(s.BuildCache)
+	if err != nil {
+		return err
+	}
+	options := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", source, upper, work)
+	if err := syscall.Mount("overlay", target, "overlay", syscall.MS_NOSUID|syscall.MS_NODEV, options); err != nil {
+		return fmt.Errorf("mount overlay: %v", err)
+	}
+	return nil
+}
+
+// bindMount makes path visible at the same place under root, read-only if asked. Top-level symlinks
+// such as /bin -> usr/bin are recreated instead, so they keep pointing into the new root.
+func bindMount(root, path string, readOnly bool) error {
+	target := filepath.Join(root, path)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 && filepath.Dir(path) == "/" {
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(link, target); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+		return nil
+	}
+
+	source, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	if info, err = os.Stat(source); err != nil {
+		return err
+	}
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0755)
+	} else if err = os.MkdirAll(filepath.Dir(target), 0755); err == nil {
+		err = touchFile(target)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %v", path, err)
+	}
+
+	flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_NOSUID)
+	if readOnly {
+		flags |= syscall.MS_RDONLY
+	}
+	if err := syscall.Mount("", target, "", flags|lockedMountFlags(target), ""); err != nil {
+		return fmt.Errorf("remount %s: %v", path, err)
+	}
+	return nil
+}
+
+// lockedMountFlags returns the flags a bind remount must keep, since the kernel refuses to clear
+// flags of mounts inherited from the server's namespace. statfs reports them with the MS_ values.
+func lockedMountFlags(path string) uintptr {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0
+	}
+	keep := int64(syscall.MS_NODEV | syscall.MS_NOEXEC | syscall.MS_NOATIME | syscall.MS_NODIRATIME | syscall.MS_RELATIME)
+	return uintptr(int64(stat.Flags) & keep)
+}
+
+// mountDevices exposes the few device files programs expect
+func mountDevices(root string) error {
+	dev := filepath.Join(root, "dev")
+	if err := os.MkdirAll(dev, 0755); err != nil {
+		return err
+	}
+	for _, name := range []string{"null", "zero", "full", "random", "urandom"} {
+		target := filepath.Join(dev, name)
+		if err := touchFile(target); err != nil {
+			return err
+		}
+		if err := syscall.Mount("/dev/"+name, target, "", syscall.MS_BIND, ""); err != nil {
+			return fmt.Errorf("bind /dev/%s: %v", name, err)
+		}
+	}
+	for name, link := range map[string]string{"fd": "/proc/self/fd", "stdin": "/proc/self/fd/0", "stdout": "/proc/self/fd/1", "stderr": "/proc/self/fd/2"} {
+		if err := os.Symlink(link, filepath.Join(dev, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSandboxEtc writes the minimal /etc a job needs to resolve localhost and its user
+func writeSandboxEtc(root string) error {
+	etc := filepath.Join(root, "etc")
+	if err := os.MkdirAll(etc, 0755); err != nil {
+		return err
+	}
+	files := map[string]string{
+		"hosts":  "127.0.0.1 localhost\n::1 localhost\n",
+		"passwd": fmt.Sprintf("root:x:0:0:root:/:/sbin/nologin\nnobody:x:%d:%d:nobody:/:/sbin/nologin\n", sandboxUID, sandboxUID),
+		"group":  fmt.Sprintf("root:x:0:\nnogroup:x:%d:\n", sandboxUID),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(etc, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// touchFile creates an empty file to mount over
+func touchFile(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// chownTree gives a job's directory to its user. With uid 0 the job keeps the server's user, which owns it already.
+func chownTree(dir string, uid, gid int) error {
+	if uid == 0 {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
+
+// chownIfNeeded changes a path's owner unless it already has it
+func chownIfNeeded(path string, uid, gid int) error {
+	var stat syscall.Stat_t
+	if err := syscall.Lstat(path, &stat); err != nil {
+		return err
+	}
+	if int(stat.Uid) == uid && int(stat.Gid) == gid {
+		return nil
+	}
+	return os.Lchown(path, uid, gid)
+}
+
+// ifreqFlags is struct ifreq as used by SIOCGIFFLAGS and SIOCSIFFLAGS
+type ifreqFlags struct {
+	name  [syscall.IFNAMSIZ]byte
+	flags uint16
+	_     [22]byte
+}
+
+// raiseLoopback brings up the lo interface of the job's network namespace
+func raiseLoopback() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	var request ifreqFlags
+	copy(request.name[:], "lo")
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCGIFFLAGS, uintptr(unsafe.Pointer(&request))); errno != 0 {
+		return errno
+	}
+	request.flags |= syscall.IFF_UP
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCSIFFLAGS, uintptr(unsafe.Pointer(&request))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// applyLimits sets the CPU time and address space limits, soft and hard alike, so the kernel kills a
+// job that reaches its CPU time with SIGKILL
+func (s *sandboxSetup) applyLimits() error {
+	if s.CPUSeconds > 0 {
+		limit := uint64(s.CPUSeconds)
+		if err := syscall.Setrlimit(syscall.RLIMIT_CPU, &syscall.Rlimit{Cur: limit, Max: limit}); err != nil {
+			return fmt.Errorf("limit cpu time: %v", err)
+		}
+	}
+	if s.MemoryMB > 0 {
+		limit := uint64(s.MemoryMB) << 20
+		if err := syscall.Setrlimit(syscall.RLIMIT_AS, &syscall.Rlimit{Cur: limit, Max: limit}); err != nil {
+			return fmt.Errorf("limit memory: %v", err)
+		}
+	}
+	return nil
+}
+
+// prctl options and securebits, which package syscall does not export
+const (
+	prSetNoNewPrivs      = 38
+	prCapbsetDrop        = 24
+	prCapAmbient         = 47
+	prCapAmbientClearAll = 4
+	prSetSecurebits      = 28
+
+	secbitNoroot               = 1 << 0
+	secbitNorootLocked         = 1 << 1
+	secbitNoSetuidFixup        = 1 << 2
+	secbitNoSetuidFixupLocked  = 1 << 3
+	secbitKeepCapsLocked       = 1 << 5
+	secbitNoCapAmbientRaise    = 1 << 6
+	secbitNoCapAmbientRaiseLck = 1 << 7
+
+	// lastCapability is well above CAP_LAST_CAP of current kernels; dropping unknown ones fails harmlessly
+	lastCapability = 63
+)
+
+// dropPrivileges switches to the job's user, or, when the server could not map one, keeps uid 0 inside
+// the namespace but gives up every capability, so the job cannot undo its mounts either way
+func (s *sandboxSetup) dropPrivileges() error {
+	if err := prctl(prSetNoNewPrivs, 1); err != nil {
+		return fmt.Errorf("set no_new_privs: %v", err)
+	}
+
+	if s.UID != 0 {
+		if err := syscall.Setgroups([]int{}); err != nil {
+			return fmt.Errorf("drop groups: %v", err)
+		}
+		if err := syscall.Setresgid(s.GID, s.GID, s.GID); err != nil {
+			return fmt.Errorf("switch group: %v", err)
+		}
+		if err := syscall.Setresuid(s.UID, s.UID, s.UID); err != nil {
+			return fmt.Errorf("switch user: %v", err)
+		}
+		// Changing user clears the parent-death signal
+		return prctl(syscall.PR_SET_PDEATHSIG, uintptr(syscall.SIGKILL))
+	}
+
+	bits := secbitNoroot | secbitNorootLocked | secbitNoSetuidFixup | secbitNoSetuidFixupLocked |
+		secbitKeepCapsLocked | secbitNoCapAmbientRaise | secbitNoCapAmbientRaiseLck
+	if err := prctl(prSetSecurebits, uintptr(bits)); err != nil {
+		return fmt.Errorf("set securebits: %v", err)
+	}
+	for capability := 0; capability <= lastCapability; capability++ {
+		if err := prctl(prCapbsetDrop, uintptr(capability)); err != nil && err != syscall.EINVAL {
+			return fmt.Errorf("drop capability %d: %v", capability, err)
+		}
+	}
+	return prctl(prCapAmbient, prCapAmbientClearAll)
+}
+
+// prctl calls prctl(2) on the current thread
+func prctl(option int, arg uintptr) error {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, uintptr(option), arg, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// signalKill reports whether the sandbox killed a job, judged only from how its process ended
+func signalKill(state *os.ProcessState, limits SandboxLimits) SandboxKill {
+	if state == nil {
+		return SandboxKill{}
+	}
+
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return SandboxKill{}
+	}
+
+	switch status.Signal() {
+	case syscall.SIGXCPU:
+		return SandboxKill{Killed: true, Reason: "cpu time limit exceeded"}
+	case syscall.SIGKILL:
+		// The soft and hard CPU limits are equal, so the kernel sends SIGKILL when a job uses up its CPU
+		// time. The limit is checked per tick while rusage is sampled, so the two can differ slightly.
+		cpuTime := state.UserTime() + state.SystemTime()
+		if limits.CPUSeconds > 0 && cpuTime >= time.Duration(limits.CPUSeconds)*time.Second*9/10 {
+			return SandboxKill{Killed: true, Reason: "cpu time limit exceeded"}
+		}
+		return SandboxKill{Killed: true, Reason: "killed by sandbox"}
+	}
+	return SandboxKill{}
+}
+
+// killProcessGroupOnCancel starts the job in its own process group and kills the group on cancellation
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
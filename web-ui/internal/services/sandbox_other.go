@@ -0,0 +1,22 @@
+//go:build !linux
+
+package services
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// newNamespaceSandbox reports that namespaces are not available on this platform
+func newNamespaceSandbox(config SandboxConfig) (Sandbox, error) {
+	return nil, errors.New("namespaces are only supported on linux")
+}
+
+// signalKill is not implemented on this platform
+func signalKill(state *os.ProcessState, limits SandboxLimits) SandboxKill {
+	return SandboxKill{}
+}
+
+// killProcessGroupOnCancel relies on the default cancellation, which kills only the direct child
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
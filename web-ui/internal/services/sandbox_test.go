@@ -0,0 +1,44 @@
+package services
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestMemoryKill(t *testing.T) {
+	limits := SandboxLimits{MemoryMB: 64}
+
+	tests := []struct {
+		name     string
+		output   string
+		exitCode int
+		limits   SandboxLimits
+		want     bool
+	}{
+		{"heap exhausted", "runtime: out of memory: cannot allocate 67108864-byte block (876347392 in use)\nfatal error: out of memory\n", 2, limits, true},
+		{"runtime out of memory", "fatal error: runtime: out of memory\n", 2, limits, true},
+		{"arena unavailable", "fatal error: runtime: cannot map pages in arena address space\n", 2, limits, true},
+		{"startup reservation", "fatal error: failed to reserve page summary memory\n", 2, limits, true},
+		{"failed mmap", "runtime: mmap(0x0, 67108864) failed with errno=12\n", 2, limits, true},
+		{"compiler out of memory", "# example\ncompile: fork/exec /usr/local/go/pkg/tool/compile: cannot allocate memory\n", 1, limits, true},
+		{"ordinary failure", "--- FAIL: TestSum (0.00s)\nFAIL\n", 1, limits, false},
+		{"panic", "panic: runtime error: index out of range [3] with length 3\n", 2, limits, false},
+		{"passed", "fatal error: out of memory\n", 0, limits, false},
+		{"no memory limit", "fatal error: out of memory\n", 2, SandboxLimits{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("/bin/sh", "-c", "exit $0", string(rune('0'+tt.exitCode)))
+			cmd.Run()
+
+			got := memoryKill(cmd.ProcessState, []byte(tt.output), tt.limits)
+			if got.Killed != tt.want {
+				t.Errorf("memoryKill(exit %d, %q) = %+v, want killed %v", tt.exitCode, tt.output, got, tt.want)
+			}
+			if got.Killed && got.Reason != "memory limit exceeded" {
+				t.Errorf("Reason = %q, want %q", got.Reason, "memory limit exceeded")
+			}
+		})
+	}
+}
@@ -1,150 +1,152 @@
 package services
 
 import (
-	"io/ioutil"
-	"path/filepath"
-	"strconv"
-	"strings"
-	"time"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"sync"
 
 	"web-ui/internal/models"
+	"web-ui/internal/scoreboard"
 )
 
 // ScoreboardService handles scoreboard-related operations
 type ScoreboardService struct {
 	scoreboards models.ScoreboardMap
+	store       SubmissionStore // Submissions that passed on this server, replayed on every load
+	mutex       sync.RWMutex
 }
 
 // NewScoreboardService creates a new scoreboard service
-func NewScoreboardService() *ScoreboardService {
+func NewScoreboardService(store SubmissionStore) *ScoreboardService {
 	return &ScoreboardService{
 		scoreboards: make(models.ScoreboardMap),
+		store:       store,
 	}
 }
 
-// LoadScoreboards loads all scoreboards from the filesystem
+// LoadScoreboards loads all scoreboards from the filesystem, replacing any loaded before, and adds the
+// submissions that passed on this server, so they and their speedups survive restarts and reloads
 func (ss *ScoreboardService) LoadScoreboards(challenges models.ChallengeMap) error {
+	scoreboards := make(models.ScoreboardMap)
 	for id := range challenges {
-		challengeDir := filepath.Join("..", "challenge-"+strconv.Itoa(id))
-		ss.loadScoreboardForChallenge(id, challengeDir)
+		challengeDir := ChallengeDir(id)
+		if entries, ok := ss.loadScoreboardForChallenge(id, challengeDir); ok {
+			scoreboards[id] = entries
+		}
 	}
-	return nil
-}
 
-// loadScoreboardForChallenge loads the scoreboard for a specific challenge
-func (ss *ScoreboardService) loadScoreboardForChallenge(id int, dir string) {
-	scoreboardPath := filepath.Join(dir, "SCOREBOARD.md")
-	scoreboardContent, err := ioutil.ReadFile(scoreboardPath)
+	submissions, err := ss.store.Query(SubmissionQuery{Action: models.ActionSubmit})
 	if err != nil {
-		return
+		return fmt.Errorf("failed to read submissions: %v", err)
 	}
-
-	// Parse scoreboard markdown table
-	entries := ss.parseScoreboardMarkdown(string(scoreboardContent), id)
-	ss.scoreboards[id] = entries
-}
-
-// parseScoreboardMarkdown parses the scoreboard markdown table
-func (ss *ScoreboardService) parseScoreboardMarkdown(content string, challengeID int) []models.ScoreboardEntry {
-	lines := strings.Split(content, "\n")
-	entries := []models.ScoreboardEntry{}
-
-	// Determine format by looking at header line
-	var format int = 1 // Default to format 1
-	headerLine := ""
-	for i, line := range lines {
-		if i > 0 && strings.Contains(line, "|") {
-			headerLine = line
-			break
+	// Oldest first, in the order AddSubmission appended them
+	for i := len(submissions) - 1; i >= 0; i-- {
+		submission := submissions[i]
+		if _, ok := challenges[submission.ChallengeID]; ok && submission.Passed {
+			scoreboards[submission.ChallengeID] = append(scoreboards[submission.ChallengeID], scoreboardEntry(submission))
 		}
 	}
 
-	if strings.Contains(headerLine, "Rank") && strings.Contains(headerLine, "Username") {
-		format = 2
-	}
-
-	for i, line := range lines {
-		// Skip header and separator lines
-		if i < 3 {
-			continue
-		}
-
-		// Skip empty lines
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-
-		// Skip separator lines
-		if strings.Contains(line, "---") {
-			continue
-		}
-
-		parts := strings.Split(line, "|")
-		if len(parts) < 3 {
-			continue
-		}
-
-		var username string
-
-		if format == 1 {
-			// Format is: | Username | Passed Tests | Total Tests |
-			username = strings.TrimSpace(parts[1])
-		} else {
-			// Format is: | Rank | Username | Solution | Date Submitted |
-			username = strings.TrimSpace(parts[2])
-		}
-
-		// Skip empty usernames or placeholders
-		if username == "" || username == "------" || ss.isNumeric(username) {
-			continue
-		}
+	ss.mutex.Lock()
+	ss.scoreboards = scoreboards
+	ss.mutex.Unlock()
+	return nil
+}
 
-		// Use current time for existing entries
-		entry := models.ScoreboardEntry{
-			Username:    username,
-			ChallengeID: challengeID,
-			SubmittedAt: time.Now(),
+// loadScoreboardForChallenge loads the scoreboard for a specific challenge
+func (ss *ScoreboardService) loadScoreboardForChallenge(id int, dir string) ([]models.ScoreboardEntry, bool) {
+	board, err := scoreboard.Load(dir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Warning: Could not load scoreboard for challenge %d: %v", id, err)
 		}
-
-		entries = append(entries, entry)
+		return nil, false
 	}
 
-	return entries
-}
-
-// isNumeric checks if a string contains only digits
-func (ss *ScoreboardService) isNumeric(s string) bool {
-	for _, r := range s {
-		if r < '0' || r > '9' {
-			return false
-		}
+	entries := make([]models.ScoreboardEntry, 0, len(board.Entries))
+	for _, entry := range board.Entries {
+		entries = append(entries, models.ScoreboardEntry{
+			Username:    entry.Username,
+			ChallengeID: id,
+			SubmittedAt: entry.SubmittedAt,
+			TestsPassed: entry.TestsPassed,
+			TestsTotal:  entry.TestsTotal,
+			ExecutionMs: entry.ExecutionMs,
+		})
 	}
-	return true
+	return entries, true
 }
 
 // GetScoreboard returns the scoreboard for a specific challenge
 func (ss *ScoreboardService) GetScoreboard(challengeID int) ([]models.ScoreboardEntry, bool) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
 	scoreboard, exists := ss.scoreboards[challengeID]
 	return scoreboard, exists
 }
 
-// GetAllScoreboards returns all scoreboards
+// GetSpeedupRanking returns each user's best benchmark speedup for a challenge, fastest first.
+// Entries loaded from the scoreboard files have no speedup and rank after every benchmarked submission.
+func (ss *ScoreboardService) GetSpeedupRanking(challengeID int) []models.ScoreboardEntry {
+	best := make(map[string]int)
+	ranking := []models.ScoreboardEntry{}
+
+	entries, _ := ss.GetScoreboard(challengeID)
+	for _, entry := range entries {
+		i, seen := best[entry.Username]
+		if !seen {
+			best[entry.Username] = len(ranking)
+			ranking = append(ranking, entry)
+		} else if entry.Speedup > ranking[i].Speedup {
+			ranking[i] = entry
+		}
+	}
+
+	// Stable, so users without a speedup keep their scoreboard order
+	sort.SliceStable(ranking, func(i, j int) bool {
+		return ranking[i].Speedup > ranking[j].Speedup
+	})
+	return ranking
+}
+
+// GetAllScoreboards returns all scoreboards, copied so new submissions do not change the result
 func (ss *ScoreboardService) GetAllScoreboards() models.ScoreboardMap {
-	return ss.scoreboards
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	scoreboards := make(models.ScoreboardMap, len(ss.scoreboards))
+	for id, entries := range ss.scoreboards {
+		scoreboards[id] = entries
+	}
+	return scoreboards
 }
 
 // AddSubmission adds a submission to the scoreboard
 func (ss *ScoreboardService) AddSubmission(submission models.Submission) {
-	entry := models.ScoreboardEntry{
-		Username:    submission.Username,
-		ChallengeID: submission.ChallengeID,
-		SubmittedAt: submission.SubmittedAt,
-	}
+	entry := scoreboardEntry(submission)
 
 	// Add to the scoreboard for this challenge
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
 	if ss.scoreboards[submission.ChallengeID] == nil {
 		ss.scoreboards[submission.ChallengeID] = []models.ScoreboardEntry{}
 	}
 
 	ss.scoreboards[submission.ChallengeID] = append(ss.scoreboards[submission.ChallengeID], entry)
 }
+
+// scoreboardEntry is the scoreboard entry of a passed submission
+func scoreboardEntry(submission models.Submission) models.ScoreboardEntry {
+	return models.ScoreboardEntry{
+		Username:    submission.Username,
+		ChallengeID: submission.ChallengeID,
+		SubmittedAt: submission.SubmittedAt,
+		TestsPassed: submission.TestsPassed,
+		TestsTotal:  submission.TestsTotal,
+		ExecutionMs: submission.ExecutionMs,
+		Speedup:     submission.Speedup,
+	}
+}
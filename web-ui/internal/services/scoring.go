@@ -0,0 +1,62 @@
+package services
+
+import (
+	"math"
+	"strings"
+)
+
+// difficultyPoints are the points a fully passed challenge of each difficulty is worth
+var difficultyPoints = map[string]float64{
+	"beginner":     10,
+	"intermediate": 20,
+	"advanced":     30,
+}
+
+// defaultChallengePoints are the points of a challenge whose difficulty is not one of the above
+const defaultChallengePoints = 20
+
+// defaultFirstTryBonusPercent is the first-try bonus when SCORING_FIRST_TRY_BONUS is not set
+const defaultFirstTryBonusPercent = 10
+
+// ScoringModel turns challenge results into leaderboard points
+type ScoringModel struct {
+	FirstTryBonusPercent int // Extra points, as a percentage of a challenge's points, for passing it on the first submit
+}
+
+// NewScoringModelFromEnv creates a scoring model with the first-try bonus from SCORING_FIRST_TRY_BONUS
+func NewScoringModelFromEnv() ScoringModel {
+	return ScoringModel{
+		FirstTryBonusPercent: getNonNegativeIntFromEnv("SCORING_FIRST_TRY_BONUS", defaultFirstTryBonusPercent),
+	}
+}
+
+// ChallengePoints returns the points a fully passed challenge of a difficulty is worth
+func ChallengePoints(difficulty string) float64 {
+	if points, ok := difficultyPoints[strings.ToLower(difficulty)]; ok {
+		return points
+	}
+	return defaultChallengePoints
+}
+
+// Points returns the points for passing some of a challenge's tests. Partly passed challenges
+// earn their share of the points; firstTry adds the bonus to a fully passed challenge.
+func (m ScoringModel) Points(difficulty string, passed, total int, firstTry bool) float64 {
+	if total <= 0 || passed <= 0 {
+		return 0
+	}
+	if passed > total {
+		passed = total
+	}
+
+	challengePoints := ChallengePoints(difficulty)
+	points := challengePoints * float64(passed) / float64(total)
+	if firstTry && passed == total {
+		points += challengePoints * float64(m.FirstTryBonusPercent) / 100
+	}
+	return points
+}
+
+// RoundPoints rounds points to one decimal place for display
+func RoundPoints(points float64) float64 {
+	return math.Round(points*10) / 10
+}
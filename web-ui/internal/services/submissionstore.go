@@ -0,0 +1,166 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// SubmissionStore persists submissions. Implementations are safe for concurrent use.
+type SubmissionStore interface {
+	// Save stores a submission and returns it with its assigned ID
+	Save(submission models.Submission) (models.Submission, error)
+	// Get returns the submission with an ID
+	Get(id int64) (models.Submission, bool, error)
+	// Query returns the submissions matching a query, newest first
+	Query(query SubmissionQuery) ([]models.Submission, error)
+	// FirstSubmitPasses returns whether each user's first submit of each core and package challenge
+	// passed, by username and submission target
+	FirstSubmitPasses() (map[string]map[SubmissionTarget]bool, error)
+	// Close releases the underlying storage
+	Close() error
+}
+
+// SubmissionQuery filters submissions. Zero fields do not filter, except that an empty PackageName
+// matches core challenges only.
+type SubmissionQuery struct {
+	Username           string
+	ChallengeID        int
+	PackageName        string
+	PackageChallengeID string
+	Action             string    // models.ActionRun or models.ActionSubmit
+	Since              time.Time // Inclusive lower bound on SubmittedAt
+	Until              time.Time // Exclusive upper bound on SubmittedAt
+	Limit              int       // Maximum number of submissions, 0 for all
+}
+
+// matches reports whether a submission passes the query's filters
+func (q SubmissionQuery) matches(submission models.Submission) bool {
+	if q.Username != "" && submission.Username != q.Username {
+		return false
+	}
+	if q.ChallengeID != 0 && submission.ChallengeID != q.ChallengeID {
+		return false
+	}
+	if submission.PackageName != q.PackageName {
+		return false
+	}
+	if q.PackageChallengeID != "" && submission.PackageChallengeID != q.PackageChallengeID {
+		return false
+	}
+	if q.Action != "" && submission.Action != q.Action {
+		return false
+	}
+	if !q.Since.IsZero() && submission.SubmittedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !submission.SubmittedAt.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// SubmissionTargetOf returns the challenge a stored submission is for
+func SubmissionTargetOf(submission models.Submission) SubmissionTarget {
+	if submission.PackageName != "" {
+		return SubmissionTarget{PackageName: submission.PackageName, ChallengeID: submission.PackageChallengeID}
+	}
+	return CoreSubmissionTarget(submission.ChallengeID)
+}
+
+// Submission store backends
+const (
+	SubmissionStoreSQLite = "sqlite"
+	SubmissionStoreJSONL  = "jsonl"
+)
+
+// NewSubmissionStoreFromEnv opens the store selected by SUBMISSION_STORE, SQLite by default.
+// If the SQLite database cannot be opened, submissions fall back to a JSON lines file.
+func NewSubmissionStoreFromEnv() (SubmissionStore, error) {
+	return openStoreFromEnv("Submission",
+		func(path string) (SubmissionStore, error) { return NewSQLiteSubmissionStore(path) },
+		"submissions.jsonl",
+		func(path string) (SubmissionStore, error) { return NewJSONLSubmissionStore(path) },
+	)
+}
+
+// sqliteDatabaseFile is the SQLite database in the data directory that every store keeps its tables in
+const sqliteDatabaseFile = "submissions.db"
+
+// openStoreFromEnv opens a store with the backend SUBMISSION_STORE selects, SQLite by default, in the
+// data directory. If the SQLite database cannot be opened, the store falls back to its own file.
+func openStoreFromEnv[S any](name string, openSQLite func(path string) (S, error), fallbackFile string, openFallback func(path string) (S, error)) (S, error) {
+	var none S
+	dataDir := dataDirFromEnv()
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return none, fmt.Errorf("failed to create data directory %s: %v", dataDir, err)
+	}
+
+	backend := os.Getenv("SUBMISSION_STORE")
+	if backend == "" {
+		backend = SubmissionStoreSQLite
+	}
+
+	switch backend {
+	case SubmissionStoreSQLite:
+		path := filepath.Join(dataDir, sqliteDatabaseFile)
+		store, err := openSQLite(path)
+		if err == nil {
+			log.Printf("%s store: SQLite (%s)", name, path)
+			return store, nil
+		}
+		log.Printf("Warning: Could not open SQLite %s store %s: %v; falling back to %s", strings.ToLower(name), path, err, fallbackFile)
+		fallthrough
+	case SubmissionStoreJSONL:
+		path := filepath.Join(dataDir, fallbackFile)
+		store, err := openFallback(path)
+		if err != nil {
+			return none, err
+		}
+		format := "JSON"
+		if filepath.Ext(path) == ".jsonl" {
+			format = "JSON lines"
+		}
+		log.Printf("%s store: %s (%s)", name, format, path)
+		return store, nil
+	default:
+		return none, fmt.Errorf("unknown SUBMISSION_STORE %q, expected %q or %q", backend, SubmissionStoreSQLite, SubmissionStoreJSONL)
+	}
+}
+
+// openSQLiteDatabase opens or creates the SQLite database at path and creates a store's schema in it
+func openSQLiteDatabase(path, schema string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+
+	// A single connection serializes the store's writers, so its concurrent handlers never see
+	// SQLITE_BUSY. Writers of other stores in the same file wait for the busy timeout.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %v", err)
+	}
+	return db, nil
+}
+
+// dataDirFromEnv returns the directory for the server's persistent data
+func dataDirFromEnv() string {
+	dir := os.Getenv("DATA_DIR")
+	if dir == "" {
+		dir = "data"
+	}
+
+	if absDir, err := filepath.Abs(dir); err == nil {
+		dir = absDir
+	}
+	return dir
+}
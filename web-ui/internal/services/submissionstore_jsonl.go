@@ -0,0 +1,180 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// JSONLSubmissionStore appends submissions to a JSON lines file and keeps them in memory for queries
+type JSONLSubmissionStore struct {
+	mutex       sync.RWMutex
+	file        *os.File
+	submissions []models.Submission // In the order they were saved
+	nextID      int64
+}
+
+// NewJSONLSubmissionStore opens or creates the file at path and loads the submissions it holds
+func NewJSONLSubmissionStore(path string) (*JSONLSubmissionStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &JSONLSubmissionStore{file: file, nextID: 1}
+
+	reader := bufio.NewReader(file)
+	var complete int64 // Offset just past the last line that ends in a newline
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			file.Close()
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		terminated := err == nil
+
+		// A crash mid-write leaves at most a partial last line; skip it rather than refuse to start
+		var submission models.Submission
+		parsed := len(bytes.TrimSpace(line)) > 0 && json.Unmarshal(line, &submission) == nil
+		if parsed {
+			store.submissions = append(store.submissions, submission)
+			if submission.ID >= store.nextID {
+				store.nextID = submission.ID + 1
+			}
+		}
+
+		if terminated {
+			complete += int64(len(line))
+			continue
+		}
+
+		// Appending after an unterminated last line would merge the next record into it. A whole
+		// record that only lost its newline gets it back; a partial one is cut off.
+		if len(line) > 0 {
+			if parsed {
+				_, err = file.Write([]byte{'\n'})
+			} else {
+				err = file.Truncate(complete)
+			}
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("failed to repair %s: %v", path, err)
+			}
+		}
+		break
+	}
+
+	return store, nil
+}
+
+// Save appends a submission and returns it with its assigned ID
+func (s *JSONLSubmissionStore) Save(submission models.Submission) (models.Submission, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	submission.ID = s.nextID
+	data, err := json.Marshal(submission)
+	if err != nil {
+		return submission, err
+	}
+
+	// One write per line keeps concurrent processes from interleaving records
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return submission, err
+	}
+
+	s.nextID++
+	s.submissions = append(s.submissions, submission)
+	return submission, nil
+}
+
+// Get returns the submission with an ID
+func (s *JSONLSubmissionStore) Get(id int64) (models.Submission, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// IDs are assigned in order, so the submissions are sorted by ID
+	i := sort.Search(len(s.submissions), func(i int) bool {
+		return s.submissions[i].ID >= id
+	})
+	if i < len(s.submissions) && s.submissions[i].ID == id {
+		return s.submissions[i], true, nil
+	}
+	return models.Submission{}, false, nil
+}
+
+// Query returns the submissions matching a query, newest first
+func (s *JSONLSubmissionStore) Query(query SubmissionQuery) ([]models.Submission, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	matches := []models.Submission{}
+	for _, submission := range s.submissions {
+		if query.matches(submission) {
+			matches = append(matches, submission)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if !matches[i].SubmittedAt.Equal(matches[j].SubmittedAt) {
+			return matches[i].SubmittedAt.After(matches[j].SubmittedAt)
+		}
+		return matches[i].ID > matches[j].ID
+	})
+
+	if query.Limit > 0 && len(matches) > query.Limit {
+		matches = matches[:query.Limit]
+	}
+	return matches, nil
+}
+
+// FirstSubmitPasses returns whether each user's first submit of each core and package challenge passed
+func (s *JSONLSubmissionStore) FirstSubmitPasses() (map[string]map[SubmissionTarget]bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	type firstSubmit struct {
+		submittedAt time.Time
+		passed      bool
+	}
+	firsts := make(map[string]map[SubmissionTarget]firstSubmit)
+	for _, submission := range s.submissions {
+		if submission.Action != models.ActionSubmit {
+			continue
+		}
+		if firsts[submission.Username] == nil {
+			firsts[submission.Username] = make(map[SubmissionTarget]firstSubmit)
+		}
+		// Submissions are in the order they were saved, so an equal time keeps the earlier one
+		target := SubmissionTargetOf(submission)
+		first, seen := firsts[submission.Username][target]
+		if !seen || submission.SubmittedAt.Before(first.submittedAt) {
+			firsts[submission.Username][target] = firstSubmit{submission.SubmittedAt, submission.Passed}
+		}
+	}
+
+	passes := make(map[string]map[SubmissionTarget]bool, len(firsts))
+	for username, targets := range firsts {
+		passes[username] = make(map[SubmissionTarget]bool, len(targets))
+		for target, first := range targets {
+			passes[username][target] = first.passed
+		}
+	}
+	return passes, nil
+}
+
+// Close closes the file
+func (s *JSONLSubmissionStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.file.Close()
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"web-ui/internal/models"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteSubmissionStore keeps submissions in a SQLite database. The filterable fields
+// are columns; the full submission is stored as JSON next to them. Package challenges are
+// rare enough to be filtered from the JSON, so older databases need no new columns.
+type SQLiteSubmissionStore struct {
+	db *sql.DB
+}
+
+// sqliteSubmissionSchema creates the submissions table and its indexes
+const sqliteSubmissionSchema = `
+CREATE TABLE IF NOT EXISTS submissions (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	action       TEXT    NOT NULL,
+	username     TEXT    NOT NULL,
+	challenge_id INTEGER NOT NULL,
+	submitted_at INTEGER NOT NULL,
+	passed       INTEGER NOT NULL,
+	data         TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS submissions_username ON submissions (username, submitted_at);
+CREATE INDEX IF NOT EXISTS submissions_challenge ON submissions (challenge_id, submitted_at);
+CREATE INDEX IF NOT EXISTS submissions_first ON submissions (action, username, challenge_id, submitted_at);
+`
+
+// sqliteCoreSubmission matches the submissions of core challenges, whose JSON has no packageName
+const sqliteCoreSubmission = "json_extract(data, '$.packageName') IS NULL"
+
+// NewSQLiteSubmissionStore opens or creates its table in the database at path
+func NewSQLiteSubmissionStore(path string) (*SQLiteSubmissionStore, error) {
+	db, err := openSQLiteDatabase(path, sqliteSubmissionSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteSubmissionStore{db: db}, nil
+}
+
+// Save stores a submission and returns it with its assigned ID
+func (s *SQLiteSubmissionStore) Save(submission models.Submission) (models.Submission, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return submission, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO submissions (action, username, challenge_id, submitted_at, passed, data) VALUES (?, ?, ?, ?, ?, '')",
+		submission.Action, submission.Username, submission.ChallengeID, submission.SubmittedAt.UnixNano(), submission.Passed,
+	)
+	if err != nil {
+		return submission, err
+	}
+	if submission.ID, err = result.LastInsertId(); err != nil {
+		return submission, err
+	}
+
+	// The stored JSON includes the ID, so it is written once the row exists
+	data, err := json.Marshal(submission)
+	if err != nil {
+		return submission, err
+	}
+	if _, err := tx.Exec("UPDATE submissions SET data = ? WHERE id = ?", string(data), submission.ID); err != nil {
+		return submission, err
+	}
+
+	return submission, tx.Commit()
+}
+
+// Get returns the submission with an ID
+func (s *SQLiteSubmissionStore) Get(id int64) (models.Submission, bool, error) {
+	var submission models.Submission
+
+	var data string
+	err := s.db.QueryRow("SELECT data FROM submissions WHERE id = ?", id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return submission, false, nil
+	} else if err != nil {
+		return submission, false, err
+	}
+
+	if err := json.Unmarshal([]byte(data), &submission); err != nil {
+		return submission, false, err
+	}
+	return submission, true, nil
+}
+
+// Query returns the submissions matching a query, newest first
+func (s *SQLiteSubmissionStore) Query(query SubmissionQuery) ([]models.Submission, error) {
+	var conditions []string
+	var args []interface{}
+
+	if query.Username != "" {
+		conditions = append(conditions, "username = ?")
+		args = append(args, query.Username)
+	}
+	if query.ChallengeID != 0 {
+		conditions = append(conditions, "challenge_id = ?")
+		args = append(args, query.ChallengeID)
+	}
+	if query.PackageName == "" {
+		conditions = append(conditions, sqliteCoreSubmission)
+	} else {
+		conditions = append(conditions, "json_extract(data, '$.packageName') = ?")
+		args = append(args, query.PackageName)
+	}
+	if query.PackageChallengeID != "" {
+		conditions = append(conditions, "json_extract(data, '$.packageChallengeId') = ?")
+		args = append(args, query.PackageChallengeID)
+	}
+	if query.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, query.Action)
+	}
+	if !query.Since.IsZero() {
+		conditions = append(conditions, "submitted_at >= ?")
+		args = append(args, query.Since.UnixNano())
+	}
+	if !query.Until.IsZero() {
+		conditions = append(conditions, "submitted_at < ?")
+		args = append(args, query.Until.UnixNano())
+	}
+
+	statement := "SELECT data FROM submissions WHERE " + strings.Join(conditions, " AND ")
+	statement += " ORDER BY submitted_at DESC, id DESC"
+	if query.Limit > 0 {
+		statement += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := s.db.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	submissions := []models.Submission{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+
+		var submission models.Submission
+		if err := json.Unmarshal([]byte(data), &submission); err != nil {
+			return nil, err
+		}
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, rows.Err()
+}
+
+// FirstSubmitPasses returns whether each user's first submit of each core and package challenge passed.
+// With a single MIN aggregate, SQLite takes the other columns from the row holding the minimum.
+func (s *SQLiteSubmissionStore) FirstSubmitPasses() (map[string]map[SubmissionTarget]bool, error) {
+	rows, err := s.db.Query(
+		"SELECT username, challenge_id, COALESCE(json_extract(data, '$.packageName'), ''), "+
+			"COALESCE(json_extract(data, '$.packageChallengeId'), ''), passed, MIN(submitted_at) "+
+			"FROM submissions WHERE action = ? GROUP BY username, challenge_id, 3, 4",
+		models.ActionSubmit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passes := make(map[string]map[SubmissionTarget]bool)
+	for rows.Next() {
+		var submission models.Submission
+		var submittedAt int64
+		if err := rows.Scan(&submission.Username, &submission.ChallengeID, &submission.PackageName, &submission.PackageChallengeID, &submission.Passed, &submittedAt); err != nil {
+			return nil, err
+		}
+		if passes[submission.Username] == nil {
+			passes[submission.Username] = make(map[SubmissionTarget]bool)
+		}
+		passes[submission.Username][SubmissionTargetOf(submission)] = submission.Passed
+	}
+
+	return passes, rows.Err()
+}
+
+// Close closes the database
+func (s *SQLiteSubmissionStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+func TestJSONLSubmissionStoreRepairsLastLine(t *testing.T) {
+	saved := `{"id":1,"action":"submit","username":"bob","challengeId":1,"passed":true}`
+	tests := []struct {
+		name    string
+		content string
+		loaded  int // Submissions read from the file before the new save
+	}{
+		{"partial last line", saved + "\n" + `{"id":2,"action":"sub`, 1},
+		{"whole record without its newline", saved, 1},
+		{"partial only line", `{"id":1,"act`, 0},
+		{"empty file", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "submissions.jsonl")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			store, err := NewJSONLSubmissionStore(path)
+			if err != nil {
+				t.Fatalf("NewJSONLSubmissionStore() error = %v", err)
+			}
+			saved, err := store.Save(models.Submission{Action: models.ActionRun, Username: "bobby", ChallengeID: 2, SubmittedAt: time.Now()})
+			if err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			store.Close()
+
+			reopened, err := NewJSONLSubmissionStore(path)
+			if err != nil {
+				t.Fatalf("reopening error = %v", err)
+			}
+			defer reopened.Close()
+
+			if got, ok, _ := reopened.Get(saved.ID); !ok || got.Username != "bobby" {
+				t.Errorf("Get(%d) after reopening = %+v, %v", saved.ID, got, ok)
+			}
+			if all, _ := reopened.Query(SubmissionQuery{}); len(all) != tt.loaded+1 {
+				t.Errorf("reopened store has %d submissions, want %d", len(all), tt.loaded+1)
+			}
+		})
+	}
+}
+
+// openTestSubmissionStores opens an empty store of each kind
+func openTestSubmissionStores(t *testing.T) map[string]SubmissionStore {
+	t.Helper()
+	sqlite, err := NewSQLiteSubmissionStore(filepath.Join(t.TempDir(), "submissions.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteSubmissionStore() error = %v", err)
+	}
+	jsonl, err := NewJSONLSubmissionStore(filepath.Join(t.TempDir(), "submissions.jsonl"))
+	if err != nil {
+		t.Fatalf("NewJSONLSubmissionStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		sqlite.Close()
+		jsonl.Close()
+	})
+	return map[string]SubmissionStore{"sqlite": sqlite, "jsonl": jsonl}
+}
+
+func TestSubmissionStoreQuery(t *testing.T) {
+	start := time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+	seed := []models.Submission{
+		{Action: models.ActionSubmit, Username: "bob", ChallengeID: 1},
+		{Action: models.ActionSubmit, Username: "bob", ChallengeID: 1, Passed: true},
+		{Action: models.ActionRun, Username: "bob", ChallengeID: 1},
+		{Action: models.ActionSubmit, Username: "alice", ChallengeID: 2, Passed: true},
+		{Action: models.ActionSubmit, Username: "bob", PackageName: "gin", PackageChallengeID: "challenge-1", Passed: true},
+		{Action: models.ActionSubmit, Username: "bob", PackageName: "gin", PackageChallengeID: "challenge-2"},
+		{Action: models.ActionRun, Username: "bob", PackageName: "gin", PackageChallengeID: "challenge-2"},
+		{Action: models.ActionSubmit, Username: "bob", PackageName: "echo", PackageChallengeID: "challenge-1", Passed: true},
+	}
+
+	tests := []struct {
+		name  string
+		query SubmissionQuery
+		want  []int // Indexes into seed, newest first
+	}{
+		{"core only by default", SubmissionQuery{}, []int{3, 2, 1, 0}},
+		{"username", SubmissionQuery{Username: "bob"}, []int{2, 1, 0}},
+		{"action", SubmissionQuery{Action: models.ActionSubmit}, []int{3, 1, 0}},
+		{"challenge with limit", SubmissionQuery{ChallengeID: 1, Limit: 2}, []int{2, 1}},
+		{"since inclusive until exclusive", SubmissionQuery{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, []int{2, 1}},
+		{"package", SubmissionQuery{PackageName: "gin"}, []int{6, 5, 4}},
+		{"package challenge and action", SubmissionQuery{PackageName: "gin", PackageChallengeID: "challenge-2", Action: models.ActionSubmit}, []int{5}},
+		{"package challenge without its package", SubmissionQuery{PackageChallengeID: "challenge-1"}, nil},
+	}
+
+	for name, store := range openTestSubmissionStores(t) {
+		ids := make([]int64, len(seed))
+		for i, submission := range seed {
+			submission.SubmittedAt = start.Add(time.Duration(i) * time.Minute)
+			saved, err := store.Save(submission)
+			if err != nil {
+				t.Fatalf("%s: Save() error = %v", name, err)
+			}
+			ids[i] = saved.ID
+		}
+
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				submissions, err := store.Query(tt.query)
+				if err != nil {
+					t.Fatalf("Query() error = %v", err)
+				}
+				var got, want []int64
+				for _, submission := range submissions {
+					got = append(got, submission.ID)
+				}
+				for _, i := range tt.want {
+					want = append(want, ids[i])
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Query() IDs = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestSubmissionStoreFirstSubmitPasses(t *testing.T) {
+	start := time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+	seed := []models.Submission{
+		{Action: models.ActionRun, Username: "bob", ChallengeID: 1, Passed: true},
+		{Action: models.ActionSubmit, Username: "bob", ChallengeID: 1},
+		{Action: models.ActionSubmit, Username: "bob", ChallengeID: 1, Passed: true},
+		{Action: models.ActionSubmit, Username: "alice", ChallengeID: 1, Passed: true},
+		{Action: models.ActionSubmit, Username: "bob", PackageName: "gin", PackageChallengeID: "challenge-1", Passed: true},
+		{Action: models.ActionSubmit, Username: "bob", PackageName: "gin", PackageChallengeID: "challenge-1"},
+		{Action: models.ActionSubmit, Username: "bob", PackageName: "echo", PackageChallengeID: "challenge-1"},
+	}
+	want := map[string]map[SubmissionTarget]bool{
+		"bob": {
+			CoreSubmissionTarget(1):                           false,
+			{PackageName: "gin", ChallengeID: "challenge-1"}:  true,
+			{PackageName: "echo", ChallengeID: "challenge-1"}: false,
+		},
+		"alice": {CoreSubmissionTarget(1): true},
+	}
+
+	for name, store := range openTestSubmissionStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, submission := range seed {
+				submission.SubmittedAt = start.Add(time.Duration(i) * time.Minute)
+				if _, err := store.Save(submission); err != nil {
+					t.Fatalf("Save() error = %v", err)
+				}
+			}
+
+			passes, err := store.FirstSubmitPasses()
+			if err != nil {
+				t.Fatalf("FirstSubmitPasses() error = %v", err)
+			}
+			if !reflect.DeepEqual(passes, want) {
+				t.Errorf("FirstSubmitPasses() = %v, want %v", passes, want)
+			}
+		})
+	}
+}
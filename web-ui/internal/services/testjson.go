@@ -0,0 +1,132 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+
+	"web-ui/internal/models"
+)
+
+// testEvent mirrors the events emitted by go test -json (see `go doc test2json`)
+type testEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Elapsed float64 `json:"Elapsed"`
+	Output  string  `json:"Output"`
+}
+
+// TestReport is the structured form of a go test -json run
+type TestReport struct {
+	Tests   []*models.TestCase
+	Summary models.TestSummary
+	Output  string // Plain text log reconstructed from the event stream
+}
+
+// ParseTestJSON builds the per-test tree from go test -json output.
+// Lines that are not JSON events (such as build errors on stderr) are kept in the plain log.
+func ParseTestJSON(raw string) TestReport {
+	report := TestReport{Tests: []*models.TestCase{}}
+	var log strings.Builder
+	cases := make(map[string]*models.TestCase)
+
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		var event testEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &event) != nil || event.Action == "" {
+			log.WriteString(line + "\n")
+			continue
+		}
+
+		if event.Output != "" {
+			log.WriteString(event.Output)
+		}
+
+		// Package level events carry no test name
+		if event.Test == "" {
+			continue
+		}
+
+		testCase := cases[event.Test]
+		if testCase == nil {
+			testCase = &models.TestCase{
+				Name:     event.Test,
+				Status:   "incomplete",
+				Subtests: []*models.TestCase{},
+			}
+			cases[event.Test] = testCase
+			if parent := findParentTest(cases, event.Test); parent != nil {
+				parent.Subtests = append(parent.Subtests, testCase)
+			} else {
+				report.Tests = append(report.Tests, testCase)
+			}
+		}
+
+		switch event.Action {
+		case "output":
+			if !isTestFramingLine(event.Output) {
+				testCase.Output += event.Output
+			}
+		case "pass", "fail", "skip":
+			testCase.Status = event.Action
+			testCase.Elapsed = event.Elapsed
+		}
+	}
+
+	report.Output = log.String()
+	report.Summary = summarizeTests(report.Tests)
+	return report
+}
+
+// findParentTest returns the closest enclosing test of a subtest name
+func findParentTest(cases map[string]*models.TestCase, name string) *models.TestCase {
+	// Subtest names may themselves contain slashes, so try every prefix from the longest
+	for i := strings.LastIndex(name, "/"); i > 0; i = strings.LastIndex(name[:i], "/") {
+		if parent, ok := cases[name[:i]]; ok {
+			return parent
+		}
+	}
+	return nil
+}
+
+// isTestFramingLine reports whether an output line is go test's own RUN/PASS/FAIL bookkeeping
+func isTestFramingLine(output string) bool {
+	trimmed := strings.TrimSpace(output)
+	for _, prefix := range []string{"=== RUN", "=== PAUSE", "=== CONT", "=== NAME", "--- PASS:", "--- FAIL:", "--- SKIP:"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// summarizeTests counts leaf test cases, so a parent with subtests is not counted twice
+func summarizeTests(tests []*models.TestCase) models.TestSummary {
+	var summary models.TestSummary
+	for _, testCase := range tests {
+		if len(testCase.Subtests) > 0 {
+			sub := summarizeTests(testCase.Subtests)
+			summary.Passed += sub.Passed
+			summary.Failed += sub.Failed
+			summary.Skipped += sub.Skipped
+			summary.Total += sub.Total
+			continue
+		}
+
+		summary.Total++
+		switch testCase.Status {
+		case "pass":
+			summary.Passed++
+		case "skip":
+			summary.Skipped++
+		default:
+			summary.Failed++
+		}
+	}
+	return summary
+}
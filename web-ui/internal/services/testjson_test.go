@@ -0,0 +1,146 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"web-ui/internal/models"
+)
+
+// testEventStream joins go test -json event lines into one stream
+func testEventStream(lines ...string) string {
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestParseTestJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		want       string // Test tree as name=status, subtests in brackets
+		summary    models.TestSummary
+		outputs    map[string]string // Output of a test by name, framing lines excluded
+		logContain []string
+	}{
+		{
+			name: "pass and fail",
+			raw: testEventStream(
+				`{"Action":"run","Test":"TestSum"}`,
+				`{"Action":"output","Test":"TestSum","Output":"=== RUN   TestSum\n"}`,
+				`{"Action":"output","Test":"TestSum","Output":"--- PASS: TestSum (0.00s)\n"}`,
+				`{"Action":"pass","Test":"TestSum","Elapsed":0.01}`,
+				`{"Action":"run","Test":"TestDiff"}`,
+				`{"Action":"output","Test":"TestDiff","Output":"    diff_test.go:9: got 1, want 2\n"}`,
+				`{"Action":"fail","Test":"TestDiff","Elapsed":0.02}`,
+				`{"Action":"fail","Elapsed":0.03}`,
+			),
+			want:       "TestSum=pass TestDiff=fail",
+			summary:    models.TestSummary{Passed: 1, Failed: 1, Total: 2},
+			outputs:    map[string]string{"TestSum": "", "TestDiff": "    diff_test.go:9: got 1, want 2\n"},
+			logContain: []string{"=== RUN   TestSum", "got 1, want 2"},
+		},
+		{
+			name: "subtests counted as leaves",
+			raw: testEventStream(
+				`{"Action":"run","Test":"TestAdd"}`,
+				`{"Action":"run","Test":"TestAdd/positive"}`,
+				`{"Action":"pass","Test":"TestAdd/positive"}`,
+				`{"Action":"run","Test":"TestAdd/negative"}`,
+				`{"Action":"skip","Test":"TestAdd/negative"}`,
+				`{"Action":"fail","Test":"TestAdd"}`,
+			),
+			want:    "TestAdd=fail[TestAdd/positive=pass TestAdd/negative=skip]",
+			summary: models.TestSummary{Passed: 1, Skipped: 1, Total: 2},
+		},
+		{
+			name: "subtest names with slashes",
+			raw: testEventStream(
+				`{"Action":"run","Test":"TestPath"}`,
+				`{"Action":"run","Test":"TestPath/a/b"}`,
+				`{"Action":"run","Test":"TestPath/a/b/c"}`,
+				`{"Action":"pass","Test":"TestPath/a/b/c"}`,
+				`{"Action":"pass","Test":"TestPath/a/b"}`,
+				`{"Action":"pass","Test":"TestPath"}`,
+			),
+			want:    "TestPath=pass[TestPath/a/b=pass[TestPath/a/b/c=pass]]",
+			summary: models.TestSummary{Passed: 1, Total: 1},
+		},
+		{
+			name: "stopped run leaves tests incomplete",
+			raw: testEventStream(
+				`{"Action":"run","Test":"TestLoop"}`,
+				`{"Action":"output","Test":"TestLoop","Output":"still going\n"}`,
+			),
+			want:    "TestLoop=incomplete",
+			summary: models.TestSummary{Failed: 1, Total: 1},
+			outputs: map[string]string{"TestLoop": "still going\n"},
+		},
+		{
+			name:       "build errors kept in the log",
+			raw:        "# example\n./solution-template.go:5:2: undefined: x\n",
+			want:       "",
+			logContain: []string{"undefined: x"},
+		},
+		{
+			name: "output that looks like json",
+			raw: testEventStream(
+				`{"not":"an event"}`,
+				`{"Action":"pass","Test":"TestOK"}`,
+			),
+			want:       "TestOK=pass",
+			summary:    models.TestSummary{Passed: 1, Total: 1},
+			logContain: []string{`{"not":"an event"}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := ParseTestJSON(tt.raw)
+
+			if got := formatTestTree(report.Tests); got != tt.want {
+				t.Errorf("tests = %q, want %q", got, tt.want)
+			}
+			if report.Summary != tt.summary {
+				t.Errorf("summary = %+v, want %+v", report.Summary, tt.summary)
+			}
+			for name, want := range tt.outputs {
+				testCase := findTestCase(report.Tests, name)
+				if testCase == nil {
+					t.Errorf("test %s missing", name)
+				} else if testCase.Output != want {
+					t.Errorf("output of %s = %q, want %q", name, testCase.Output, want)
+				}
+			}
+			for _, want := range tt.logContain {
+				if !strings.Contains(report.Output, want) {
+					t.Errorf("log %q does not contain %q", report.Output, want)
+				}
+			}
+		})
+	}
+}
+
+// formatTestTree writes a test tree as name=status, with subtests in brackets
+func formatTestTree(tests []*models.TestCase) string {
+	var parts []string
+	for _, testCase := range tests {
+		part := testCase.Name + "=" + testCase.Status
+		if len(testCase.Subtests) > 0 {
+			part += "[" + formatTestTree(testCase.Subtests) + "]"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// findTestCase finds a test anywhere in a tree by its full name
+func findTestCase(tests []*models.TestCase, name string) *models.TestCase {
+	for _, testCase := range tests {
+		if testCase.Name == name {
+			return testCase
+		}
+		if found := findTestCase(testCase.Subtests, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -1,15 +1,12 @@
 package services
 
 import (
-	"fmt"
 	"io/ioutil"
 	"os"
-	"path/filepath"
-	"strconv"
-	"strings"
 	"sync"
 
 	"web-ui/internal/models"
+	"web-ui/internal/scoreboard"
 )
 
 // UserService handles user-related operations
@@ -61,24 +58,14 @@ func (us *UserService) LoadUserAttempts(username string, challenges models.Chall
 
 // hasUserSubmission checks if a user has a submission for a challenge
 func (us *UserService) hasUserSubmission(username string, challengeID int) bool {
-	// Try different path formats to handle potential path issues
-	// Absolute path
-	submissionDir := filepath.Join("..", fmt.Sprintf("challenge-%d", challengeID), "submissions", username)
-	submissionFile := filepath.Join(submissionDir, "solution-template.go")
-
-	// Check if the file exists
-	if _, err := os.Stat(submissionFile); err == nil {
-		return true
-	}
-
-	// Alternative path (direct from workspace root)
-	altSubmissionFile := filepath.Join(fmt.Sprintf("challenge-%d", challengeID), "submissions", username, "solution-template.go")
-
-	if _, err := os.Stat(altSubmissionFile); err == nil {
-		return true
+	submissionFile, err := SubmissionPath(CoreSubmissionTarget(challengeID), username)
+	if err != nil {
+		return false
 	}
 
-	return false
+	// Check if the file exists
+	_, err = os.Stat(WorkspacePath(submissionFile))
+	return err == nil
 }
 
 // GetExistingSolution returns the content of an existing solution file if it exists
@@ -87,22 +74,16 @@ func (us *UserService) GetExistingSolution(username string, challengeID int) str
 		return ""
 	}
 
-	// Try different path formats
-	// First try the relative path from web-ui
-	submissionFile := filepath.Join("..", fmt.Sprintf("challenge-%d", challengeID), "submissions", username, "solution-template.go")
-	content, err := ioutil.ReadFile(submissionFile)
-	if err == nil {
-		return string(content)
+	submissionFile, err := SubmissionPath(CoreSubmissionTarget(challengeID), username)
+	if err != nil {
+		return ""
 	}
 
-	// Try alternative path from root directory
-	altSubmissionFile := filepath.Join(fmt.Sprintf("challenge-%d", challengeID), "submissions", username, "solution-template.go")
-	content, err = ioutil.ReadFile(altSubmissionFile)
-	if err == nil {
-		return string(content)
+	content, err := ioutil.ReadFile(WorkspacePath(submissionFile))
+	if err != nil {
+		return ""
 	}
-
-	return ""
+	return string(content)
 }
 
 // RefreshUserAttempts clears the cache for a user and reloads their attempts
@@ -115,6 +96,15 @@ func (us *UserService) RefreshUserAttempts(username string, challenges models.Ch
 	return us.LoadUserAttempts(username, challenges)
 }
 
+// ClearCache drops every user's cached attempts, returning how many users were cached
+func (us *UserService) ClearCache() int {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+	cleared := len(us.userAttempts)
+	us.userAttempts = make(models.UserAttemptsMap)
+	return cleared
+}
+
 // GetUserAttempts returns the cached user attempts or loads them if not cached
 func (us *UserService) GetUserAttempts(username string, challenges models.ChallengeMap) *models.UserAttemptedChallenges {
 	us.mutex.RLock()
@@ -128,44 +118,18 @@ func (us *UserService) GetUserAttempts(username string, challenges models.Challe
 
 // calculateScore calculates the score for a user's submission for a challenge
 func (us *UserService) calculateScore(username string, challengeID int) int {
-	// Read the scoreboard file for this challenge
-	scoreboardPath := filepath.Join("..", fmt.Sprintf("challenge-%d", challengeID), "SCOREBOARD.md")
-	content, err := ioutil.ReadFile(scoreboardPath)
+	board, err := scoreboard.Load(ChallengeDir(challengeID))
 	if err != nil {
-		// Try alternative path
-		scoreboardPath = filepath.Join(fmt.Sprintf("challenge-%d", challengeID), "SCOREBOARD.md")
-		content, err = ioutil.ReadFile(scoreboardPath)
-		if err != nil {
-			// No scoreboard file, return default score
-			return 50
-		}
+		// No scoreboard file, return default score
+		return 50
 	}
 
-	scoreboardContent := string(content)
-
-	// Parse the scoreboard to find this user's results
-	lines := strings.Split(scoreboardContent, "\n")
-	for _, line := range lines {
-		// Look for lines that contain the username
-		if strings.Contains(line, username) && strings.Contains(line, "|") {
-			// Parse the table row: | Username | Passed Tests | Total Tests |
-			parts := strings.Split(line, "|")
-			if len(parts) >= 4 {
-				passedStr := strings.TrimSpace(parts[2])
-				totalStr := strings.TrimSpace(parts[3])
-
-				passed, err1 := strconv.Atoi(passedStr)
-				total, err2 := strconv.Atoi(totalStr)
-
-				if err1 == nil && err2 == nil && total > 0 {
-					// Calculate percentage score
-					score := (passed * 100) / total
-					return score
-				}
-			}
-		}
+	entry, found := board.Find(username)
+	if !found || entry.TestsTotal == 0 {
+		// User not found in scoreboard, return 0
+		return 0
 	}
 
-	// User not found in scoreboard, return 0
-	return 0
+	// Calculate percentage score
+	return (entry.TestsPassed * 100) / entry.TestsTotal
 }
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"web-ui/internal/scoreboard"
+	"web-ui/internal/utils"
+)
+
+const (
+	githubAPIURL = "https://api.github.com"
+
+	// deliveryRetention is how long a delivery ID is remembered to reject replays
+	deliveryRetention = 72 * time.Hour
+
+	// maxWebhookFiles bounds the files one delivery can re-ingest, matching what GitHub lists for a comparison
+	maxWebhookFiles = 300
+
+	// maxPullRequestFilePages bounds the pages of a pull request's files, which GitHub caps at 3000 files
+	maxPullRequestFilePages = 30
+)
+
+// repositoryPattern matches an owner/name repository from a webhook payload
+var repositoryPattern = regexp.MustCompile(`^[a-zA-Z0-9-]+/[a-zA-Z0-9._-]+$`)
+
+// WebhookService verifies GitHub webhook deliveries and re-ingests the submissions and scoreboards
+// they change. Deliveries change the repository on GitHub, not the local checkout, so changed files
+// are fetched at the pushed commit and written into the workspace.
+type WebhookService struct {
+	secret     []byte
+	repository string // owner/name of the repository deliveries may re-ingest
+	token      string
+	apiURL     string
+	client     *http.Client
+	deliveries WebhookDeliveryStore // Delivery IDs seen within deliveryRetention
+	ingest     sync.Mutex           // Serializes workspace writes from overlapping deliveries
+}
+
+// WebhookFile is a repository file a delivery changed that the server reads
+type WebhookFile struct {
+	Path               string // Relative to the workspace root, with forward slashes
+	ChallengeID        int    // Core challenge, or 0 for a package challenge
+	PackageName        string
+	PackageChallengeID string
+	Username           string // Set for solutions, empty for scoreboards
+}
+
+// WebhookIngest lists the workspace files a delivery updated and removed
+type WebhookIngest struct {
+	Updated []WebhookFile
+	Removed []WebhookFile
+}
+
+// NewWebhookServiceFromEnv reads the webhook secret from GITHUB_WEBHOOK_SECRET. Without it every
+// delivery is rejected. GITHUB_WEBHOOK_REPOSITORY, as owner/name, is the only repository whose pushes
+// and merged pull requests are re-ingested. GITHUB_TOKEN or GH_TOKEN, if set, authenticates the
+// fetches of changed files. Delivery IDs are kept in deliveries to reject replays.
+func NewWebhookServiceFromEnv(deliveries WebhookDeliveryStore) *WebhookService {
+	ws := &WebhookService{
+		secret:     []byte(os.Getenv("GITHUB_WEBHOOK_SECRET")),
+		repository: os.Getenv("GITHUB_WEBHOOK_REPOSITORY"),
+		token:      os.Getenv("GITHUB_TOKEN"),
+		apiURL:     githubAPIURL,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		deliveries: deliveries,
+	}
+	if ws.token == "" {
+		ws.token = os.Getenv("GH_TOKEN")
+	}
+	if apiURL := os.Getenv("GITHUB_API_URL"); apiURL != "" {
+		ws.apiURL = strings.TrimSuffix(apiURL, "/")
+	}
+
+	if !ws.Enabled() {
+		log.Printf("Warning: GITHUB_WEBHOOK_SECRET is not set, so GitHub webhook deliveries are rejected")
+	} else if !repositoryPattern.MatchString(ws.repository) {
+		log.Printf("Warning: GITHUB_WEBHOOK_REPOSITORY is not set to an owner/name repository, so push and pull request deliveries are rejected")
+		ws.repository = ""
+	}
+	return ws
+}
+
+// Enabled reports whether a secret is configured to verify deliveries
+func (ws *WebhookService) Enabled() bool {
+	return len(ws.secret) > 0
+}
+
+// Verify checks an X-Hub-Signature-256 header, "sha256=" and the hex HMAC-SHA256 of the body
+func (ws *WebhookService) Verify(signature string, body []byte) bool {
+	if !ws.Enabled() || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	received, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, ws.secret)
+	mac.Write(body)
+	return hmac.Equal(received, mac.Sum(nil))
+}
+
+// AllowsRepository reports whether deliveries from a repository may be re-ingested: only the
+// configured GITHUB_WEBHOOK_REPOSITORY, compared case-insensitively as GitHub does
+func (ws *WebhookService) AllowsRepository(repository string) bool {
+	return ws.repository != "" && strings.EqualFold(repository, ws.repository)
+}
+
+// Remember records a delivery ID, returning false if it was already seen and so is a replay.
+// IDs are kept for deliveryRetention.
+func (ws *WebhookService) Remember(delivery string) (bool, error) {
+	now := time.Now()
+	return ws.deliveries.Remember(delivery, now, now.Add(-deliveryRetention))
+}
+
+// Forget drops a delivery ID whose processing failed, so GitHub's redelivery of it is accepted
+func (ws *WebhookService) Forget(delivery string) {
+	if err := ws.deliveries.Forget(delivery); err != nil {
+		log.Printf("Error forgetting webhook delivery %s: %v", delivery, err)
+	}
+}
+
+// WebhookFiles picks the paths the server reads out of a delivery's changed paths: solutions under
+// a challenge's submissions directory, and scoreboards. Duplicates and other files are dropped.
+func WebhookFiles(paths []string) []WebhookFile {
+	var files []WebhookFile
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if file, ok := webhookFile(p); ok {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// webhookFile classifies a repository path, checking every element the way a submission path is checked
+func webhookFile(p string) (WebhookFile, bool) {
+	if path.Clean(p) != p {
+		return WebhookFile{}, false
+	}
+	file := WebhookFile{Path: p}
+	parts := strings.Split(p, "/")
+
+	// Leave the challenge directory's own elements in parts
+	switch {
+	case len(parts) >= 2 && strings.HasPrefix(parts[0], "challenge-"):
+		id, err := strconv.Atoi(strings.TrimPrefix(parts[0], "challenge-"))
+		if err != nil || id <= 0 || parts[0] != fmt.Sprintf("challenge-%d", id) {
+			return WebhookFile{}, false
+		}
+		file.ChallengeID = id
+		parts = parts[1:]
+	case len(parts) >= 4 && parts[0] == "packages":
+		if !pathSegmentPattern.MatchString(parts[1]) || !pathSegmentPattern.MatchString(parts[2]) {
+			return WebhookFile{}, false
+		}
+		file.PackageName, file.PackageChallengeID = parts[1], parts[2]
+		parts = parts[3:]
+	default:
+		return WebhookFile{}, false
+	}
+
+	switch {
+	case len(parts) == 1:
+		return file, parts[0] == scoreboard.MarkdownFileName || parts[0] == scoreboard.JSONFileName
+	case len(parts) == 3 && parts[0] == "submissions":
+		if !ValidGitHubUsername(parts[1]) || !pathSegmentPattern.MatchString(parts[2]) || !strings.HasSuffix(parts[2], ".go") {
+			return WebhookFile{}, false
+		}
+		file.Username = parts[1]
+		return file, true
+	}
+	return WebhookFile{}, false
+}
+
+// PullRequestFiles lists the paths a pull request changed, including the old paths of renamed files
+func (ws *WebhookService) PullRequestFiles(ctx context.Context, repository string, number int) ([]string, error) {
+	if !repositoryPattern.MatchString(repository) {
+		return nil, fmt.Errorf("invalid repository %q", repository)
+	}
+
+	var paths []string
+	for page := 1; page <= maxPullRequestFilePages; page++ {
+		var files []githubChangedFile
+		endpoint := fmt.Sprintf("%s/repos/%s/pulls/%d/files?per_page=100&page=%d", ws.apiURL, repository, number, page)
+		if err := ws.getJSON(ctx, endpoint, &files); err != nil {
+			return nil, err
+		}
+		paths = appendChangedPaths(paths, files)
+		if len(files) < 100 {
+			break
+		}
+	}
+	return paths, nil
+}
+
+// CompareFiles lists the paths changed between two commits, for pushes too large for their payload to list every commit
+func (ws *WebhookService) CompareFiles(ctx context.Context, repository, base, head string) ([]string, error) {
+	if !repositoryPattern.MatchString(repository) {
+		return nil, fmt.Errorf("invalid repository %q", repository)
+	}
+
+	var comparison struct {
+		Files []githubChangedFile `json:"files"`
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/compare/%s...%s", ws.apiURL, repository, url.PathEscape(base), url.PathEscape(head))
+	if err := ws.getJSON(ctx, endpoint, &comparison); err != nil {
+		return nil, err
+	}
+	return appendChangedPaths(nil, comparison.Files), nil
+}
+
+// githubChangedFile is a file in a pull request's or comparison's file list
+type githubChangedFile struct {
+	Filename         string `json:"filename"`
+	PreviousFilename string `json:"previous_filename"`
+}
+
+// appendChangedPaths appends each file's path, and its old path if it was renamed
+func appendChangedPaths(paths []string, files []githubChangedFile) []string {
+	for _, file := range files {
+		paths = append(paths, file.Filename)
+		if file.PreviousFilename != "" {
+			paths = append(paths, file.PreviousFilename)
+		}
+	}
+	return paths
+}
+
+// Ingest brings the workspace copies of files in line with a repository at ref: files that exist at
+// ref are fetched and written atomically, and files missing there are removed. It stops at the first
+// failed fetch, returning what was done so far.
+func (ws *WebhookService) Ingest(ctx context.Context, repository, ref string, files []WebhookFile) (WebhookIngest, error) {
+	var result WebhookIngest
+	if !repositoryPattern.MatchString(repository) {
+		return result, fmt.Errorf("invalid repository %q", repository)
+	}
+	if len(files) > maxWebhookFiles {
+		log.Printf("Warning: Delivery changed %d submission files, only the first %d are re-ingested", len(files), maxWebhookFiles)
+		files = files[:maxWebhookFiles]
+	}
+
+	ws.ingest.Lock()
+	defer ws.ingest.Unlock()
+
+	for _, file := range files {
+		data, err := ws.fetchFile(ctx, repository, ref, file.Path)
+		localPath := WorkspacePath(strings.Split(file.Path, "/")...)
+
+		if errors.Is(err, errFileNotFound) {
+			if err := os.Remove(localPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return result, err
+			}
+			os.Remove(filepath.Dir(localPath)) // The user's directory, if that was their last file
+			result.Removed = append(result.Removed, file)
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		if err := os.MkdirAll(WorkspacePath(strings.Split(path.Dir(file.Path), "/")...), 0755); err != nil {
+			return result, err
+		}
+		if err := utils.WriteFileAtomic(localPath, data, 0644); err != nil {
+			return result, err
+		}
+		result.Updated = append(result.Updated, file)
+	}
+	return result, nil
+}
+
+// errFileNotFound is returned by fetchFile for a file that does not exist at the ref
+var errFileNotFound = errors.New("file not found")
+
+// fetchFile returns a file's raw contents at ref through the contents API
+func (ws *WebhookService) fetchFile(ctx context.Context, repository, ref, filePath string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s", ws.apiURL, repository, filePath, url.QueryEscape(ref))
+	resp, err := ws.get(ctx, endpoint, "application/vnd.github.raw")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errFileNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub returned status %d for %s", resp.StatusCode, filePath)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// getJSON decodes a GitHub API response
+func (ws *WebhookService) getJSON(ctx context.Context, endpoint string, value interface{}) error {
+	resp, err := ws.get(ctx, endpoint, "application/vnd.github+json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub returned status %d for %s", resp.StatusCode, endpoint)
+	}
+	return json.NewDecoder(resp.Body).Decode(value)
+}
+
+// get sends an authenticated GitHub API request
+func (ws *WebhookService) get(ctx context.Context, endpoint, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", "go-interview-practice-web-ui/1.0")
+	if ws.token != "" {
+		req.Header.Set("Authorization", "Bearer "+ws.token)
+	}
+	return ws.client.Do(req)
+}
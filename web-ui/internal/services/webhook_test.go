@@ -0,0 +1,231 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sign returns the X-Hub-Signature-256 header GitHub sends for a body
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookVerify(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	valid := sign("test-secret", body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		body      []byte
+		want      bool
+	}{
+		{"signed body", "test-secret", valid, body, true},
+		{"uppercase hex", "test-secret", "sha256=" + strings.ToUpper(strings.TrimPrefix(valid, "sha256=")), body, true},
+		{"signed with another secret", "test-secret", sign("other-secret", body), body, false},
+		{"changed body", "test-secret", valid, []byte(`{"ref":"refs/heads/evil"}`), false},
+		{"missing prefix", "test-secret", strings.TrimPrefix(valid, "sha256="), body, false},
+		{"sha1 prefix", "test-secret", "sha1=" + strings.TrimPrefix(valid, "sha256="), body, false},
+		{"not hex", "test-secret", "sha256=not-hex", body, false},
+		{"truncated", "test-secret", valid[:len(valid)-2], body, false},
+		{"empty signature", "test-secret", "", body, false},
+		{"no secret configured", "", sign("", body), body, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := &WebhookService{secret: []byte(tt.secret)}
+			if got := ws.Verify(tt.signature, tt.body); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// deliveryStores opens each delivery store backend on a fresh file, reopening the same file when called again
+var deliveryStores = []struct {
+	name string
+	file string
+	open func(path string) (WebhookDeliveryStore, error)
+}{
+	{"json", "webhook_deliveries.json", func(path string) (WebhookDeliveryStore, error) { return NewJSONWebhookDeliveryStore(path) }},
+	{"sqlite", sqliteDatabaseFile, func(path string) (WebhookDeliveryStore, error) { return NewSQLiteWebhookDeliveryStore(path) }},
+}
+
+func TestWebhookRemember(t *testing.T) {
+	for _, backend := range deliveryStores {
+		t.Run(backend.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), backend.file)
+			store, err := backend.open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ws := &WebhookService{deliveries: store}
+
+			steps := []struct {
+				action   string // "remember" or "forget"
+				delivery string
+				want     bool
+			}{
+				{"remember", "delivery-1", true},
+				{"remember", "delivery-1", false},
+				{"remember", "delivery-2", true},
+				{"forget", "delivery-1", false},
+				{"remember", "delivery-1", true},
+				{"forget", "never-seen", false},
+				{"remember", "delivery-2", false},
+			}
+			for i, step := range steps {
+				if step.action == "forget" {
+					ws.Forget(step.delivery)
+					continue
+				}
+				got, err := ws.Remember(step.delivery)
+				if err != nil {
+					t.Fatalf("step %d: Remember(%q) error = %v", i, step.delivery, err)
+				}
+				if got != step.want {
+					t.Errorf("step %d: Remember(%q) = %v, want %v", i, step.delivery, got, step.want)
+				}
+			}
+
+			// A restart still rejects the deliveries already handled
+			if err := store.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if store, err = backend.open(path); err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			ws = &WebhookService{deliveries: store}
+			for _, delivery := range []string{"delivery-1", "delivery-2"} {
+				if fresh, err := ws.Remember(delivery); err != nil || fresh {
+					t.Errorf("Remember(%q) after reopening = %v, %v; want a replay", delivery, fresh, err)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookDeliveryExpiry(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, backend := range deliveryStores {
+		t.Run(backend.name, func(t *testing.T) {
+			store, err := backend.open(filepath.Join(t.TempDir(), backend.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+
+			remember := func(delivery string, at time.Time) bool {
+				t.Helper()
+				fresh, err := store.Remember(delivery, at, at.Add(-deliveryRetention))
+				if err != nil {
+					t.Fatalf("Remember(%q) error = %v", delivery, err)
+				}
+				return fresh
+			}
+
+			remember("old", start)
+			remember("recent", start.Add(deliveryRetention/2))
+
+			later := start.Add(deliveryRetention + time.Hour)
+			if !remember("old", later) {
+				t.Error("a delivery seen before the retention window was still rejected")
+			}
+			if remember("recent", later) {
+				t.Error("a delivery seen within the retention window was accepted again")
+			}
+		})
+	}
+}
+
+func TestWebhookFiles(t *testing.T) {
+	tests := []struct {
+		path string
+		want *WebhookFile // nil if the path is dropped
+	}{
+		{"challenge-1/submissions/octocat/solution-template.go",
+			&WebhookFile{Path: "challenge-1/submissions/octocat/solution-template.go", ChallengeID: 1, Username: "octocat"}},
+		{"challenge-12/SCOREBOARD.md", &WebhookFile{Path: "challenge-12/SCOREBOARD.md", ChallengeID: 12}},
+		{"challenge-12/scoreboard.json", &WebhookFile{Path: "challenge-12/scoreboard.json", ChallengeID: 12}},
+		{"packages/gin/challenge-1-basic-routing/submissions/octocat/solution.go",
+			&WebhookFile{Path: "packages/gin/challenge-1-basic-routing/submissions/octocat/solution.go", PackageName: "gin", PackageChallengeID: "challenge-1-basic-routing", Username: "octocat"}},
+		{"packages/gin/challenge-1-basic-routing/SCOREBOARD.md",
+			&WebhookFile{Path: "packages/gin/challenge-1-basic-routing/SCOREBOARD.md", PackageName: "gin", PackageChallengeID: "challenge-1-basic-routing"}},
+
+		// Files the server does not read
+		{"README.md", nil},
+		{"challenge-1/README.md", nil},
+		{"challenge-1/solution-template.go", nil},
+		{"challenge-1/submissions/octocat/notes.txt", nil},
+		{"challenge-1/submissions/octocat/nested/solution.go", nil},
+		{"packages/gin/SCOREBOARD.md", nil},
+		{"packages/gin/README.md", nil},
+
+		// Challenge directories that are not a positive number as written
+		{"challenge-0/SCOREBOARD.md", nil},
+		{"challenge--1/SCOREBOARD.md", nil},
+		{"challenge-01/SCOREBOARD.md", nil},
+		{"challenge-x/SCOREBOARD.md", nil},
+
+		// Usernames GitHub would not allow
+		{"challenge-1/submissions/-octocat/solution.go", nil},
+		{"challenge-1/submissions/octo_cat/solution.go", nil},
+		{"challenge-1/submissions/../solution.go", nil},
+
+		// Paths that are absolute, unclean or climb out of their directory
+		{"/challenge-1/SCOREBOARD.md", nil},
+		{"/etc/passwd", nil},
+		{"challenge-1/../challenge-2/SCOREBOARD.md", nil},
+		{"challenge-1/submissions/octocat/../../../../etc/solution.go", nil},
+		{"challenge-1/./SCOREBOARD.md", nil},
+		{"challenge-1//SCOREBOARD.md", nil},
+		{"challenge-1/SCOREBOARD.md/", nil},
+		{"packages/../challenge-1/SCOREBOARD.md", nil},
+		{"packages/gin/../../SCOREBOARD.md", nil},
+		{"packages/.hidden/challenge-1/SCOREBOARD.md", nil},
+		{"packages/gin/..challenge/SCOREBOARD.md", nil},
+		{"packages/gin/challenge-1/submissions/octocat/.go", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := webhookFile(tt.path)
+			if ok != (tt.want != nil) {
+				t.Fatalf("webhookFile(%q) ok = %v, want %v", tt.path, ok, tt.want != nil)
+			}
+			if tt.want != nil && got != *tt.want {
+				t.Errorf("webhookFile(%q) = %+v, want %+v", tt.path, got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookFilesDropsDuplicates(t *testing.T) {
+	got := WebhookFiles([]string{
+		"challenge-1/SCOREBOARD.md",
+		"README.md",
+		"challenge-1/SCOREBOARD.md",
+		"challenge-1/submissions/octocat/solution-template.go",
+		"challenge-1/submissions/../../etc/passwd",
+	})
+	want := []WebhookFile{
+		{Path: "challenge-1/SCOREBOARD.md", ChallengeID: 1},
+		{Path: "challenge-1/submissions/octocat/solution-template.go", ChallengeID: 1, Username: "octocat"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WebhookFiles() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,26 @@
+package services
+
+import "time"
+
+// WebhookDeliveryStore remembers the GitHub webhook deliveries the server has handled, so a replayed
+// delivery is rejected across restarts. Implementations are safe for concurrent use.
+type WebhookDeliveryStore interface {
+	// Remember records a delivery ID seen at a time, reporting false if it was already recorded.
+	// Deliveries seen before expireBefore are dropped first.
+	Remember(delivery string, seenAt, expireBefore time.Time) (bool, error)
+	// Forget drops a delivery ID
+	Forget(delivery string) error
+	// Close releases the underlying storage
+	Close() error
+}
+
+// NewWebhookDeliveryStoreFromEnv opens a delivery store with the backend selected by SUBMISSION_STORE,
+// in the submission store's database. If the SQLite database cannot be opened, delivery IDs fall back
+// to a JSON file.
+func NewWebhookDeliveryStoreFromEnv() (WebhookDeliveryStore, error) {
+	return openStoreFromEnv("Webhook delivery",
+		func(path string) (WebhookDeliveryStore, error) { return NewSQLiteWebhookDeliveryStore(path) },
+		"webhook_deliveries.json",
+		func(path string) (WebhookDeliveryStore, error) { return NewJSONWebhookDeliveryStore(path) },
+	)
+}
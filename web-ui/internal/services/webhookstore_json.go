@@ -0,0 +1,84 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"web-ui/internal/utils"
+)
+
+// JSONWebhookDeliveryStore keeps webhook delivery IDs in memory and rewrites them to a JSON file on
+// every change. Expired and forgotten deliveries are dropped, so it is not append-only.
+type JSONWebhookDeliveryStore struct {
+	mutex      sync.Mutex
+	path       string
+	deliveries map[string]time.Time // Delivery ID -> when it was seen
+}
+
+// NewJSONWebhookDeliveryStore opens the file at path, creating it on the first change, and loads the deliveries it holds
+func NewJSONWebhookDeliveryStore(path string) (*JSONWebhookDeliveryStore, error) {
+	store := &JSONWebhookDeliveryStore{path: path, deliveries: make(map[string]time.Time)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.deliveries); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return store, nil
+}
+
+// save writes every delivery atomically. The caller holds the mutex.
+func (s *JSONWebhookDeliveryStore) save() error {
+	data, err := json.MarshalIndent(s.deliveries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(s.path, append(data, '\n'), 0644)
+}
+
+// Remember records a delivery ID, reporting false if it was already recorded
+func (s *JSONWebhookDeliveryStore) Remember(delivery string, seenAt, expireBefore time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id, seen := range s.deliveries {
+		if seen.Before(expireBefore) {
+			delete(s.deliveries, id)
+		}
+	}
+	if _, seen := s.deliveries[delivery]; seen {
+		return false, nil
+	}
+
+	s.deliveries[delivery] = seenAt.UTC()
+	if err := s.save(); err != nil {
+		delete(s.deliveries, delivery)
+		return false, err
+	}
+	return true, nil
+}
+
+// Forget drops a delivery ID
+func (s *JSONWebhookDeliveryStore) Forget(delivery string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, seen := s.deliveries[delivery]; !seen {
+		return nil
+	}
+	delete(s.deliveries, delivery)
+	return s.save()
+}
+
+// Close does nothing, as every change is already written
+func (s *JSONWebhookDeliveryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteWebhookDeliveryStore keeps webhook delivery IDs in a SQLite database
+type SQLiteWebhookDeliveryStore struct {
+	db *sql.DB
+}
+
+// sqliteWebhookDeliverySchema creates the webhook deliveries table
+const sqliteWebhookDeliverySchema = `
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	delivery TEXT    PRIMARY KEY,
+	seen_at  INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_seen ON webhook_deliveries (seen_at);
+`
+
+// NewSQLiteWebhookDeliveryStore opens or creates its table in the database at path
+func NewSQLiteWebhookDeliveryStore(path string) (*SQLiteWebhookDeliveryStore, error) {
+	db, err := openSQLiteDatabase(path, sqliteWebhookDeliverySchema)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteWebhookDeliveryStore{db: db}, nil
+}
+
+// Remember records a delivery ID, reporting false if it was already recorded
+func (s *SQLiteWebhookDeliveryStore) Remember(delivery string, seenAt, expireBefore time.Time) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM webhook_deliveries WHERE seen_at < ?", expireBefore.UnixNano()); err != nil {
+		return false, err
+	}
+	result, err := tx.Exec("INSERT OR IGNORE INTO webhook_deliveries (delivery, seen_at) VALUES (?, ?)", delivery, seenAt.UnixNano())
+	if err != nil {
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted > 0, tx.Commit()
+}
+
+// Forget drops a delivery ID
+func (s *SQLiteWebhookDeliveryStore) Forget(delivery string) error {
+	_, err := s.db.Exec("DELETE FROM webhook_deliveries WHERE delivery = ?", delivery)
+	return err
+}
+
+// Close closes the database
+func (s *SQLiteWebhookDeliveryStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"web-ui/internal/utils"
+)
+
+// Submission path errors
+var (
+	ErrInvalidUsername         = errors.New("invalid GitHub username")
+	ErrInvalidSubmissionTarget = errors.New("invalid challenge")
+)
+
+// workspaceRoot is the repository checkout holding the challenges and packages, set once by ConfigureWorkspaceFromEnv
+var workspaceRoot string
+
+// pathSegmentPattern matches a package name or challenge ID that is safe to use as one path element
+var pathSegmentPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// ConfigureWorkspaceFromEnv resolves the repository root once at startup, from WORKSPACE_ROOT or,
+// since the server runs from web-ui, the parent of the working directory
+func ConfigureWorkspaceFromEnv() (string, error) {
+	root := os.Getenv("WORKSPACE_ROOT")
+	if root == "" {
+		root = ".."
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace root: %v", err)
+	}
+	if info, err := os.Stat(root); err != nil {
+		return "", fmt.Errorf("failed to open workspace root: %v", err)
+	} else if !info.IsDir() {
+		return "", fmt.Errorf("workspace root %s is not a directory", root)
+	}
+
+	workspaceRoot = root
+	log.Printf("Workspace root: %s", root)
+	return root, nil
+}
+
+// WorkspaceRoot returns the absolute path of the repository checkout
+func WorkspaceRoot() string {
+	if workspaceRoot == "" {
+		// Not configured, e.g. in tools that skip main: assume the server's layout
+		if root, err := filepath.Abs(".."); err == nil {
+			return root
+		}
+		return ".."
+	}
+	return workspaceRoot
+}
+
+// WorkspacePath joins trusted path elements onto the workspace root
+func WorkspacePath(elem ...string) string {
+	return filepath.Join(append([]string{WorkspaceRoot()}, elem...)...)
+}
+
+// ChallengeDir returns the directory of a core challenge
+func ChallengeDir(challengeID int) string {
+	return WorkspacePath(fmt.Sprintf("challenge-%d", challengeID))
+}
+
+// SubmissionTarget names the challenge a solution is written for: a package challenge when
+// PackageName is set, otherwise the core challenge with the numeric ChallengeID
+type SubmissionTarget struct {
+	PackageName string `json:"packageName,omitempty"`
+	ChallengeID string `json:"challengeId"`
+}
+
+// CoreSubmissionTarget returns the target for a core challenge
+func CoreSubmissionTarget(challengeID int) SubmissionTarget {
+	return SubmissionTarget{ChallengeID: strconv.Itoa(challengeID)}
+}
+
+// IsPackage reports whether the target is a package challenge
+func (t SubmissionTarget) IsPackage() bool {
+	return t.PackageName != ""
+}
+
+// FileName is the solution file a submission is saved as
+func (t SubmissionTarget) FileName() string {
+	if t.IsPackage() {
+		return "solution.go"
+	}
+	return "solution-template.go"
+}
+
+// challengeDir returns the challenge directory relative to the workspace root, after checking
+// that every element stays a single path segment
+func (t SubmissionTarget) challengeDir() (string, error) {
+	if t.IsPackage() {
+		if !pathSegmentPattern.MatchString(t.PackageName) || !pathSegmentPattern.MatchString(t.ChallengeID) {
+			return "", ErrInvalidSubmissionTarget
+		}
+		return filepath.Join("packages", t.PackageName, t.ChallengeID), nil
+	}
+
+	id, err := strconv.Atoi(t.ChallengeID)
+	if err != nil || id <= 0 {
+		return "", ErrInvalidSubmissionTarget
+	}
+	return fmt.Sprintf("challenge-%d", id), nil
+}
+
+// commitMessage is the suggested message for committing a user's solution
+func (t SubmissionTarget) commitMessage(username string) string {
+	if t.IsPackage() {
+		return fmt.Sprintf("Add solution for %s %s by %s", t.PackageName, t.ChallengeID, username)
+	}
+	return fmt.Sprintf("Add solution for Challenge %s by %s", t.ChallengeID, username)
+}
+
+// SubmissionPath returns a user's solution file for a target, relative to the workspace root.
+// Usernames must follow GitHub's rules, so no request can name a path outside the challenge's submissions.
+func SubmissionPath(target SubmissionTarget, username string) (string, error) {
+	if !ValidGitHubUsername(username) {
+		return "", ErrInvalidUsername
+	}
+	dir, err := target.challengeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "submissions", username, target.FileName()), nil
+}
+
+// SaveSubmissionResponse represents the response from saving a submission
+type SaveSubmissionResponse struct {
+	Success     bool     `json:"success"`
+	Message     string   `json:"message"`
+	FilePath    string   `json:"filePath"`
+	GitCommands []string `json:"gitCommands"`
+}
+
+// SaveSubmission writes a user's solution into the workspace, replacing any earlier one in a single rename
+func SaveSubmission(target SubmissionTarget, username, code string) (SaveSubmissionResponse, error) {
+	relativePath, err := SubmissionPath(target, username)
+	if err != nil {
+		return SaveSubmissionResponse{}, err
+	}
+
+	path := WorkspacePath(relativePath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return SaveSubmissionResponse{}, err
+	}
+	if err := utils.WriteFileAtomic(path, []byte(code), 0644); err != nil {
+		return SaveSubmissionResponse{}, err
+	}
+
+	return SaveSubmissionResponse{
+		Success:  true,
+		Message:  "Solution saved to filesystem",
+		FilePath: path,
+		GitCommands: []string{
+			"cd " + WorkspaceRoot(),
+			fmt.Sprintf("git add %s", filepath.ToSlash(relativePath)),
+			fmt.Sprintf("git commit -m \"%s\"", target.commitMessage(username)),
+			"git push origin main",
+		},
+	}, nil
+}
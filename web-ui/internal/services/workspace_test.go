@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useWorkspace points the workspace root at a temporary directory for one test
+func useWorkspace(t *testing.T) string {
+	t.Helper()
+	previous := workspaceRoot
+	workspaceRoot = t.TempDir()
+	t.Cleanup(func() { workspaceRoot = previous })
+	return workspaceRoot
+}
+
+func TestValidGitHubUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{"octocat", true},
+		{"Octo-Cat", true},
+		{"a", true},
+		{"42", true},
+		{"a1-b2-c3", true},
+		{strings.Repeat("a", 39), true},
+		{strings.Repeat("a", 40), false},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"a..b", false},
+		{"a.b", false},
+		{"-octocat", false},
+		{"octocat-", false},
+		{"octo--cat", false},
+		{"octo_cat", false},
+		{"octo cat", false},
+		{"/etc", false},
+		{"a/b", false},
+		{`a\b`, false},
+		{"../octocat", false},
+		{"octocat\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			if got := ValidGitHubUsername(tt.username); got != tt.want {
+				t.Errorf("ValidGitHubUsername(%q) = %v, want %v", tt.username, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmissionPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   SubmissionTarget
+		username string
+		want     string
+		wantErr  error
+	}{
+		{"core challenge", CoreSubmissionTarget(1), "octocat", "challenge-1/submissions/octocat/solution-template.go", nil},
+		{"core challenge with leading zero", SubmissionTarget{ChallengeID: "07"}, "octocat", "challenge-7/submissions/octocat/solution-template.go", nil},
+		{"package challenge", SubmissionTarget{PackageName: "gin", ChallengeID: "challenge-1-basic-routing"}, "octocat", "packages/gin/challenge-1-basic-routing/submissions/octocat/solution.go", nil},
+		{"parent directory username", CoreSubmissionTarget(1), "..", "", ErrInvalidUsername},
+		{"nested parent directory username", CoreSubmissionTarget(1), "../../etc", "", ErrInvalidUsername},
+		{"absolute username", CoreSubmissionTarget(1), "/etc/passwd", "", ErrInvalidUsername},
+		{"empty username", CoreSubmissionTarget(1), "", "", ErrInvalidUsername},
+		{"zero challenge", CoreSubmissionTarget(0), "octocat", "", ErrInvalidSubmissionTarget},
+		{"negative challenge", CoreSubmissionTarget(-1), "octocat", "", ErrInvalidSubmissionTarget},
+		{"parent directory challenge", SubmissionTarget{ChallengeID: ".."}, "octocat", "", ErrInvalidSubmissionTarget},
+		{"absolute challenge", SubmissionTarget{ChallengeID: "/etc"}, "octocat", "", ErrInvalidSubmissionTarget},
+		{"parent directory package", SubmissionTarget{PackageName: "..", ChallengeID: "challenge-1"}, "octocat", "", ErrInvalidSubmissionTarget},
+		{"nested package", SubmissionTarget{PackageName: "gin/../..", ChallengeID: "challenge-1"}, "octocat", "", ErrInvalidSubmissionTarget},
+		{"absolute package", SubmissionTarget{PackageName: "/etc", ChallengeID: "challenge-1"}, "octocat", "", ErrInvalidSubmissionTarget},
+		{"parent directory package challenge", SubmissionTarget{PackageName: "gin", ChallengeID: ".."}, "octocat", "", ErrInvalidSubmissionTarget},
+		{"absolute package challenge", SubmissionTarget{PackageName: "gin", ChallengeID: "/etc"}, "octocat", "", ErrInvalidSubmissionTarget},
+		{"empty package challenge", SubmissionTarget{PackageName: "gin"}, "octocat", "", ErrInvalidSubmissionTarget},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SubmissionPath(tt.target, tt.username)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SubmissionPath() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != filepath.FromSlash(tt.want) {
+				t.Errorf("SubmissionPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveSubmission(t *testing.T) {
+	root := useWorkspace(t)
+	target := SubmissionTarget{PackageName: "gin", ChallengeID: "challenge-1"}
+
+	if _, err := SaveSubmission(target, "octocat", "package main // first"); err != nil {
+		t.Fatalf("SaveSubmission() error = %v", err)
+	}
+	response, err := SaveSubmission(target, "octocat", "package main // second")
+	if err != nil {
+		t.Fatalf("SaveSubmission() error = %v", err)
+	}
+
+	want := filepath.Join(root, "packages", "gin", "challenge-1", "submissions", "octocat", "solution.go")
+	if response.FilePath != want {
+		t.Errorf("FilePath = %q, want %q", response.FilePath, want)
+	}
+	code, err := os.ReadFile(want)
+	if err != nil {
+		t.Fatalf("solution not written: %v", err)
+	}
+	if string(code) != "package main // second" {
+		t.Errorf("solution = %q, want the second save", code)
+	}
+
+	// The atomic write leaves nothing but the solution behind
+	entries, err := os.ReadDir(filepath.Dir(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("submission directory holds %d files, want only the solution", len(entries))
+	}
+}
+
+func TestSaveSubmissionRejectsEscapes(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   SubmissionTarget
+		username string
+		wantErr  error
+	}{
+		{"parent directory username", CoreSubmissionTarget(1), "..", ErrInvalidUsername},
+		{"traversing username", CoreSubmissionTarget(1), "../../outside", ErrInvalidUsername},
+		{"absolute username", CoreSubmissionTarget(1), "/tmp/outside", ErrInvalidUsername},
+		{"parent directory challenge", SubmissionTarget{ChallengeID: "../outside"}, "octocat", ErrInvalidSubmissionTarget},
+		{"traversing package", SubmissionTarget{PackageName: "../../outside", ChallengeID: "challenge-1"}, "octocat", ErrInvalidSubmissionTarget},
+		{"absolute package", SubmissionTarget{PackageName: "/tmp/outside", ChallengeID: "challenge-1"}, "octocat", ErrInvalidSubmissionTarget},
+		{"traversing package challenge", SubmissionTarget{PackageName: "gin", ChallengeID: "../../../outside"}, "octocat", ErrInvalidSubmissionTarget},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Nest the workspace so a file escaping it would land in the test's own directory
+			parent := t.TempDir()
+			previous := workspaceRoot
+			workspaceRoot = filepath.Join(parent, "workspace")
+			t.Cleanup(func() { workspaceRoot = previous })
+			if err := os.Mkdir(workspaceRoot, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := SaveSubmission(tt.target, tt.username, "package main"); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SaveSubmission() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var written []string
+			filepath.Walk(parent, func(path string, info os.FileInfo, err error) error {
+				if err == nil && path != parent && path != workspaceRoot {
+					written = append(written, path)
+				}
+				return nil
+			})
+			if len(written) != 0 {
+				t.Errorf("SaveSubmission() wrote %v", written)
+			}
+		})
+	}
+}
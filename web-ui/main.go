@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"embed"
 	"fmt"
 	"log"
@@ -20,14 +21,51 @@ func main() {
 	// Load environment variables from .env file
 	loadEnvFile()
 
+	// Resolve the repository checkout before any service reads challenges from it
+	if _, err := services.ConfigureWorkspaceFromEnv(); err != nil {
+		log.Fatalf("Failed to configure workspace: %v", err)
+	}
+
 	// Initialize services
+	submissionStore, err := services.NewSubmissionStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to open submission store: %v", err)
+	}
+	defer submissionStore.Close()
+
 	challengeService := services.NewChallengeService()
-	scoreboardService := services.NewScoreboardService()
+	scoreboardService := services.NewScoreboardService(submissionStore)
 	userService := services.NewUserService()
-	executionService := services.NewExecutionService()
+	executionService, err := services.NewExecutionService()
+	if err != nil {
+		log.Fatalf("Failed to set up code execution: %v", err)
+	}
 	packageService := services.NewPackageService()
 	aiService := services.NewAIService()
 
+	achievementStore, err := services.NewAchievementStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to open achievement store: %v", err)
+	}
+	defer achievementStore.Close()
+
+	cohortStore, err := services.NewCohortStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to open cohort store: %v", err)
+	}
+	defer cohortStore.Close()
+
+	webhookDeliveries, err := services.NewWebhookDeliveryStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to open webhook delivery store: %v", err)
+	}
+	defer webhookDeliveries.Close()
+
+	authService, err := services.NewAuthServiceFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure authentication: %v", err)
+	}
+
 	// Load data
 	log.Println("Loading challenges...")
 	if err := challengeService.LoadChallenges(); err != nil {
@@ -44,6 +82,21 @@ func main() {
 		log.Fatalf("Failed to load packages: %v", err)
 	}
 
+	// Warm the caches without delaying startup. Build caches need the modules, so they come second.
+	go func() {
+		log.Println("Warming module cache...")
+		if err := executionService.WarmModuleCache(context.Background(), services.WorkspaceRoot()); err != nil {
+			log.Printf("Warning: %v; runs needing those modules will fail until they are cached", err)
+		}
+
+		challenges := packageService.GetExecutionChallenges()
+		for _, challenge := range challengeService.GetChallenges() {
+			challenges = append(challenges, challenge)
+		}
+		executionService.WarmBuildCaches(context.Background(), challenges)
+		log.Printf("Warmed build caches for %d challenges", len(challenges))
+	}()
+
 	// Initialize server
 	srv := server.NewServer(
 		content,
@@ -53,15 +106,20 @@ func main() {
 		executionService,
 		packageService,
 		aiService,
+		submissionStore,
+		achievementStore,
+		cohortStore,
+		webhookDeliveries,
+		authService,
 	)
 
 	// Setup routes
-	mux := srv.SetupRoutes()
+	handler := srv.SetupRoutes()
 
 	// Start server
 	port := 8080
 	log.Printf("Server starting on http://localhost:%d", port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), mux))
+	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), handler))
 }
 
 // loadEnvFile loads environment variables from a .env file
@@ -1,5 +1,40 @@
 // Common JavaScript utilities for Go Interview Practice web UI
 
+// Read the CSRF token the server keeps in a cookie
+function csrfToken() {
+    const cookie = document.cookie.split(';').map(c => c.trim()).find(c => c.startsWith('csrf_token='));
+    return cookie ? decodeURIComponent(cookie.substring('csrf_token='.length)) : '';
+}
+
+// Send the CSRF token with every same-origin request that changes something, as the server requires
+(function() {
+    const originalFetch = window.fetch;
+    window.fetch = function(input, init = {}) {
+        const request = input instanceof Request ? input : null;
+        const method = (init.method || (request ? request.method : 'GET')).toUpperCase();
+        const url = new URL(request ? request.url : input, window.location.href);
+        if (!['GET', 'HEAD', 'OPTIONS'].includes(method) && url.origin === window.location.origin) {
+            const headers = new Headers(init.headers || (request ? request.headers : undefined));
+            headers.set('X-CSRF-Token', csrfToken());
+            init = { ...init, headers };
+        }
+        return originalFetch.call(this, input, init);
+    };
+})();
+
+// Read a JSON response, failing with the server's message for an error status
+async function readJSONResponse(response) {
+    if (!response.ok) {
+        throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+    }
+    return response.json();
+}
+
+// Send the browser to the sign-in page, returning here afterwards
+function signIn() {
+    window.location.href = `/login?next=${encodeURIComponent(window.location.pathname + window.location.search)}`;
+}
+
 // Helper for formatting timestamps
 function formatDate(dateString) {
     const date = new Date(dateString);
@@ -484,6 +519,292 @@ function formatTestOutput(output) {
         .replace(/--- PASS/g, '<span class="text-success">--- PASS</span>');
 }
 
+// Describe runs that were stopped before the tests finished
+function executionStatusMessage(result) {
+    if (result.status === 'timeout') {
+        return 'The test run hit its time limit and was stopped. The output below is partial - check for infinite loops or blocking calls.';
+    }
+    if (result.status === 'killed' || result.killed) {
+        return `The sandbox stopped the run: ${result.killReason || 'resource limit exceeded'}.`;
+    }
+    return '';
+}
+
+// Queue a code run and poll until it finishes. onProgress receives each job status while waiting.
+function runQueuedJob(payload, onProgress) {
+    return fetch('/api/jobs', {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json'
+        },
+        body: JSON.stringify(payload)
+    })
+    .then(response => {
+        if (!response.ok) {
+            return response.text().then(text => { throw new Error(text.trim() || response.statusText); });
+        }
+        return response.json();
+    })
+    .then(job => new Promise((resolve, reject) => {
+        const poll = (current) => {
+            if (onProgress) onProgress(current);
+            if (current.status === 'done' || current.status === 'canceled') {
+                resolve(current.result);
+                return;
+            }
+            setTimeout(() => {
+                fetch(`/api/jobs/${current.id}`)
+                    .then(response => {
+                        if (!response.ok) throw new Error('Lost track of the queued run');
+                        return response.json();
+                    })
+                    .then(poll)
+                    .catch(reject);
+            }, 1000);
+        };
+        poll(job);
+    }));
+}
+
+// Describe a job's place in the execution queue
+function jobProgressMessage(job) {
+    if (job.status === 'queued') {
+        return `Queued - position ${job.queuePosition} in line...`;
+    }
+    return 'Running tests...';
+}
+
+// Render structured go test results as one row per test, with subtests indented
+function renderTestResults(tests, rawOutput) {
+    let html = '';
+
+    if (tests && tests.length > 0) {
+        html += '<div class="list-group test-results-list mb-3">';
+        tests.forEach(test => {
+            html += renderTestRow(test, 0);
+        });
+        html += '</div>';
+    }
+
+    // Keep the full log available for build errors and panics
+    if (rawOutput) {
+        const open = !tests || tests.length === 0 ? 'open' : '';
+        html += `<details class="test-raw-output" ${open}>
+            <summary>Full test output</summary>
+            <pre class="bg-light p-3 rounded mt-2"><code>${escapeHtml(rawOutput)}</code></pre>
+        </details>`;
+    }
+
+    return html;
+}
+
+// Render a single test row and its subtests
+function renderTestRow(test, depth) {
+    const icons = {
+        pass: '<i class="bi bi-check-circle-fill text-success"></i>',
+        fail: '<i class="bi bi-x-circle-fill text-danger"></i>',
+        skip: '<i class="bi bi-dash-circle text-secondary"></i>',
+        incomplete: '<i class="bi bi-hourglass-split text-warning"></i>'
+    };
+    const shortName = depth === 0 ? test.name : test.name.split('/').slice(depth).join('/');
+    const output = test.output ? `<pre class="test-case-output mb-0 mt-1">${escapeHtml(test.output)}</pre>` : '';
+
+    let html = `<div class="list-group-item test-case test-case-${test.status}" style="padding-left: ${1 + depth * 1.5}rem;">
+        <div class="d-flex align-items-center justify-content-between">
+            <span>${icons[test.status] || icons.incomplete} <span class="ms-1">${escapeHtml(shortName.replace(/_/g, ' '))}</span></span>
+            <small class="text-muted">${(test.elapsed || 0).toFixed(2)}s</small>
+        </div>
+        ${output}
+    </div>`;
+
+    (test.subtests || []).forEach(subtest => {
+        html += renderTestRow(subtest, depth + 1);
+    });
+
+    return html;
+}
+
+// Collect the analysis modes ticked in a container of .analysis-option checkboxes
+function selectedAnalyses(containerId) {
+    const container = document.getElementById(containerId);
+    if (!container) return [];
+    return Array.from(container.querySelectorAll('.analysis-option:checked')).map(input => input.value);
+}
+
+// Render race detector, vet and staticcheck findings, separate from the test results
+function renderDiagnostics(diagnostics) {
+    if (!diagnostics || diagnostics.length === 0) return '';
+
+    const badges = {
+        race: 'bg-danger',
+        vet: 'bg-warning text-dark',
+        staticcheck: 'bg-info text-dark'
+    };
+
+    let html = '<div class="list-group diagnostics-list">';
+    diagnostics.forEach(diagnostic => {
+        const location = diagnostic.file ? `${diagnostic.file}:${diagnostic.line}${diagnostic.column ? ':' + diagnostic.column : ''}` : '';
+        const code = diagnostic.code ? ` <span class="text-muted">(${escapeHtml(diagnostic.code)})</span>` : '';
+        const details = diagnostic.details ? `<details class="mt-1"><summary>Details</summary><pre class="test-case-output mb-0 mt-1">${escapeHtml(diagnostic.details)}</pre></details>` : '';
+
+        html += `<div class="list-group-item diagnostic">
+            <span class="badge ${badges[diagnostic.tool] || 'bg-secondary'} me-2">${escapeHtml(diagnostic.tool)}</span>
+            ${location ? `<code class="me-2">${escapeHtml(location)}</code>` : ''}
+            <span>${escapeHtml(diagnostic.message)}</span>${code}
+            ${details}
+        </div>`;
+    });
+    html += '</div>';
+
+    return html;
+}
+
+// Convert diagnostics in the submission file to Ace editor annotations
+function diagnosticAnnotations(diagnostics) {
+    return (diagnostics || [])
+        .filter(diagnostic => diagnostic.file === 'solution-template.go' && diagnostic.line > 0)
+        .map(diagnostic => ({
+            row: diagnostic.line - 1,
+            column: Math.max((diagnostic.column || 1) - 1, 0),
+            text: `${diagnostic.tool}: ${diagnostic.message}`,
+            type: diagnostic.tool === 'race' ? 'error' : 'warning'
+        }));
+}
+
+// Render the coverage of the submission file, overall and per function
+function renderCoverage(report) {
+    if (!report) return '';
+
+    const percentClass = percent => percent >= 80 ? 'bg-success' : percent >= 50 ? 'bg-warning text-dark' : 'bg-danger';
+
+    let rows = '';
+    report.functions.forEach(fn => {
+        rows += `<tr>
+            <td><code>${escapeHtml(fn.name)}</code> <span class="text-muted small">line ${fn.line}</span></td>
+            <td class="text-end">${fn.statements}</td>
+            <td class="text-end"><span class="badge ${percentClass(fn.percent)}">${fn.percent.toFixed(1)}%</span></td>
+        </tr>`;
+    });
+
+    return `<div class="card mt-3">
+        <div class="card-header">Coverage
+            <span class="float-end fw-bold">${report.percent.toFixed(1)}% of statements</span>
+        </div>
+        <div class="card-body">
+            ${rows ? `<table class="table table-sm coverage-table mb-2">
+                <thead><tr><th>Function</th><th class="text-end">Statements</th><th class="text-end">Covered</th></tr></thead>
+                <tbody>${rows}</tbody>
+            </table>` : ''}
+            <p class="text-muted small mb-0">${report.uncoveredLines.length > 0 ? `${report.uncoveredLines.length} line${report.uncoveredLines.length === 1 ? '' : 's'} the tests never reach are highlighted in the editor.` : 'The tests reach every line of your code.'}</p>
+        </div>
+    </div>`;
+}
+
+// Highlight the lines of the submission the tests never reached, replacing earlier highlights
+function showCoverage(editor, report) {
+    const session = editor.getSession();
+    (session.coverageMarkers || []).forEach(id => session.removeMarker(id));
+    session.coverageMarkers = [];
+    if (!report) return;
+
+    const Range = ace.require('ace/range').Range;
+    report.uncoveredLines.forEach(line => {
+        session.coverageMarkers.push(session.addMarker(new Range(line - 1, 0, line - 1, 1), 'coverage-uncovered', 'fullLine'));
+    });
+}
+
+// Render a user's attempts at a challenge, newest first, with restore and diff actions
+function renderAttempts(attempts) {
+    if (!attempts || attempts.length === 0) {
+        return '<div class="alert alert-info">No attempts yet. Run or submit your code to save one.</div>';
+    }
+
+    let html = '<div class="list-group attempt-list">';
+    attempts.forEach((attempt, index) => {
+        const previous = attempts[index + 1];
+        const status = attempt.passed
+            ? '<span class="badge bg-success">passed</span>'
+            : '<span class="badge bg-danger">failed</span>';
+
+        html += `<div class="list-group-item d-flex align-items-center gap-3 attempt">
+            <input class="form-check-input attempt-select m-0" type="checkbox" value="${attempt.id}" aria-label="Select attempt ${attempt.version}">
+            <div class="flex-grow-1">
+                <div><strong>v${attempt.version}</strong>
+                    <span class="badge ${attempt.action === 'submit' ? 'bg-primary' : 'bg-secondary'} ms-1">${escapeHtml(attempt.action)}</span>
+                    ${status}
+                    <span class="text-muted small ms-2">${attempt.testsPassed}/${attempt.testsTotal} tests, ${formatExecutionTime(attempt.executionMs)}</span>
+                </div>
+                <div class="text-muted small">${new Date(attempt.submittedAt).toLocaleString()}</div>
+            </div>
+            ${previous ? `<button class="btn btn-outline-secondary btn-sm diff-attempt-btn" data-from="${previous.id}" data-to="${attempt.id}">Diff with v${previous.version}</button>` : ''}
+            <button class="btn btn-outline-primary btn-sm restore-attempt-btn" data-id="${attempt.id}">Restore</button>
+        </div>`;
+    });
+    html += '</div>';
+
+    return html;
+}
+
+// Render a unified diff with added and removed lines colored
+function renderDiff(diff) {
+    if (!diff) {
+        return '<div class="alert alert-secondary mb-0">The two attempts have identical code.</div>';
+    }
+
+    const lines = diff.replace(/\n$/, '').split('\n').map(line => {
+        let cls = '';
+        if (line.startsWith('@@')) cls = 'diff-hunk';
+        else if (line.startsWith('+++') || line.startsWith('---')) cls = 'diff-file';
+        else if (line.startsWith('+')) cls = 'diff-added';
+        else if (line.startsWith('-')) cls = 'diff-removed';
+        return `<span class="diff-line ${cls}">${escapeHtml(line)}</span>`;
+    });
+
+    return `<pre class="attempt-diff mb-0">${lines.join('\n')}</pre>`;
+}
+
+// Render benchmark timings and the speedup of each optimized function over its reference
+function renderBenchmarks(report) {
+    if (!report) return '';
+
+    const formatNs = ns => ns >= 1e6 ? `${(ns / 1e6).toFixed(2)} ms` : ns >= 1e3 ? `${(ns / 1e3).toFixed(2)} µs` : `${ns.toFixed(1)} ns`;
+
+    let body = '';
+    if (report.error) {
+        body += `<div class="alert alert-warning">${escapeHtml(report.error)}</div>`;
+    }
+
+    if (report.comparisons && report.comparisons.length > 0) {
+        body += `<table class="table table-sm benchmark-table">
+            <thead><tr><th>Benchmark</th><th class="text-end">Yours</th><th class="text-end">Reference</th><th class="text-end">B/op</th><th class="text-end">allocs/op</th><th class="text-end">Speedup</th></tr></thead>
+            <tbody>`;
+        report.comparisons.forEach(comparison => {
+            body += `<tr>
+                <td><code>${escapeHtml(comparison.name)}</code></td>
+                <td class="text-end">${formatNs(comparison.nsPerOp)}</td>
+                <td class="text-end text-muted">${formatNs(comparison.referenceNsPerOp)}</td>
+                <td class="text-end">${Math.round(comparison.bytesPerOp)} <span class="text-muted">/ ${Math.round(comparison.referenceBytesPerOp)}</span></td>
+                <td class="text-end">${Math.round(comparison.allocsPerOp)} <span class="text-muted">/ ${Math.round(comparison.referenceAllocsPerOp)}</span></td>
+                <td class="text-end fw-bold ${comparison.speedup >= 1 ? 'text-success' : 'text-danger'}">${comparison.speedup.toFixed(2)}×</td>
+            </tr>`;
+        });
+        body += '</tbody></table>';
+    } else if (report.results && report.results.length > 0) {
+        body += '<table class="table table-sm benchmark-table"><thead><tr><th>Benchmark</th><th class="text-end">ns/op</th><th class="text-end">B/op</th><th class="text-end">allocs/op</th></tr></thead><tbody>';
+        report.results.forEach(result => {
+            body += `<tr><td><code>${escapeHtml(result.name)}</code></td><td class="text-end">${formatNs(result.nsPerOp)}</td><td class="text-end">${Math.round(result.bytesPerOp)}</td><td class="text-end">${Math.round(result.allocsPerOp)}</td></tr>`;
+        });
+        body += '</tbody></table>';
+    }
+
+    return `<div class="card mt-3">
+        <div class="card-header">Benchmarks
+            ${report.speedup ? `<span class="float-end fw-bold">${report.speedup.toFixed(2)}× overall speedup</span>` : ''}
+        </div>
+        <div class="card-body">${body || '<p class="text-muted mb-0">No benchmarks ran.</p>'}</div>
+    </div>`;
+}
+
 // Handle form submissions with AJAX
 function handleFormSubmit(formElement, successCallback, errorCallback) {
     formElement.addEventListener('submit', function(e) {
@@ -553,21 +874,6 @@ document.addEventListener('DOMContentLoaded', function() {
             });
         }
     });
-    
-    // Handle username persistence
-    const usernameInput = document.getElementById('username');
-    if (usernameInput) {
-        // Load saved username
-        const savedUsername = localStorage.getItem('githubUsername');
-        if (savedUsername) {
-            usernameInput.value = savedUsername;
-        }
-        
-        // Save username when changed
-        usernameInput.addEventListener('change', function() {
-            localStorage.setItem('githubUsername', this.value);
-        });
-    }
 });
 
 // Initialize learning materials with highlighting
@@ -677,4 +983,119 @@ function initializeHints(hintsText) {
     } catch (error) {
         console.error('Error initializing hints:', error);
     }
-} 
\ No newline at end of file
+} 
+// Subscribe to live leaderboard events from /api/events. filter may name a challenge or a package;
+// onEvent receives each parsed event. Returns the EventSource, or null if the browser has none.
+function subscribeLiveEvents(filter, onEvent) {
+    if (!window.EventSource) {
+        return null;
+    }
+
+    const params = new URLSearchParams();
+    if (filter && filter.challenge) params.set('challenge', filter.challenge);
+    if (filter && filter.package) params.set('package', filter.package);
+    const query = params.toString();
+
+    const source = new EventSource(`/api/events${query ? `?${query}` : ''}`);
+    ['submission-passed', 'rank-changed'].forEach(type => {
+        source.addEventListener(type, message => {
+            try {
+                onEvent(JSON.parse(message.data));
+            } catch (error) {
+                console.error('Invalid live event:', error);
+            }
+        });
+    });
+    return source;
+}
+
+// Describe a live event for a notification
+function describeLiveEvent(event) {
+    const challenge = event.packageName
+        ? `${event.packageName} ${event.packageChallengeId || ''}`.trim()
+        : `Challenge ${event.challengeId}`;
+
+    if (event.type === 'rank-changed') {
+        let board = `the ${challenge} scoreboard`;
+        if (event.leaderboard === 'main') {
+            board = 'the leaderboard';
+        } else if (event.leaderboard === 'package') {
+            board = `the ${event.packageName} leaderboard`;
+        }
+        if (!event.rank) {
+            return `${event.username} left ${board}`;
+        }
+        if (!event.previousRank) {
+            return `${event.username} entered ${board} at #${event.rank}`;
+        }
+        return `${event.username} moved from #${event.previousRank} to #${event.rank} on ${board}`;
+    }
+    return `${event.username} passed ${challenge}`;
+}
+
+// Show a notification for a live event in the corner of the page
+function showLiveToast(message) {
+    const toast = document.createElement('div');
+    toast.className = 'alert alert-info alert-dismissible fade show position-fixed';
+    toast.style.cssText = 'top: 80px; right: 20px; z-index: 1050; min-width: 300px;';
+    toast.innerHTML = `
+        <i class="bi bi-broadcast me-2"></i>${escapeHtml(message)}
+        <button type="button" class="btn-close" data-bs-dismiss="alert"></button>
+    `;
+    document.body.appendChild(toast);
+
+    setTimeout(() => {
+        if (toast.parentNode) {
+            toast.parentNode.removeChild(toast);
+        }
+    }, 4000);
+}
+
+// Offer to commit a passing submission to its submission branch when the server has the git integration
+// enabled. submissionId is the stored submission's id, notify shows a toast, and the result goes in resultElement.
+function setupGitCommit(button, resultElement, submissionId, notify) {
+    if (!button) {
+        return;
+    }
+
+    fetch('/api/git/status')
+        .then(readJSONResponse)
+        .then(status => {
+            if (status.enabled) {
+                button.classList.remove('d-none');
+            }
+        })
+        .catch(error => console.log('Could not check git integration:', error.message));
+
+    button.addEventListener('click', function() {
+        button.disabled = true;
+        button.innerHTML = '<span class="spinner-border spinner-border-sm" role="status" aria-hidden="true"></span> Committing...';
+
+        fetch('/api/git/submissions', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({ submissionId: submissionId })
+        })
+        .then(readJSONResponse)
+        .then(data => {
+            notify('Success', data.unchanged ? 'The branch already has this solution' : `Committed to ${data.branch}`, 'success');
+            resultElement.innerHTML = `
+                <div class="mt-3 p-3 bg-light rounded">
+                    <h6>🌿 Committed to <code>${escapeHtml(data.branch)}</code></h6>
+                    <p class="mb-2">${data.commits} commit${data.commits === 1 ? '' : 's'} on top of <code>${escapeHtml(data.base.slice(0, 7))}</code>. Push the branch to your fork and open a pull request:</p>
+                    <div class="bg-dark text-light p-2 rounded mb-2"><code>$ ${escapeHtml(data.pushCommand)}</code></div>
+                    <a class="btn btn-sm btn-outline-primary" href="${data.patchUrl}">Download Patch</a>
+                    <a class="btn btn-sm btn-outline-secondary" href="${data.bundleUrl}">Download Bundle</a>
+                </div>`;
+            button.disabled = false;
+            button.textContent = '🌿 Commit Again';
+        })
+        .catch(error => {
+            notify('Error', 'Failed to commit: ' + error.message, 'error');
+            button.disabled = false;
+            button.textContent = '🌿 Commit to Branch';
+        });
+    });
+}